@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/service"
+)
+
+// Input formats of a batch
+const (
+	inputAuto   = "auto"
+	inputCSV    = "csv"    // order_id,quantity[,pack_sizes|profile], as for POST /api/calculate/csv
+	inputNDJSON = "ndjson" // one PackRequest per line, as for POST /api/calculate/batch
+	inputLines  = "lines"  // one quantity per line
+)
+
+// runBatch calculates every request of a file, or of stdin
+// Failed requests are reported in the output and make the command exit with exitFailed
+func runBatch(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var options calcOptions
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: packcalc batch [flags] [FILE]")
+		flags.PrintDefaults()
+	}
+	outputs := []string{"table", "json", "ndjson", "csv"}
+	options.register(flags, outputs...)
+	input := flags.String("in", inputAuto, "input format: auto, csv, ndjson or lines (one quantity per line)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := options.checkOutput(outputs...); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return usagef("batch takes at most one file, got %d arguments", flags.NArg())
+	}
+
+	reader := stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	rows, requests, err := readBatch(*input, data)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		return usagef("input has no requests")
+	}
+
+	packService, err := options.service()
+	if err != nil {
+		return err
+	}
+
+	response := &model.BatchResponse{Results: make([]model.BatchItemResult, 0, len(requests))}
+	encoder := json.NewEncoder(stdout)
+	err = packService.CalculateBatch(ctx, service.BatchOf(requests), func(result model.BatchItemResult) error {
+		response.AddResult(result)
+		if options.output == "ndjson" {
+			return encoder.Encode(result)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch options.output {
+	case "json":
+		err = writeJSON(stdout, response)
+	case "csv":
+		err = service.WriteCSVResults(stdout, rows, response.Results)
+	case "table":
+		err = writeBatchTable(stdout, rows, response)
+	}
+	if err != nil {
+		return err
+	}
+
+	if response.Totals.Failed > 0 {
+		fmt.Fprintf(stderr, "packcalc: %d of %d requests failed\n", response.Totals.Failed, response.Totals.Requests)
+		return errReported
+	}
+	return nil
+}
+
+// readBatch reads the requests of data in format, guessing the format when it is auto
+// Requests that cannot be read carry their error, so they are reported with the results
+func readBatch(format string, data []byte) ([]service.CSVRow, []service.BatchRequest, error) {
+	if format == inputAuto {
+		format = detectBatchFormat(data)
+	}
+
+	switch format {
+	case inputCSV:
+		return service.ReadCSVRequests(bytes.NewReader(data), 0)
+	case inputNDJSON, inputLines:
+		var rows []service.CSVRow
+		var requests []service.BatchRequest
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, len(data)+1)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			row, request := readBatchLine(format, line, text)
+			rows = append(rows, row)
+			requests = append(requests, request)
+		}
+		return rows, requests, scanner.Err()
+	default:
+		return nil, nil, usagef("-in must be one of auto, csv, ndjson or lines, got: %q", format)
+	}
+}
+
+// readBatchLine reads the request on line of an NDJSON or lines input
+func readBatchLine(format string, line int, text string) (service.CSVRow, service.BatchRequest) {
+	if format == inputLines {
+		quantity, err := strconv.Atoi(text)
+		if err != nil {
+			message := fmt.Sprintf("must be a whole number on line %d, got: %q", line, text)
+			return service.CSVRow{Quantity: text}, service.BatchRequest{Err: model.NewFieldError("quantity", message)}
+		}
+		return service.CSVRow{Quantity: text}, service.BatchRequest{Request: &model.PackRequest{Quantity: quantity}}
+	}
+
+	var request model.PackRequest
+	if err := json.Unmarshal([]byte(text), &request); err != nil {
+		message := fmt.Sprintf("line %d is not a valid request: %v", line, err)
+		return service.CSVRow{}, service.BatchRequest{Err: model.NewValidationError(message)}
+	}
+	return service.CSVRow{Quantity: strconv.Itoa(request.Quantity)}, service.BatchRequest{Request: &request}
+}
+
+// detectBatchFormat guesses the format of data from its first line: a JSON object
+// starts NDJSON, a comma a CSV, and anything else one quantity per line
+func detectBatchFormat(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		switch {
+		case len(line) == 0:
+			continue
+		case line[0] == '{':
+			return inputNDJSON
+		case bytes.ContainsRune(line, ','):
+			return inputCSV
+		default:
+			return inputLines
+		}
+	}
+	return inputLines
+}
+
+// writeBatchTable writes one row per request with its totals and packs, or its error
+func writeBatchTable(w io.Writer, rows []service.CSVRow, response *model.BatchResponse) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "#\tORDER\tQUANTITY\tTOTAL ITEMS\tOVERAGE\tTOTAL PACKS\tPACKS\tERROR")
+	for i, result := range response.Results {
+		if result.Result == nil {
+			fmt.Fprintf(table, "%d\t%s\t%s\t\t\t\t\t%s\n", i+1, rows[i].OrderID, rows[i].Quantity, result.Error)
+			continue
+		}
+
+		packs := make([]string, 0, len(result.Result.PackBreakdown))
+		for _, size := range sortedSizes(result.Result.PackBreakdown) {
+			packs = append(packs, fmt.Sprintf("%dx%d", result.Result.PackBreakdown[size], size))
+		}
+		fmt.Fprintf(table, "%d\t%s\t%d\t%d\t%d\t%d\t%s\t\n", i+1, rows[i].OrderID, result.Result.Quantity,
+			result.Result.TotalItems, result.Result.TotalItems-result.Result.Quantity, result.Result.TotalPacks,
+			strings.Join(packs, " "))
+	}
+	fmt.Fprintf(table, "\n%d requests, %d failed\n", response.Totals.Requests, response.Totals.Failed)
+	return table.Flush()
+}
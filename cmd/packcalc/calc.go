@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// runCalc calculates one quantity
+func runCalc(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var options calcOptions
+	flags := flag.NewFlagSet("calc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: packcalc calc [flags] QUANTITY")
+		flags.PrintDefaults()
+	}
+	options.register(flags, "table", "json")
+	policy := flags.String("policy", "", "ranking policy, see GET /api/policies (default: fewest items, then packs)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := options.checkOutput("table", "json"); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usagef("calc takes one quantity, got %d arguments", flags.NArg())
+	}
+	quantity, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return usagef("quantity must be a whole number, got: %q", flags.Arg(0))
+	}
+
+	packService, err := options.service()
+	if err != nil {
+		return err
+	}
+	response, err := packService.CalculatePackDistribution(ctx, &model.PackRequest{Quantity: quantity, Policy: *policy})
+	if err != nil {
+		return err
+	}
+
+	if options.output == "json" {
+		return writeJSON(stdout, response)
+	}
+	return writeResponseTable(stdout, response)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeResponseTable writes the totals and the packs of response, largest packs first
+func writeResponseTable(w io.Writer, response *model.PackResponse) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "Quantity\t%d\n", response.Quantity)
+	fmt.Fprintf(table, "Total items\t%d\n", response.TotalItems)
+	fmt.Fprintf(table, "Overage\t%d\n", response.TotalItems-response.Quantity)
+	fmt.Fprintf(table, "Total packs\t%d\n", response.TotalPacks)
+	if response.TotalCost > 0 {
+		fmt.Fprintf(table, "Total cost\t%d\n", response.TotalCost)
+	}
+
+	fmt.Fprintln(table, "\nPACK SIZE\tCOUNT\tITEMS")
+	for _, size := range sortedSizes(response.PackBreakdown) {
+		count := response.PackBreakdown[size]
+		fmt.Fprintf(table, "%d\t%d\t%d\n", size, count, size*count)
+	}
+	return table.Flush()
+}
+
+// sortedSizes returns the pack sizes of breakdown, largest first
+func sortedSizes(breakdown map[int]int) []int {
+	sizes := make([]int, 0, len(breakdown))
+	for size := range breakdown {
+		sizes = append(sizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return sizes
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// runCompare compares the distributions of quantities under -sizes and -with
+func runCompare(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var options calcOptions
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: packcalc compare [flags] -with SIZES")
+		flags.PrintDefaults()
+	}
+	options.register(flags, "table", "json")
+	with := flags.String("with", "", "comma-separated pack sizes compared with -sizes (required)")
+	list := flags.String("quantities", "", "comma-separated quantities, instead of -range")
+	span := flags.String("range", "1:1000", "quantities FROM:TO[:STEP]")
+	summary := flags.Bool("summary", false, "only write the summary")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := options.checkOutput("table", "json"); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usagef("compare takes no arguments, got: %s", strings.Join(flags.Args(), " "))
+	}
+	if *with == "" {
+		return usagef("-with is required")
+	}
+
+	base, err := parsePackSizes("-sizes", options.sizes)
+	if err != nil {
+		return err
+	}
+	candidate, err := parsePackSizes("-with", *with)
+	if err != nil {
+		return err
+	}
+	quantities, err := parseQuantities(*list, *span)
+	if err != nil {
+		return err
+	}
+	calc, _, err := options.calculator()
+	if err != nil {
+		return err
+	}
+
+	comparison, err := calculator.Compare(ctx, calc, quantities, base, candidate)
+	if err != nil {
+		return err
+	}
+	if *summary {
+		comparison.Quantities = nil
+	}
+
+	if options.output == "json" {
+		return writeJSON(stdout, comparison)
+	}
+	return writeComparisonTable(stdout, comparison)
+}
+
+// parseQuantities parses -quantities, or -range when no quantities are listed
+func parseQuantities(list, span string) ([]int, error) {
+	if list != "" {
+		return parsePackSizes("-quantities", list)
+	}
+
+	fields := strings.Split(span, ":")
+	bounds := []int{0, 0, 1}
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, usagef("-range must be FROM:TO[:STEP], got: %q", span)
+	}
+	for i, field := range fields {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, usagef("-range must be FROM:TO[:STEP], got: %q", span)
+		}
+		bounds[i] = value
+	}
+
+	quantities, err := calculator.QuantityRange(bounds[0], bounds[1], bounds[2])
+	if err != nil {
+		return nil, usagef("-range %v", err)
+	}
+	return quantities, nil
+}
+
+// writeComparisonTable writes one row per quantity, then the totals of both sets
+func writeComparisonTable(w io.Writer, comparison *calculator.Comparison) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(comparison.Quantities) > 0 {
+		fmt.Fprintln(table, "QUANTITY\tBASE ITEMS\tBASE PACKS\tCANDIDATE ITEMS\tCANDIDATE PACKS\tITEMS DIFF\tPACKS DIFF")
+		for _, entry := range comparison.Quantities {
+			fmt.Fprintf(table, "%d\t%s\t%s\t%+d\t%+d\n", entry.Quantity,
+				outcomeCells(entry.Base), outcomeCells(entry.Candidate), entry.ItemsDiff, entry.PacksDiff)
+		}
+		fmt.Fprintln(table)
+	}
+
+	summary := comparison.Summary
+	fmt.Fprintf(table, "\tBASE %s\tCANDIDATE %s\tDIFF\n", joinSizes(comparison.Base), joinSizes(comparison.Candidate))
+	fmt.Fprintf(table, "Total items\t%d\t%d\t%+d\n", summary.Base.TotalItems, summary.Candidate.TotalItems, summary.ItemsDiff)
+	fmt.Fprintf(table, "Total packs\t%d\t%d\t%+d\n", summary.Base.TotalPacks, summary.Candidate.TotalPacks, summary.PacksDiff)
+	fmt.Fprintf(table, "Total overage\t%d\t%d\t%+d\n", summary.Base.TotalOverage, summary.Candidate.TotalOverage, summary.OverageDiff)
+	fmt.Fprintf(table, "Max overage\t%d\t%d\t\n", summary.Base.MaxOverage, summary.Candidate.MaxOverage)
+	fmt.Fprintf(table, "Average overage\t%.2f\t%.2f\t\n", summary.Base.AverageOverage, summary.Candidate.AverageOverage)
+	fmt.Fprintf(table, "Unreachable\t%d\t%d\t\n", summary.Base.Unreachable, summary.Candidate.Unreachable)
+	fmt.Fprintf(table, "\n%d quantities: candidate better on %d, worse on %d, same on %d\n",
+		summary.Quantities, summary.Better, summary.Worse, summary.Same)
+	return table.Flush()
+}
+
+// outcomeCells returns the items and packs cells of outcome
+func outcomeCells(outcome calculator.CompareOutcome) string {
+	if outcome.Unreachable {
+		return "unreachable\t-"
+	}
+	return fmt.Sprintf("%d\t%d", outcome.TotalItems, outcome.TotalPacks)
+}
+
+// joinSizes formats sizes as a comma-separated list
+func joinSizes(sizes []int) string {
+	fields := make([]string, len(sizes))
+	for i, size := range sizes {
+		fields[i] = strconv.Itoa(size)
+	}
+	return strings.Join(fields, ",")
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/internal/server"
+	"github.com/marcellribeiro/awesomeProject/internal/service"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// Exit codes, so scripts can tell why a command failed
+const (
+	exitOK          = 0
+	exitFailed      = 1 // a calculation failed, e.g. insufficient stock or a batch with failed requests
+	exitUsage       = 2 // invalid arguments or input
+	exitUnavailable = 3 // a calculation timed out or was interrupted
+	exitInternal    = 4 // I/O or server failure
+)
+
+// defaultPackSizes are used when -sizes is not given
+const defaultPackSizes = "250,500,1000,2000,5000"
+
+// Shared tables of the calculator, as in the default server configuration
+const (
+	tableCells = 1 << 22
+	tableSets  = 16
+)
+
+const usage = `packcalc calculates pack distributions without starting the API server
+
+Usage:
+  packcalc calc [flags] QUANTITY           calculate one quantity
+  packcalc batch [flags] [FILE]            calculate every request of FILE, or of stdin
+  packcalc compare [flags] -with SIZES     compare two pack size sets over quantities
+  packcalc serve [-port PORT]              serve the API and web interface
+
+Run "packcalc COMMAND -h" for the flags of a command.
+
+Exit codes:
+  0  success
+  1  a calculation failed
+  2  invalid arguments or input
+  3  a calculation timed out or was interrupted
+  4  I/O or server failure
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command of args and returns its exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	var err error
+	switch command, args := args[0], args[1:]; command {
+	case "calc":
+		err = runCalc(ctx, args, stdout, stderr)
+	case "batch":
+		err = runBatch(ctx, args, stdin, stdout, stderr)
+	case "compare":
+		err = runCompare(ctx, args, stdout, stderr)
+	case "serve":
+		err = runServe(args, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "packcalc: unknown command %q\n\n%s", command, usage)
+		return exitUsage
+	}
+
+	if err != nil && !isReported(err) {
+		fmt.Fprintf(stderr, "packcalc: %v\n", err)
+	}
+	return exitCode(err)
+}
+
+// errReported is returned by commands that already reported their failures, such as
+// a batch writing its failed requests
+var errReported = errors.New("failures reported in the output")
+
+// usageError is an invalid argument or flag
+// Errors of the flag package are reported by the flag package itself
+type usageError struct {
+	message  string
+	reported bool
+}
+
+func (e *usageError) Error() string { return e.message }
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// parseFlags parses the flags of args
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &usageError{message: err.Error(), reported: true}
+}
+
+// isReported reports whether err was written to stderr already
+func isReported(err error) bool {
+	var usageErr *usageError
+	return errors.Is(err, flag.ErrHelp) || errors.Is(err, errReported) ||
+		(errors.As(err, &usageErr) && usageErr.reported)
+}
+
+// exitCode returns the exit code reporting err; asking for help is not an error
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, errReported):
+		return exitFailed
+	}
+
+	switch service.ErrorCode(err) {
+	case model.CodeValidationFailed, model.CodeInvalidRequest, model.CodeNoPackSizes:
+		return exitUsage
+	case model.CodeTimeout, model.CodeCanceled:
+		return exitUnavailable
+	case model.CodeRepositoryFailure, model.CodeInternal:
+		return exitInternal
+	default:
+		return exitFailed
+	}
+}
+
+// calcOptions are the calculator flags shared by the commands
+type calcOptions struct {
+	sizes     string
+	algorithm string
+	timeout   time.Duration
+	output    string
+}
+
+// register adds the calculator flags to flags; outputs lists the accepted -o values
+func (o *calcOptions) register(flags *flag.FlagSet, outputs ...string) {
+	flags.StringVar(&o.sizes, "sizes", defaultPackSizes, "comma-separated pack sizes")
+	flags.StringVar(&o.algorithm, "algorithm", calculator.AlgorithmDynamic, "calculator algorithm: dynamic or residue")
+	flags.DurationVar(&o.timeout, "timeout", 0, "maximum time per calculation, e.g. 2s (0 for none)")
+	flags.StringVar(&o.output, "o", outputs[0], "output format: "+strings.Join(outputs, ", "))
+}
+
+// checkOutput validates the -o flag against outputs
+func (o *calcOptions) checkOutput(outputs ...string) error {
+	for _, output := range outputs {
+		if o.output == output {
+			return nil
+		}
+	}
+	return usagef("-o must be one of %s, got: %q", strings.Join(outputs, ", "), o.output)
+}
+
+// calculator creates the calculator of the options, sharing its tables between quantities
+func (o *calcOptions) calculator() (calculator.PackCalculator, calculator.Limits, error) {
+	limits := calculator.DefaultLimits()
+	limits.Timeout = o.timeout
+
+	calc, err := calculator.NewPackCalculator(o.algorithm, limits)
+	if err != nil {
+		return nil, limits, usagef("%v", err)
+	}
+	if dynamic, ok := calc.(*calculator.DynamicPackCalculator); ok {
+		calc = dynamic.WithTables(calculator.NewTableStore(calculator.TableStoreOptions{MaxCells: tableCells, MaxSets: tableSets}))
+	}
+	return calc, limits, nil
+}
+
+// service creates a pack service whose default pack sizes are -sizes
+func (o *calcOptions) service() (service.PackService, error) {
+	sizes, err := parsePackSizes("-sizes", o.sizes)
+	if err != nil {
+		return nil, err
+	}
+	calc, limits, err := o.calculator()
+	if err != nil {
+		return nil, err
+	}
+
+	repo := repository.NewInMemoryPackRepository()
+	if err := repo.SetPackSizes(sizes); err != nil {
+		return nil, err
+	}
+	// Offline calculations are not quotes, so none are recorded
+	return service.NewPackService(calc, repo, nil, limits), nil
+}
+
+// parsePackSizes parses the comma-separated positive pack sizes of the named flag
+func parsePackSizes(name, value string) ([]int, error) {
+	var sizes []int
+	for _, field := range strings.Split(value, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || size <= 0 {
+			return nil, usagef("%s must be comma-separated positive numbers, got: %q", name, value)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// runServe serves the API configured by the environment, like cmd/api
+func runServe(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	port := flags.String("port", "", "port to listen on (default $PORT, or 8080)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usagef("serve takes no arguments, got: %s", strings.Join(flags.Args(), " "))
+	}
+
+	return server.Run(*port)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout []string
+		wantStderr string
+	}{
+		{
+			name:       "Calc table",
+			args:       []string{"calc", "501"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Total items  750", "Overage      249", "500        1      500"},
+		},
+		{
+			name:       "Calc with pack sizes",
+			args:       []string{"calc", "-sizes", "23,31,53", "500000"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Total items  500000", "53         9429"},
+		},
+		{
+			name:       "Calc without quantity",
+			args:       []string{"calc"},
+			wantCode:   exitUsage,
+			wantStderr: "calc takes one quantity",
+		},
+		{
+			name:       "Calc invalid quantity",
+			args:       []string{"calc", "0"},
+			wantCode:   exitUsage,
+			wantStderr: "quantity",
+		},
+		{
+			name:       "Calc unknown output",
+			args:       []string{"calc", "-o", "xml", "1"},
+			wantCode:   exitUsage,
+			wantStderr: "-o must be one of table, json",
+		},
+		{
+			name:       "Calc unknown flag",
+			args:       []string{"calc", "-bogus", "1"},
+			wantCode:   exitUsage,
+			wantStderr: "flag provided but not defined: -bogus",
+		},
+		{
+			name:       "Calc invalid pack sizes",
+			args:       []string{"calc", "-sizes", "250,x", "1"},
+			wantCode:   exitUsage,
+			wantStderr: "-sizes must be comma-separated positive numbers",
+		},
+		{
+			name:       "Calc help",
+			args:       []string{"calc", "-h"},
+			wantCode:   exitOK,
+			wantStderr: "Usage: packcalc calc",
+		},
+		{
+			name:       "Batch lines",
+			args:       []string{"batch", "-o", "csv"},
+			stdin:      "1\n\n251\n",
+			wantCode:   exitOK,
+			wantStdout: []string{"order_id,quantity,pack_250,", ",1,1,0,0,0,0,250,249,1,", ",251,0,1,0,0,0,500,249,1,"},
+		},
+		{
+			name:       "Batch NDJSON",
+			args:       []string{"batch", "-o", "ndjson"},
+			stdin:      `{"quantity":12001}` + "\n" + `{"quantity":10,"pack_sizes":[3]}` + "\n",
+			wantCode:   exitOK,
+			wantStdout: []string{`"index":0,"result":{"quantity":12001`, `"index":1,"result":{"quantity":10,"total_items":12`, `"pack_breakdown":{"3":4}`},
+		},
+		{
+			name:       "Batch CSV",
+			args:       []string{"batch"},
+			stdin:      "order_id,quantity\nSO-1,501\nSO-2,abc\n",
+			wantCode:   exitFailed,
+			wantStdout: []string{"SO-1", "1x500 1x250", "SO-2", "2 requests, 1 failed"},
+			wantStderr: "1 of 2 requests failed",
+		},
+		{
+			name:       "Batch empty input",
+			args:       []string{"batch"},
+			stdin:      "\n",
+			wantCode:   exitUsage,
+			wantStderr: "input has no requests",
+		},
+		{
+			name:       "Batch unknown input format",
+			args:       []string{"batch", "-in", "xml"},
+			stdin:      "1\n",
+			wantCode:   exitUsage,
+			wantStderr: "-in must be one of",
+		},
+		{
+			name:       "Batch missing file",
+			args:       []string{"batch", "missing.csv"},
+			wantCode:   exitInternal,
+			wantStderr: "missing.csv",
+		},
+		{
+			name:       "Compare",
+			args:       []string{"compare", "-with", "300,600", "-range", "1:1000:250"},
+			wantCode:   exitOK,
+			wantStdout: []string{"501       750         2           600              1                -150        -1", "candidate better on 3, worse on 1"},
+		},
+		{
+			name:       "Compare without candidate",
+			args:       []string{"compare"},
+			wantCode:   exitUsage,
+			wantStderr: "-with is required",
+		},
+		{
+			name:       "Compare invalid range",
+			args:       []string{"compare", "-with", "300", "-range", "10:1"},
+			wantCode:   exitUsage,
+			wantStderr: "-range range end 1 is before its start 10",
+		},
+		{
+			name:       "Compare too large quantity",
+			args:       []string{"compare", "-with", "300", "-quantities", "2000000000"},
+			wantCode:   exitFailed,
+			wantStderr: "base pack sizes",
+		},
+		{
+			name:       "No command",
+			wantCode:   exitUsage,
+			wantStderr: "Usage:",
+		},
+		{
+			name:       "Unknown command",
+			args:       []string{"bogus"},
+			wantCode:   exitUsage,
+			wantStderr: `unknown command "bogus"`,
+		},
+		{
+			name:       "Help",
+			args:       []string{"help"},
+			wantCode:   exitOK,
+			wantStdout: []string{"Exit codes:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+			if code != tt.wantCode {
+				t.Errorf("run() = %d, want %d, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected stdout to contain %q, got:\n%s", want, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("Expected stderr to contain %q, got:\n%s", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"calc", "-o", "json", "12001"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("run() = %d, stderr: %s", code, stderr.String())
+	}
+	var response model.PackResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if response.TotalItems != 12250 || response.TotalPacks != 4 {
+		t.Errorf("Expected 12250 items in 4 packs, got %d in %d", response.TotalItems, response.TotalPacks)
+	}
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"compare", "-o", "json", "-summary", "-with", "300,600", "-quantities", "1,251"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("run() = %d, stderr: %s", code, stderr.String())
+	}
+	var comparison calculator.Comparison
+	if err := json.Unmarshal(stdout.Bytes(), &comparison); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if len(comparison.Quantities) != 0 || comparison.Summary.Quantities != 2 || comparison.Summary.ItemsDiff != -150 {
+		t.Errorf("Expected a summary of 2 quantities with 150 fewer items, got %+v", comparison)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"No error", nil, exitOK},
+		{"Usage", usagef("bad"), exitUsage},
+		{"Reported failures", errReported, exitFailed},
+		{"Validation", model.NewValidationError("bad"), exitUsage},
+		{"Timeout", calculator.ErrTimeout, exitUnavailable},
+		{"Canceled", calculator.ErrCanceled, exitUnavailable},
+		{"Insufficient stock", calculator.ErrInsufficientStock, exitFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -72,6 +72,12 @@ type APIComponents struct {
 
 func GetAPIDocumentation() APIDocumentation {
 	minOne := 1
+	idempotencyKey := APIParameter{
+		Name:        "Idempotency-Key",
+		In:          "header",
+		Schema:      APISchema{Type: "string"},
+		Description: "Up to 255 visible ASCII characters making the request safe to retry: the first successful response is stored for PACK_IDEMPOTENCY_TTL and replayed byte for byte, with Idempotent-Replayed: true, to requests with the same key and body. A retry while the first request runs gets 409 (code idempotency_key_in_use); the key sent with a different body or endpoint gets 422 (code idempotency_key_reused), and a body above 16 MiB gets 413 (code body_too_large)",
+	}
 
 	return APIDocumentation{
 		OpenAPI: "3.0.0",
@@ -118,7 +124,7 @@ func GetAPIDocumentation() APIDocumentation {
 			"/api/pack-sizes": {
 				"get": {
 					Summary:     "Get Pack Sizes",
-					Description: "Retrieve all configured pack sizes",
+					Description: "Retrieve all configured pack sizes. The ETag header holds the version to send in If-Match when updating",
 					Responses: map[string]APIResponse{
 						"200": {
 							Description: "List of pack sizes",
@@ -131,6 +137,10 @@ func GetAPIDocumentation() APIDocumentation {
 												Type:    "array",
 												Example: []int{250, 500, 1000},
 											},
+											"version": {
+												Type:    "integer",
+												Example: 3,
+											},
 										},
 									},
 								},
@@ -140,7 +150,16 @@ func GetAPIDocumentation() APIDocumentation {
 				},
 				"put": {
 					Summary:     "Update Pack Sizes",
-					Description: "Update the configured pack sizes",
+					Description: "Update the configured pack sizes. With an If-Match header the update only applies if the pack sizes are still at that version",
+					Parameters: []APIParameter{
+						{
+							Name:        "If-Match",
+							In:          "header",
+							Required:    false,
+							Schema:      APISchema{Type: "string"},
+							Description: "ETag from GET /api/pack-sizes, e.g. \"3\"",
+						},
+					},
 					RequestBody: &APIRequestBody{
 						Required: true,
 						Content: map[string]APIContent{
@@ -167,17 +186,58 @@ func GetAPIDocumentation() APIDocumentation {
 												Type:    "array",
 												Example: []int{250, 500, 1000},
 											},
+											"version": {
+												Type:    "integer",
+												Example: 4,
+											},
 										},
 									},
 								},
 							},
 						},
+						"412": {
+							Description: "The pack sizes were changed since the If-Match version",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
 						"400": {
 							Description: "Invalid request",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/pack-sizes/revisions": {
+				"get": {
+					Summary:     "List Pack Size Revisions",
+					Description: "List every change of the pack sizes, oldest first. Each revision's version is the pack sizes version it produced",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Pack size revisions",
 							Content: map[string]APIContent{
 								"application/json": {
 									Schema: APISchema{
-										Ref: "#/components/schemas/ErrorResponse",
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"revisions": {
+												Type: "array",
+												Example: []map[string]interface{}{
+													{"version": 1, "created_at": "2024-05-01T09:00:00Z", "old_pack_sizes": []int{}, "new_pack_sizes": []int{250, 500, 1000}, "author": "alice"},
+													{"version": 2, "created_at": "2024-05-02T09:00:00Z", "old_pack_sizes": []int{250, 500, 1000}, "new_pack_sizes": []int{250, 750}, "author": "bob", "reason": "new supplier"},
+												},
+											},
+										},
 									},
 								},
 							},
@@ -185,37 +245,210 @@ func GetAPIDocumentation() APIDocumentation {
 					},
 				},
 			},
-			"/api/calculate": {
+			"/api/pack-sizes/revisions/diff": {
+				"get": {
+					Summary:     "Diff Pack Size Revisions",
+					Description: "Compare the pack sizes of two revisions",
+					Parameters: []APIParameter{
+						{
+							Name:        "from",
+							In:          "query",
+							Required:    true,
+							Schema:      APISchema{Type: "integer"},
+							Description: "Version of the first revision",
+						},
+						{
+							Name:        "to",
+							In:          "query",
+							Required:    true,
+							Schema:      APISchema{Type: "integer"},
+							Description: "Version of the second revision",
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Pack sizes added and removed between the revisions",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackSizesDiff",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid versions",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Revision not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/pack-sizes/analysis": {
 				"post": {
-					Summary:     "Calculate Pack Distribution",
-					Description: "Calculate the optimal pack distribution for a given quantity. Rules: 1) Only whole packs 2) Minimize total items 3) Minimize number of packs",
+					Summary:     "Analyze Pack Sizes",
+					Description: "Report which quantities a pack size set reaches exactly (GCD, Frobenius number, conductor), the overage it causes over a range of quantities and the sizes no optimal breakdown of the range uses. The body is optional: without pack sizes the profile (default when not given) is analyzed, and without a range every quantity up to the conductor plus the largest pack",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: false,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/AnalysisRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Pack size set analysis",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackSizesAnalysis",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid pack sizes or range",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"422": {
+							Description: "No pack sizes configured, or a range too large to analyze",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/pack-sizes/compare": {
+				"post": {
+					Summary:     "Compare Pack Sizes",
+					Description: "Show the impact of a proposed pack size set before saving it: every quantity, listed or in a range, is calculated with the current pack sizes of the profile (default when not given) and with the proposed ones. Per quantity and in total, the differences in items shipped, overage and packs are proposed minus current, so negative values are savings",
+					Parameters:  []APIParameter{idempotencyKey},
 					RequestBody: &APIRequestBody{
 						Required: true,
 						Content: map[string]APIContent{
 							"application/json": {
 								Schema: APISchema{
-									Ref: "#/components/schemas/PackRequest",
+									Ref: "#/components/schemas/ComparisonRequest",
 								},
 							},
 						},
 					},
 					Responses: map[string]APIResponse{
 						"200": {
-							Description: "Calculation successful",
+							Description: "Comparison of the current (base) and proposed (candidate) pack sizes",
 							Content: map[string]APIContent{
 								"application/json": {
 									Schema: APISchema{
-										Ref: "#/components/schemas/PackResponse",
+										Ref: "#/components/schemas/Comparison",
 									},
 								},
 							},
 						},
 						"400": {
-							Description: "Invalid request or calculation failed",
+							Description: "Invalid pack sizes or quantities",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"422": {
+							Description: "No current pack sizes, or a quantity too large to calculate",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/recommendations": {
+				"post": {
+					Summary:     "Recommend Pack Sizes",
+					Description: "Start a background search for the set of at most max_sizes pack sizes, chosen from min_size to max_size in steps of step, that packs a history of order quantities with the lowest score: the total overage times overage_weight plus the total packs times pack_weight (only the overage when both are 0). The job is answered with its Location; poll it for progress and for the result, compared with the current default pack sizes",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/RecommendationRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"202": {
+							Description: "Job queued",
 							Content: map[string]APIContent{
 								"application/json": {
 									Schema: APISchema{
-										Ref: "#/components/schemas/ErrorResponse",
+										Ref: "#/components/schemas/RecommendationJob",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid orders, candidate range or weights",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"429": {
+							Description: "Too many recommendations already running",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
 									},
 								},
 							},
@@ -223,79 +456,1812 @@ func GetAPIDocumentation() APIDocumentation {
 					},
 				},
 			},
-		},
-		Components: APIComponents{
-			Schemas: map[string]APISchema{
-				"PackRequest": {
-					Type: "object",
-					Properties: map[string]APIProperty{
-						"quantity": {
-							Type:        "integer",
-							Description: "Number of items to order",
-							Example:     251,
-							Minimum:     &minOne,
+			"/api/recommendations/{id}": {
+				"get": {
+					Summary:     "Get Pack Size Recommendation",
+					Description: "Get the status and progress of a recommendation job, with its result once it succeeded or its error code once it failed",
+					Parameters: []APIParameter{
+						{
+							Name:        "id",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "string"},
+							Description: "Job ID returned when the job was started",
 						},
-						"pack_sizes": {
-							Type:        "array",
-							Description: "Optional custom pack sizes (if not provided, uses configured pack sizes)",
-							Example:     []int{250, 500, 1000},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Recommendation job",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/RecommendationJob",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Job not found, or no longer kept",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
 						},
 					},
-					Required: []string{"quantity"},
 				},
-				"PackResponse": {
-					Type: "object",
-					Properties: map[string]APIProperty{
-						"quantity": {
-							Type:        "integer",
-							Description: "Original requested quantity",
-							Example:     251,
+			},
+			"/api/quotes": {
+				"get": {
+					Summary:     "List Quotes",
+					Description: "List the quotes recorded for successful calculations and order lines (not batch or CSV rows), newest first, among the latest PACK_QUOTE_LIMIT kept. Every filter is optional; total counts every matching quote, so limit and offset page through them",
+					Parameters: []APIParameter{
+						{
+							Name:        "profile",
+							In:          "query",
+							Schema:      APISchema{Type: "string"},
+							Description: "Only quotes of this profile",
 						},
-						"total_items": {
-							Type:        "integer",
-							Description: "Total items that will be shipped",
-							Example:     500,
+						{
+							Name:        "min_quantity",
+							In:          "query",
+							Schema:      APISchema{Type: "integer"},
+							Description: "Only quotes of at least this quantity",
 						},
-						"total_packs": {
-							Type:        "integer",
-							Description: "Total number of packs",
-							Example:     1,
+						{
+							Name:        "max_quantity",
+							In:          "query",
+							Schema:      APISchema{Type: "integer"},
+							Description: "Only quotes of at most this quantity",
 						},
-						"pack_breakdown": {
-							Type:        "object",
-							Description: "Breakdown of packs by size",
-							Example:     map[string]int{"500": 1},
+						{
+							Name:        "since",
+							In:          "query",
+							Schema:      APISchema{Type: "string"},
+							Description: "Only quotes created at or after this RFC 3339 time",
 						},
-						"pack_sizes_used": {
-							Type:        "array",
-							Description: "Pack sizes that were used for calculation",
-							Example:     []int{250, 500, 1000},
+						{
+							Name:        "until",
+							In:          "query",
+							Schema:      APISchema{Type: "string"},
+							Description: "Only quotes created at or before this RFC 3339 time",
+						},
+						{
+							Name:        "pack_sizes_version",
+							In:          "query",
+							Schema:      APISchema{Type: "integer"},
+							Description: "Only quotes calculated with this revision of the default pack sizes",
+						},
+						{
+							Name:        "limit",
+							In:          "query",
+							Schema:      APISchema{Type: "integer"},
+							Description: "Quotes per page, from 1 to 100 (default: 20)",
+						},
+						{
+							Name:        "offset",
+							In:          "query",
+							Schema:      APISchema{Type: "integer"},
+							Description: "Matching quotes skipped before the page",
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Page of matching quotes",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/QuoteList",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Unreadable or invalid filter",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
 						},
 					},
 				},
-				"UpdatePackSizesRequest": {
-					Type: "object",
-					Properties: map[string]APIProperty{
-						"pack_sizes": {
-							Type:        "array",
-							Description: "Array of pack sizes (positive integers)",
-							Example:     []int{250, 500, 1000, 2000, 5000},
+			},
+			"/api/quotes/{id}": {
+				"get": {
+					Summary:     "Get Quote",
+					Description: "Get a recorded calculation by the quote_id of its response: when it was made, the revision of the default pack sizes it used, the request and the response",
+					Parameters: []APIParameter{
+						{
+							Name:        "id",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "string"},
+							Description: "Quote ID returned as quote_id by the calculation",
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Quote",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/Quote",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Quote not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
 						},
 					},
-					Required: []string{"pack_sizes"},
 				},
-				"ErrorResponse": {
-					Type: "object",
-					Properties: map[string]APIProperty{
-						"error": {
-							Type:        "string",
-							Description: "Error message",
-							Example:     "Invalid request",
+			},
+			"/api/pack-sizes/revisions/{version}/rollback": {
+				"post": {
+					Summary:     "Roll Back Pack Sizes",
+					Description: "Restore the pack sizes of an earlier revision. The rollback is recorded as a new revision",
+					Parameters: []APIParameter{
+						{
+							Name:        "version",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "integer"},
+							Description: "Version of the revision to restore",
 						},
-						"message": {
-							Type:        "string",
-							Description: "Detailed error message",
-							Example:     "quantity must be greater than 0",
+						idempotencyKey,
+					},
+					RequestBody: &APIRequestBody{
+						Required: false,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Type: "object",
+									Properties: map[string]APIProperty{
+										"author": {
+											Type:    "string",
+											Example: "alice",
+										},
+										"reason": {
+											Type:    "string",
+											Example: "750 boxes are not in the warehouse yet",
+										},
+									},
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Pack sizes restored",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"message": {
+												Type:    "string",
+												Example: "Pack sizes rolled back to revision 1",
+											},
+											"pack_sizes": {
+												Type:    "array",
+												Example: []int{250, 500, 1000},
+											},
+											"version": {
+												Type:    "integer",
+												Example: 3,
+											},
+										},
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Revision not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/stock": {
+				"get": {
+					Summary:     "Get Stock",
+					Description: "Retrieve the number of packs available per pack size. Sizes without an entry are unlimited",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Available packs per size",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"stock": {
+												Type:    "object",
+												Example: map[string]int{"5000": 2},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				"put": {
+					Summary:     "Update Stock",
+					Description: "Replace the number of packs available per pack size. An empty object removes all limits",
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/UpdateStockRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Stock updated successfully",
+						},
+						"400": {
+							Description: "Invalid request",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/pack-costs": {
+				"get": {
+					Summary:     "Get Pack Costs",
+					Description: "Retrieve the cost of one pack per pack size. Sizes without an entry cost nothing",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Cost per pack size",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"pack_costs": {
+												Type:    "object",
+												Example: map[string]int{"250": 40, "5000": 300},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				"put": {
+					Summary:     "Update Pack Costs",
+					Description: "Replace the cost of one pack per pack size. An empty object removes all costs",
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/UpdatePackCostsRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Pack costs updated successfully",
+						},
+						"400": {
+							Description: "Invalid request",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/cache/stats": {
+				"get": {
+					Summary:     "Get Cache Statistics",
+					Description: "Statistics of the result cache in front of the calculator. Results are keyed on the quantity and the normalized pack sizes, and the cache is cleared whenever the pack sizes change. enabled is false when PACK_CACHE_SIZE is 0",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Cache statistics",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/CacheStats",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/policies": {
+				"get": {
+					Summary:     "Get Policies",
+					Description: "List the named policies that rank pack distributions. Each criterion only breaks ties left by the previous ones",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Available policies",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"policies": {
+												Type: "array",
+												Example: []map[string]interface{}{
+													{"name": "default", "criteria": []string{"items_over", "pack_count"}},
+													{"name": "fewest-packs", "criteria": []string{"pack_count", "items_over", "cost"}},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/profiles": {
+				"get": {
+					Summary:     "List Profiles",
+					Description: "List the named pack size profiles, including the default profile behind /api/pack-sizes, /api/stock and /api/pack-costs",
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Available profiles",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Type: "object",
+										Properties: map[string]APIProperty{
+											"profiles": {
+												Type: "array",
+												Example: []map[string]interface{}{
+													{"name": "default", "pack_sizes": []int{250, 500, 1000, 2000, 5000}},
+													{"name": "bolts", "pack_sizes": []int{50, 100}},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				"post": {
+					Summary:     "Create Profile",
+					Description: "Create a named pack size profile with optional stock and pack costs",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/PackProfile",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"201": {
+							Description: "Profile created",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackProfile",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid profile",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"409": {
+							Description: "Profile already exists",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/profiles/{name}": {
+				"get": {
+					Summary: "Get Profile",
+					Parameters: []APIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "string"},
+							Description: "Profile name",
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Profile found",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackProfile",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+				"put": {
+					Summary:     "Update Profile",
+					Description: "Replace the pack sizes, stock and pack costs of a profile. The name comes from the path",
+					Parameters: []APIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "string"},
+							Description: "Profile name",
+						},
+					},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/PackProfile",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Profile updated",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackProfile",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid profile",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+				"delete": {
+					Summary:     "Delete Profile",
+					Description: "Delete a profile. The default profile cannot be deleted",
+					Parameters: []APIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Required:    true,
+							Schema:      APISchema{Type: "string"},
+							Description: "Profile name",
+						},
+					},
+					Responses: map[string]APIResponse{
+						"204": {
+							Description: "Profile deleted",
+						},
+						"400": {
+							Description: "The default profile cannot be deleted",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/calculate": {
+				"post": {
+					Summary:     "Calculate Pack Distribution",
+					Description: "Calculate the optimal pack distribution for a given quantity. Rules: 1) Only whole packs 2) Minimize total items 3) Minimize number of packs",
+					Parameters: []APIParameter{
+						{
+							Name:        "explain",
+							In:          "query",
+							Schema:      APISchema{Type: "boolean"},
+							Description: "Same as explain in the body: include a trace of why the breakdown was chosen",
+						},
+						idempotencyKey,
+					},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/PackRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Calculation successful",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackResponse",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid request (code invalid_request or validation_failed)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found (code profile_not_found)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"409": {
+							Description: "Not enough stock to cover the quantity (code insufficient_stock) or a request with the same Idempotency-Key still in progress (code idempotency_key_in_use)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"413": {
+							Description: "Quantity above the configured maximum (code quantity_too_large)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"422": {
+							Description: "No pack sizes, no distribution within the overage limit, quantity unreachable or calculation tables above the configured size (codes no_pack_sizes, overage_limit, unreachable, table_too_large), or the Idempotency-Key sent with a different request (code idempotency_key_reused)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"500": {
+							Description: "Repository or internal failure (codes repository_failure, internal_error)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"503": {
+							Description: "Calculation ran longer than the configured timeout or the client went away (codes calculation_timeout, calculation_canceled)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/v2/calculate": {
+				"post": {
+					Summary:     "Calculate Pack Distribution (v2)",
+					Description: "Same calculation and request as /api/calculate, answered with a stable schema: the packs are an array ordered from the largest size down, with the overage, the pack sizes revision and the algorithm used. /api/calculate keeps its response",
+					Parameters: []APIParameter{
+						{
+							Name:        "explain",
+							In:          "query",
+							Schema:      APISchema{Type: "boolean"},
+							Description: "Same as explain in the body: include a trace of why the breakdown was chosen",
+						},
+						idempotencyKey,
+					},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/PackRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Calculation successful",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/PackResponseV2",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid request (code invalid_request or validation_failed)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"404": {
+							Description: "Profile not found (code profile_not_found)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"409": {
+							Description: "Not enough stock to cover the quantity (code insufficient_stock) or a request with the same Idempotency-Key still in progress (code idempotency_key_in_use)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"413": {
+							Description: "Quantity above the configured maximum (code quantity_too_large)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"422": {
+							Description: "No pack sizes, no distribution within the overage limit, quantity unreachable or calculation tables above the configured size (codes no_pack_sizes, overage_limit, unreachable, table_too_large), or the Idempotency-Key sent with a different request (code idempotency_key_reused)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"500": {
+							Description: "Repository or internal failure (codes repository_failure, internal_error)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"503": {
+							Description: "Calculation ran longer than the configured timeout or the client went away (codes calculation_timeout, calculation_canceled)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/calculate/batch": {
+				"post": {
+					Summary:     "Calculate Batch",
+					Description: "Calculate many PackRequests at once with bounded concurrency. A JSON array is answered with every result at once; an NDJSON body (application/x-ndjson, one request per line) is answered with one NDJSON result per request, streamed in request order. Failing requests report an error without failing the batch. A batch holds at most 10000 requests; an NDJSON request past the limit is answered with an invalid_request result that ends the stream",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Type:  "array",
+									Items: &APISchema{Ref: "#/components/schemas/PackRequest"},
+								},
+							},
+							"application/x-ndjson": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/PackRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Results in request order",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/BatchResponse",
+									},
+								},
+								"application/x-ndjson": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/BatchItemResult",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid request, empty batch or more than 10000 requests",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"409": {
+							Description: "A request with the same Idempotency-Key still in progress (code idempotency_key_in_use)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"422": {
+							Description: "Idempotency-Key sent with a different request (code idempotency_key_reused)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/calculate/csv": {
+				"post": {
+					Summary:     "Calculate CSV",
+					Description: "Calculate every order of a CSV with rows order_id,quantity[,pack_sizes|profile], sent as the body or as the file field of a multipart form. The optional third column is read as pack sizes when it lists numbers (e.g. 50|100) and as a profile name otherwise; a first row starting with order_id is a header. Returns a CSV with one column per pack size (0 when a calculated row sends none) plus total_items, overage and total_packs; rows that fail are reported in the error column. Cells starting with =, +, -, @, a tab or a carriage return are prefixed with '",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"text/csv": {
+								Schema: APISchema{
+									Type: "string",
+								},
+							},
+							"multipart/form-data": {
+								Schema: APISchema{
+									Type: "object",
+									Properties: map[string]APIProperty{
+										"file": {
+											Type:        "string",
+											Description: "CSV file with one order per row",
+										},
+									},
+									Required: []string{"file"},
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "CSV download, e.g. order_id,quantity,pack_250,pack_500,total_items,overage,total_packs,error",
+							Content: map[string]APIContent{
+								"text/csv": {
+									Schema: APISchema{
+										Type: "string",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Missing file, CSV without orders or with more than 10000",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+						"413": {
+							Description: "Upload above 16 MiB (code body_too_large)",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/api/orders/calculate": {
+				"post": {
+					Summary:     "Calculate Multi-Line Order",
+					Description: "Calculate pack distributions for several products at once. Each line accepts the same fields as /api/calculate plus a sku, and is validated on its own: failing lines report an error without failing the order",
+					Parameters:  []APIParameter{idempotencyKey},
+					RequestBody: &APIRequestBody{
+						Required: true,
+						Content: map[string]APIContent{
+							"application/json": {
+								Schema: APISchema{
+									Ref: "#/components/schemas/OrderRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]APIResponse{
+						"200": {
+							Description: "Per-line results and order totals",
+							Content: map[string]APIContent{
+								"application/json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/OrderResponse",
+									},
+								},
+							},
+						},
+						"400": {
+							Description: "Invalid request",
+							Content: map[string]APIContent{
+								"application/problem+json": {
+									Schema: APISchema{
+										Ref: "#/components/schemas/ProblemDetails",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Components: APIComponents{
+			Schemas: map[string]APISchema{
+				"PackRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"quantity": {
+							Type:        "integer",
+							Description: "Number of items to order",
+							Example:     251,
+							Minimum:     &minOne,
+						},
+						"profile": {
+							Type:        "string",
+							Description: "Optional profile name from /api/profiles (if not provided, uses the default profile)",
+							Example:     "bolts",
+						},
+						"pack_sizes": {
+							Type:        "array",
+							Description: "Optional custom pack sizes (if not provided, uses configured pack sizes)",
+							Example:     []int{250, 500, 1000},
+						},
+						"stock": {
+							Type:        "object",
+							Description: "Optional packs available per size (if not provided, uses configured stock for configured pack sizes)",
+							Example:     map[string]int{"1000": 1},
+						},
+						"pack_costs": {
+							Type:        "object",
+							Description: "Optional cost of one pack per size in minor currency units (if not provided, uses configured pack costs)",
+							Example:     map[string]int{"250": 40, "500": 70, "1000": 120},
+						},
+						"objective": {
+							Type:        "string",
+							Description: "What to minimize after the least items: packs (default, costs are only reported) or cost (pack count breaks ties)",
+							Example:     "packs",
+						},
+						"policy": {
+							Type:        "string",
+							Description: "Optional named policy from /api/policies (cannot be combined with objective)",
+							Example:     "fewest-packs",
+						},
+						"max_overage_percent": {
+							Type:        "number",
+							Description: "Optional limit on items shipped above the quantity, as a percentage of the quantity",
+							Example:     10,
+						},
+						"alternatives": {
+							Type:        "integer",
+							Description: "Optional number of distinct distributions to list, ranked by the policy within the stock, rank 1 being the breakdown (at most 10)",
+							Example:     3,
+						},
+						"explain": {
+							Type:        "boolean",
+							Description: "Optional trace of why the breakdown was chosen; the breakdown then comes from the dynamic calculator (default policy and unlimited stock only)",
+							Example:     true,
+						},
+					},
+					Required: []string{"quantity"},
+				},
+				"PackResponse": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"quantity": {
+							Type:        "integer",
+							Description: "Original requested quantity",
+							Example:     251,
+						},
+						"total_items": {
+							Type:        "integer",
+							Description: "Total items that will be shipped",
+							Example:     500,
+						},
+						"total_packs": {
+							Type:        "integer",
+							Description: "Total number of packs",
+							Example:     1,
+						},
+						"pack_breakdown": {
+							Type:        "object",
+							Description: "Breakdown of packs by size",
+							Example:     map[string]int{"500": 1},
+						},
+						"pack_sizes_used": {
+							Type:        "array",
+							Description: "Pack sizes that were used for calculation",
+							Example:     []int{250, 500, 1000},
+						},
+						"total_cost": {
+							Type:        "integer",
+							Description: "Total cost of the packs (omitted when no costs are known)",
+							Example:     70,
+						},
+						"policy": {
+							Type:        "string",
+							Description: "Name of the policy that ranked the distribution",
+							Example:     "default",
+						},
+						"alternatives": {
+							Type:        "array",
+							Description: "Ranked alternative distributions, only when requested. Rank 1 is an optimal one",
+							Example: []map[string]interface{}{
+								{"rank": 1, "pack_breakdown": map[string]int{"1000": 1}, "total_items": 1000, "items_over": 0, "total_packs": 1},
+								{"rank": 2, "pack_breakdown": map[string]int{"500": 2}, "total_items": 1000, "items_over": 0, "total_packs": 2},
+							},
+						},
+						"explanation": {
+							Type:        "object",
+							Description: "Trace of why the breakdown was chosen, only when requested: the least reachable amount, the amounts rejected around it and the pack counts compared for it",
+							Example: map[string]interface{}{
+								"minimum_amount": 500,
+								"candidates": []map[string]interface{}{
+									{"from": 251, "to": 499, "reachable": false, "chosen": false, "reason": "no combination of pack sizes adds up to these amounts"},
+									{"from": 500, "to": 500, "reachable": true, "chosen": true, "reason": "least amount of items that fulfills the order"},
+									{"from": 750, "to": 750, "reachable": true, "chosen": false, "reason": "sends 250 more items than 500"},
+								},
+								"pack_counts": []map[string]interface{}{
+									{"last_pack": 250, "total_packs": 2, "pack_breakdown": map[string]int{"250": 2}, "chosen": false, "reason": "uses 2 packs instead of 1"},
+									{"last_pack": 500, "total_packs": 1, "pack_breakdown": map[string]int{"500": 1}, "chosen": true, "reason": "fewest packs to send 500 items"},
+								},
+							},
+						},
+						"quote_id": {
+							Type:        "string",
+							Description: "ID of the quote the calculation was recorded as, for GET /api/quotes/{id}; batch and CSV results have none",
+							Example:     "3f2a9c1d7b6e4085",
+						},
+					},
+				},
+				"PackResponseV2": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"quantity": {
+							Type:        "integer",
+							Description: "Original requested quantity",
+							Example:     501,
+						},
+						"packs": {
+							Type:        "array",
+							Description: "Packs to send per size (see Pack), largest size first; sizes without packs are left out",
+							Example: []map[string]int{
+								{"pack_size": 500, "count": 1, "items": 500},
+								{"pack_size": 250, "count": 1, "items": 250},
+							},
+						},
+						"total_items": {
+							Type:        "integer",
+							Description: "Total items that will be shipped",
+							Example:     750,
+						},
+						"total_packs": {
+							Type:        "integer",
+							Description: "Total number of packs",
+							Example:     2,
+						},
+						"overage": {
+							Type:        "integer",
+							Description: "Items shipped beyond the quantity",
+							Example:     249,
+						},
+						"overage_percent": {
+							Type:        "number",
+							Description: "Overage as a percentage of the quantity, rounded to two decimals",
+							Example:     49.7,
+						},
+						"pack_sizes_used": {
+							Type:        "array",
+							Description: "Pack sizes that were used for calculation",
+							Example:     []int{250, 500, 1000},
+						},
+						"pack_set_version": {
+							Type:        "integer",
+							Description: "Revision of the default pack sizes used (see /api/pack-sizes/revisions); omitted when the sizes came from the request or another profile",
+							Example:     3,
+						},
+						"algorithm": {
+							Type:        "string",
+							Description: "Calculator that chose the packs: dynamic or residue (PACK_ALGORITHM), or bounded when stock or a policy other than the default rules decided",
+							Example:     "dynamic",
+						},
+						"total_cost": {
+							Type:        "integer",
+							Description: "Total cost of the packs (omitted when no costs are known)",
+							Example:     70,
+						},
+						"policy": {
+							Type:        "string",
+							Description: "Name of the policy that ranked the distribution",
+							Example:     "default",
+						},
+						"alternatives": {
+							Type:        "array",
+							Description: "Ranked alternative distributions, only when requested, with their packs as an array. Rank 1 is an optimal one",
+							Example: []map[string]interface{}{
+								{"rank": 1, "packs": []map[string]int{{"pack_size": 1000, "count": 1, "items": 1000}}, "total_items": 1000, "overage": 0, "total_packs": 1},
+							},
+						},
+						"explanation": {
+							Type:        "object",
+							Description: "Trace of why the breakdown was chosen, only when requested, as in PackResponse",
+						},
+						"quote_id": {
+							Type:        "string",
+							Description: "ID of the quote the calculation was recorded as, for GET /api/quotes/{id}; batch and CSV results have none",
+							Example:     "3f2a9c1d7b6e4085",
+						},
+					},
+				},
+				"Pack": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_size": {
+							Type:        "integer",
+							Description: "Items in one pack",
+							Example:     500,
+						},
+						"count": {
+							Type:        "integer",
+							Description: "Number of packs of this size",
+							Example:     1,
+						},
+						"items": {
+							Type:        "integer",
+							Description: "Items in these packs (pack_size times count)",
+							Example:     500,
+						},
+					},
+				},
+				"UpdatePackSizesRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_sizes": {
+							Type:        "array",
+							Description: "Array of pack sizes (positive integers)",
+							Example:     []int{250, 500, 1000, 2000, 5000},
+						},
+						"author": {
+							Type:        "string",
+							Description: "Optional name of who makes the change, kept in the revision history",
+							Example:     "alice",
+						},
+						"reason": {
+							Type:        "string",
+							Description: "Optional reason for the change, kept in the revision history",
+							Example:     "added 2000 boxes",
+						},
+					},
+					Required: []string{"pack_sizes"},
+				},
+				"CacheStats": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"enabled": {
+							Type:        "boolean",
+							Description: "Whether results are cached",
+							Example:     true,
+						},
+						"capacity": {
+							Type:        "integer",
+							Description: "Largest number of cached results",
+							Example:     10000,
+						},
+						"ttl_seconds": {
+							Type:        "number",
+							Description: "How long a result stays cached, 0 until evicted",
+							Example:     600,
+						},
+						"entries": {
+							Type:        "integer",
+							Description: "Results cached now",
+							Example:     42,
+						},
+						"hits": {
+							Type:        "integer",
+							Description: "Calculations answered from the cache",
+							Example:     9000,
+						},
+						"misses": {
+							Type:        "integer",
+							Description: "Calculations that reached the calculator",
+							Example:     1000,
+						},
+						"hit_ratio": {
+							Type:        "number",
+							Description: "hits / (hits + misses)",
+							Example:     0.9,
+						},
+						"evictions": {
+							Type:        "integer",
+							Description: "Least recently used results dropped to make room",
+							Example:     0,
+						},
+						"expirations": {
+							Type:        "integer",
+							Description: "Results dropped because they were older than the TTL",
+							Example:     12,
+						},
+						"invalidations": {
+							Type:        "integer",
+							Description: "Times the cache was cleared because the pack sizes changed",
+							Example:     1,
+						},
+					},
+				},
+				"PackSizesDiff": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"from": {
+							Type:    "integer",
+							Example: 1,
+						},
+						"to": {
+							Type:    "integer",
+							Example: 2,
+						},
+						"from_pack_sizes": {
+							Type:    "array",
+							Example: []int{250, 500, 1000},
+						},
+						"to_pack_sizes": {
+							Type:    "array",
+							Example: []int{250, 750},
+						},
+						"added": {
+							Type:        "array",
+							Description: "Pack sizes only in the second revision",
+							Example:     []int{750},
+						},
+						"removed": {
+							Type:        "array",
+							Description: "Pack sizes only in the first revision",
+							Example:     []int{500, 1000},
+						},
+					},
+				},
+				"UpdateStockRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"stock": {
+							Type:        "object",
+							Description: "Packs available per pack size (non-negative integers)",
+							Example:     map[string]int{"2000": 3, "5000": 1},
+						},
+					},
+					Required: []string{"stock"},
+				},
+				"UpdatePackCostsRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_costs": {
+							Type:        "object",
+							Description: "Cost of one pack per pack size (non-negative integers)",
+							Example:     map[string]int{"250": 40, "500": 70, "1000": 120},
+						},
+					},
+					Required: []string{"pack_costs"},
+				},
+				"PackProfile": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"name": {
+							Type:        "string",
+							Description: "Profile name: letters, digits, '.', '_' or '-', up to 64 characters",
+							Example:     "bolts",
+						},
+						"pack_sizes": {
+							Type:        "array",
+							Description: "Pack sizes of the profile (positive integers)",
+							Example:     []int{50, 100},
+						},
+						"stock": {
+							Type:        "object",
+							Description: "Optional packs available per pack size",
+							Example:     map[string]int{"100": 20},
+						},
+						"pack_costs": {
+							Type:        "object",
+							Description: "Optional cost of one pack per pack size",
+							Example:     map[string]int{"50": 5, "100": 9},
+						},
+					},
+					Required: []string{"name", "pack_sizes"},
+				},
+				"OrderRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"order_id": {
+							Type:        "string",
+							Description: "Optional order reference echoed in the response",
+							Example:     "SO-1001",
+						},
+						"lines": {
+							Type:        "array",
+							Description: "Order lines: sku plus any PackRequest field",
+							Example: []map[string]interface{}{
+								{"sku": "widget", "quantity": 501},
+								{"sku": "bolt", "quantity": 120, "pack_sizes": []int{50, 100}},
+							},
+						},
+					},
+					Required: []string{"lines"},
+				},
+				"OrderResponse": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"order_id": {
+							Type:        "string",
+							Description: "Order reference from the request",
+							Example:     "SO-1001",
+						},
+						"lines": {
+							Type:        "array",
+							Description: "One entry per line with either a result (PackResponse) or an error",
+							Example: []map[string]interface{}{
+								{"sku": "widget", "result": map[string]interface{}{"quantity": 501, "total_items": 750, "total_packs": 2}},
+								{"sku": "", "error": "sku is required"},
+							},
+						},
+						"totals": {
+							Type:        "object",
+							Description: "Totals over the successful lines",
+							Example:     map[string]int{"lines": 2, "failed_lines": 1, "total_quantity": 501, "total_items": 750, "total_packs": 2},
+						},
+					},
+				},
+				"BatchItemResult": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"index": {
+							Type:        "integer",
+							Description: "Position of the request in the batch, starting at 0",
+							Example:     0,
+						},
+						"result": {
+							Type:        "object",
+							Description: "PackResponse of a successful request",
+							Example:     map[string]interface{}{"quantity": 501, "total_items": 750, "total_packs": 2},
+						},
+						"error": {
+							Type:        "string",
+							Description: "Why the request failed",
+							Example:     "quantity must be greater than 0",
+						},
+						"code": {
+							Type:        "string",
+							Description: "Machine-readable error code of a failed request",
+							Example:     "validation_failed",
+						},
+					},
+				},
+				"BatchResponse": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"results": {
+							Type:        "array",
+							Description: "One BatchItemResult per request, in request order",
+							Example: []map[string]interface{}{
+								{"index": 0, "result": map[string]interface{}{"quantity": 501, "total_items": 750, "total_packs": 2}},
+								{"index": 1, "error": "quantity must be greater than 0", "code": "validation_failed"},
+							},
+						},
+						"totals": {
+							Type:        "object",
+							Description: "Number of requests and of failed requests",
+							Example:     map[string]int{"requests": 2, "failed": 1},
+						},
+					},
+				},
+				"AnalysisRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_sizes": {
+							Type:        "array",
+							Description: "Pack sizes to analyze (default: the sizes of the profile)",
+							Example:     []int{6, 9, 20},
+						},
+						"profile": {
+							Type:        "string",
+							Description: "Profile whose pack sizes are analyzed when pack_sizes is empty (default: default)",
+							Example:     "default",
+						},
+						"from": {
+							Type:        "integer",
+							Description: "First quantity of the range (default: 1)",
+							Example:     1,
+						},
+						"to": {
+							Type:        "integer",
+							Description: "Last quantity of the range (default: the conductor plus the largest pack, or from plus the largest pack when from is past the conductor)",
+							Example:     100,
+						},
+					},
+				},
+				"PackSizesAnalysis": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_sizes": {
+							Type:    "array",
+							Example: []int{6, 9, 20},
+						},
+						"gcd": {
+							Type:        "integer",
+							Description: "Greatest common divisor of the pack sizes; only its multiples are ever reached exactly",
+							Example:     1,
+						},
+						"all_reachable": {
+							Type:        "boolean",
+							Description: "Whether every quantity is reached exactly, i.e. gcd is 1 and there are no gaps",
+							Example:     false,
+						},
+						"frobenius_number": {
+							Type:        "integer",
+							Description: "Largest multiple of gcd the pack sizes cannot reach exactly, 0 when there is none",
+							Example:     43,
+						},
+						"conductor": {
+							Type:        "integer",
+							Description: "Quantity from which every multiple of gcd is reached exactly",
+							Example:     44,
+						},
+						"gaps": {
+							Type:        "integer",
+							Description: "Number of multiples of gcd that are never reached exactly",
+							Example:     22,
+						},
+						"quantities": {
+							Type:        "object",
+							Description: "Range analyzed with its maximum and average overage and the quantities sent without overage",
+							Example: map[string]interface{}{
+								"from": 1, "to": 64, "max_overage": 5, "max_overage_quantity": 1,
+								"average_overage": 0.578125, "exact_quantities": 42,
+							},
+						},
+						"sizes": {
+							Type:        "array",
+							Description: "Per pack size, the number of quantities of the range with an optimal breakdown using it",
+							Example: []map[string]interface{}{
+								{"size": 6, "quantities": 41, "redundant": false},
+								{"size": 9, "quantities": 45, "redundant": false},
+								{"size": 20, "quantities": 30, "redundant": false},
+							},
+						},
+						"redundant_sizes": {
+							Type:        "array",
+							Description: "Pack sizes no optimal breakdown of the range uses",
+							Example:     []int{},
+						},
+					},
+				},
+				"ComparisonRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"pack_sizes": {
+							Type:        "array",
+							Description: "Proposed pack sizes",
+							Example:     []int{300, 600},
+						},
+						"profile": {
+							Type:        "string",
+							Description: "Profile whose pack sizes are the current ones (default: default)",
+							Example:     "default",
+						},
+						"quantities": {
+							Type:        "array",
+							Description: "Quantities to compare, at most 10000; instead of from, to and step",
+							Example:     []int{1, 251, 501, 750},
+						},
+						"from": {
+							Type:        "integer",
+							Description: "First quantity of the range (default: 1)",
+							Example:     1,
+						},
+						"to": {
+							Type:        "integer",
+							Description: "Last quantity of the range, required without quantities",
+							Example:     1000,
+						},
+						"step": {
+							Type:        "integer",
+							Description: "Step between the quantities of the range (default: 1)",
+							Example:     1,
+						},
+						"summary": {
+							Type:        "boolean",
+							Description: "Leave the comparison of each quantity out of the response",
+							Example:     false,
+						},
+					},
+				},
+				"Comparison": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"base_pack_sizes": {
+							Type:        "array",
+							Description: "Current pack sizes",
+							Example:     []int{250, 500, 1000},
+						},
+						"candidate_pack_sizes": {
+							Type:        "array",
+							Description: "Proposed pack sizes",
+							Example:     []int{300, 600},
+						},
+						"quantities": {
+							Type:        "array",
+							Description: "Per quantity, the outcome of both sets and the differences; unreachable outcomes have no differences",
+							Example: []map[string]interface{}{
+								{
+									"quantity":   251,
+									"base":       map[string]interface{}{"pack_breakdown": map[string]int{"500": 1}, "total_items": 500, "overage": 249, "total_packs": 1},
+									"candidate":  map[string]interface{}{"pack_breakdown": map[string]int{"300": 1}, "total_items": 300, "overage": 49, "total_packs": 1},
+									"items_diff": -200, "overage_diff": -200, "packs_diff": 0,
+								},
+							},
+						},
+						"summary": {
+							Type:        "object",
+							Description: "Totals of both sets over the quantities they reach, summed differences and how many quantities the proposed set ships fewer, more or as many items, then packs, for",
+							Example: map[string]interface{}{
+								"quantities": 4,
+								"base": map[string]interface{}{
+									"total_items": 2250, "total_overage": 747, "max_overage": 249,
+									"average_overage": 186.75, "total_packs": 6, "unreachable": 0,
+								},
+								"candidate": map[string]interface{}{
+									"total_items": 2100, "total_overage": 597, "max_overage": 299,
+									"average_overage": 149.25, "total_packs": 5, "unreachable": 0,
+								},
+								"items_diff": -150, "overage_diff": -150, "packs_diff": -1,
+								"better": 2, "worse": 2, "same": 0,
+							},
+						},
+					},
+				},
+				"RecommendationRequest": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"orders": {
+							Type:        "array",
+							Description: "Historical order quantities and how many times each was ordered (count defaults to 1), at most 10000 entries; required unless history is given",
+							Example:     []map[string]int{{"quantity": 300, "count": 10}, {"quantity": 600, "count": 5}, {"quantity": 250, "count": 2}},
+						},
+						"history": {
+							Type:        "object",
+							Description: "Builds the orders from the recorded quotes instead, filtered like GET /api/quotes (profile, min_quantity, max_quantity, since, until, pack_sizes_version); every matching quote is one order and limit and offset are ignored. Cannot be combined with orders",
+							Example: map[string]interface{}{
+								"profile": "default",
+								"since":   "2024-01-01T00:00:00Z",
+							},
+						},
+						"min_size": {
+							Type:        "integer",
+							Description: "Smallest candidate pack size",
+							Example:     100,
+						},
+						"max_size": {
+							Type:        "integer",
+							Description: "Largest candidate pack size; the range holds at most 200 candidates",
+							Example:     600,
+						},
+						"step": {
+							Type:        "integer",
+							Description: "Step between candidate pack sizes (default: 1)",
+							Example:     100,
+						},
+						"max_sizes": {
+							Type:        "integer",
+							Description: "Most pack sizes the recommended set holds, from 1 to 10",
+							Example:     2,
+						},
+						"overage_weight": {
+							Type:        "integer",
+							Description: "Weight of the total overage in the score (default: 1 when pack_weight is 0 too)",
+							Example:     1,
+						},
+						"pack_weight": {
+							Type:        "integer",
+							Description: "Weight of the total packs in the score",
+							Example:     0,
+						},
+						"max_evaluations": {
+							Type:        "integer",
+							Description: "Most pack size sets evaluated, up to 20000 (default: 2000); beyond it a local search is used",
+							Example:     2000,
+						},
+					},
+				},
+				"RecommendationJob": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"id": {
+							Type:    "string",
+							Example: "9f86d081884c7d65",
+						},
+						"status": {
+							Type:        "string",
+							Description: "queued, running, succeeded or failed",
+							Example:     "succeeded",
+						},
+						"created_at": {
+							Type:    "string",
+							Example: "2024-01-15T10:30:00Z",
+						},
+						"started_at": {
+							Type:    "string",
+							Example: "2024-01-15T10:30:00Z",
+						},
+						"finished_at": {
+							Type:    "string",
+							Example: "2024-01-15T10:30:01Z",
+						},
+						"evaluated": {
+							Type:        "integer",
+							Description: "Pack size sets evaluated so far",
+							Example:     21,
+						},
+						"max_evaluations": {
+							Type:    "integer",
+							Example: 2000,
+						},
+						"result": {
+							Type:        "object",
+							Description: "Recommended and current pack sizes with their totals over the orders, the change from current to recommended, the candidates searched and whether every set of them was evaluated",
+							Example: map[string]interface{}{
+								"recommended": map[string]interface{}{
+									"pack_sizes": []int{300, 600}, "orders": 17, "total_items": 6600, "total_overage": 100,
+									"total_packs": 17, "average_overage": 5.88, "score": 100,
+								},
+								"current": map[string]interface{}{
+									"pack_sizes": []int{250, 500, 1000, 2000, 5000}, "orders": 17, "total_items": 9250, "total_overage": 2750,
+									"total_packs": 22, "average_overage": 161.76, "score": 2750,
+								},
+								"change":     map[string]int{"total_items": -2650, "total_overage": -2650, "total_packs": -5},
+								"candidates": []int{100, 200, 300, 400, 500, 600},
+								"exhaustive": true,
+							},
+						},
+						"error": {
+							Type:        "string",
+							Description: "Why the job failed",
+						},
+						"code": {
+							Type:        "string",
+							Description: "Error code of a failed job, as in ProblemDetails",
+						},
+					},
+				},
+				"Quote": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"id": {
+							Type:    "string",
+							Example: "3f2a9c1d7b6e4085",
+						},
+						"created_at": {
+							Type:    "string",
+							Example: "2024-01-15T10:30:00Z",
+						},
+						"profile": {
+							Type:    "string",
+							Example: "default",
+						},
+						"pack_sizes_version": {
+							Type:        "integer",
+							Description: "Revision of the default pack sizes used; omitted when the sizes came from the request or another profile",
+							Example:     3,
+						},
+						"request": {
+							Type:        "object",
+							Description: "The PackRequest as received",
+							Example:     map[string]interface{}{"quantity": 251},
+						},
+						"response": {
+							Type:        "object",
+							Description: "The PackResponse as returned, without its quote_id",
+							Example: map[string]interface{}{
+								"quantity": 251, "total_items": 500, "total_packs": 1,
+								"pack_breakdown": map[string]int{"500": 1}, "pack_sizes_used": []int{250, 500, 1000}, "policy": "default",
+							},
+						},
+					},
+				},
+				"QuoteList": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"quotes": {
+							Type:        "array",
+							Description: "Quotes of the page, newest first",
+							Example: []map[string]interface{}{
+								{"id": "3f2a9c1d7b6e4085", "created_at": "2024-01-15T10:30:00Z", "profile": "default", "pack_sizes_version": 3,
+									"request": map[string]int{"quantity": 251}, "response": map[string]int{"quantity": 251, "total_items": 500, "total_packs": 1}},
+							},
+						},
+						"total": {
+							Type:        "integer",
+							Description: "Quotes matching the filter, on every page",
+							Example:     1,
+						},
+						"limit": {
+							Type:    "integer",
+							Example: 20,
+						},
+						"offset": {
+							Type:    "integer",
+							Example: 0,
+						},
+					},
+				},
+				"ProblemDetails": {
+					Type: "object",
+					Properties: map[string]APIProperty{
+						"type": {
+							Type:        "string",
+							Description: "URI reference identifying the problem type, derived from the code",
+							Example:     "/problems/validation_failed",
+						},
+						"title": {
+							Type:        "string",
+							Description: "Short summary of the problem type",
+							Example:     "Validation failed",
+						},
+						"status": {
+							Type:        "integer",
+							Description: "HTTP status code",
+							Example:     400,
+						},
+						"detail": {
+							Type:        "string",
+							Description: "Explanation specific to this occurrence",
+							Example:     "quantity must be greater than 0, got: -1",
+						},
+						"instance": {
+							Type:        "string",
+							Description: "Request path and query that caused the problem",
+							Example:     "/api/calculate",
+						},
+						"code": {
+							Type:        "string",
+							Description: "Machine-readable error code: invalid_request, validation_failed (400), not_found, profile_not_found, revision_not_found, job_not_found (404), insufficient_stock, profile_exists (409), version_conflict (412), quantity_too_large, body_too_large (413), no_pack_sizes, unreachable, overage_limit, table_too_large (422), too_many_jobs (429), repository_failure, internal_error (500), calculation_timeout, calculation_canceled (503)",
+							Example:     "validation_failed",
+						},
+						"errors": {
+							Type:        "array",
+							Description: "One entry per invalid field, when the problem is about the request content",
+							Example:     []map[string]string{{"field": "quantity", "message": "must be greater than 0, got: -1"}},
 						},
 					},
 				},
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/service"
+	log "github.com/sirupsen/logrus"
+)
+
+// problemType is the status and title shared by every error with the same code
+type problemType struct {
+	status int
+	title  string
+}
+
+// problemTypes maps error codes to their HTTP status and title
+// Codes missing here are answered like internal errors
+var problemTypes = map[string]problemType{
+	model.CodeInvalidRequest:    {http.StatusBadRequest, "Invalid request"},
+	model.CodeValidationFailed:  {http.StatusBadRequest, "Validation failed"},
+	model.CodeNotFound:          {http.StatusNotFound, "Not found"},
+	model.CodeNoPackSizes:       {http.StatusUnprocessableEntity, "No pack sizes"},
+	model.CodeQuantityTooLarge:  {http.StatusRequestEntityTooLarge, "Quantity too large"},
+	model.CodeBodyTooLarge:      {http.StatusRequestEntityTooLarge, "Request body too large"},
+	model.CodeTableTooLarge:     {http.StatusUnprocessableEntity, "Calculation too large"},
+	model.CodeTimeout:           {http.StatusServiceUnavailable, "Calculation timed out"},
+	model.CodeCanceled:          {http.StatusServiceUnavailable, "Calculation canceled"},
+	model.CodeUnreachable:       {http.StatusUnprocessableEntity, "Quantity unreachable"},
+	model.CodeInsufficientStock: {http.StatusConflict, "Insufficient stock"},
+	model.CodeOverageLimit:      {http.StatusUnprocessableEntity, "Overage limit exceeded"},
+	model.CodeProfileNotFound:   {http.StatusNotFound, "Profile not found"},
+	model.CodeProfileExists:     {http.StatusConflict, "Profile already exists"},
+	model.CodeRevisionNotFound:  {http.StatusNotFound, "Revision not found"},
+	model.CodeVersionConflict:   {http.StatusPreconditionFailed, "Pack sizes were modified"},
+	model.CodeJobNotFound:       {http.StatusNotFound, "Job not found"},
+	model.CodeTooManyJobs:       {http.StatusTooManyRequests, "Too many jobs"},
+	model.CodeQuoteNotFound:     {http.StatusNotFound, "Quote not found"},
+	model.CodeIdempotencyInUse:  {http.StatusConflict, "Request in progress"},
+	model.CodeIdempotencyReused: {http.StatusUnprocessableEntity, "Idempotency key reused"},
+	model.CodeRepositoryFailure: {http.StatusInternalServerError, "Storage failure"},
+	model.CodeInternal:          {http.StatusInternalServerError, "Internal error"},
+}
+
+// Report binding errors with the JSON names of the fields
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName returns the JSON name of a struct field, empty for embedded structs
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// errRouteNotFound is recorded when no API route matches the request
+var errRouteNotFound = errors.New("no API endpoint matches this path")
+
+// requestError is a request the handler could not read, as opposed to a request
+// the service rejected
+type requestError struct {
+	message string
+	fields  []model.FieldError
+}
+
+func (e *requestError) Error() string { return e.message }
+
+// invalidRequest creates the error for a request that could not be read
+func invalidRequest(message string, fields ...model.FieldError) error {
+	return &requestError{message: message, fields: fields}
+}
+
+// invalidField creates the error for a request with one unreadable field
+func invalidField(field, message string) error {
+	return invalidRequest(field+" "+message, model.FieldError{Field: field, Message: message})
+}
+
+// bindingError converts a JSON binding error into an invalid request,
+// listing the offending fields when they are known
+func bindingError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]model.FieldError, len(validationErrs))
+		messages := make([]string, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = model.FieldError{Field: fieldPath(fieldErr), Message: tagMessage(fieldErr)}
+			messages[i] = fields[i].Field + " " + fields[i].Message
+		}
+		return invalidRequest(strings.Join(messages, "; "), fields...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := model.FieldError{Field: typeErr.Field, Message: fmt.Sprintf("must be %s, got: %s", typeErr.Type, typeErr.Value)}
+		return invalidRequest(field.Field+" "+field.Message, field)
+	}
+
+	return invalidRequest(err.Error())
+}
+
+// fieldPath returns the JSON path of a failed binding rule, e.g. lines[0].quantity
+// Embedded structs keep their Go name in the namespace, so upper case segments are skipped
+func fieldPath(fieldErr validator.FieldError) string {
+	segments := strings.Split(fieldErr.Namespace(), ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != "" && unicode.IsUpper(rune(segment[0])) {
+			continue
+		}
+		path = append(path, segment)
+	}
+	if len(path) == 0 {
+		return fieldErr.Field()
+	}
+	return strings.Join(path, ".")
+}
+
+// tagMessage describes a failed binding rule
+func tagMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// ProblemDetails is a middleware writing the error recorded by a handler with c.Error
+// as an RFC 7807 problem, so handlers only decide that a request failed, not how
+// the failure looks on the wire
+func (h *PackHandler) ProblemDetails(c *gin.Context) {
+	c.Next()
+	writeProblem(c)
+}
+
+// NotFound answers requests that match no route, with a problem under /api
+func (h *PackHandler) NotFound(c *gin.Context) {
+	if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	_ = c.Error(errRouteNotFound)
+	writeProblem(c)
+}
+
+// writeProblem logs the last error recorded on c and writes it unless a response was
+// written already
+// This is the only place API errors are logged: server errors as errors, request errors as warnings
+func writeProblem(c *gin.Context) {
+	if len(c.Errors) == 0 {
+		return
+	}
+
+	problem := newProblem(c.Errors.Last().Err)
+	problem.Instance = c.Request.URL.RequestURI()
+	entry := log.WithFields(log.Fields{
+		"status": problem.Status,
+		"code":   problem.Code,
+	})
+	if problem.Status >= http.StatusInternalServerError {
+		entry.Errorf("%s %s failed: %s", c.Request.Method, problem.Instance, problem.Detail)
+	} else {
+		entry.Warnf("%s %s rejected: %s", c.Request.Method, problem.Instance, problem.Detail)
+	}
+	if c.Writer.Written() {
+		return
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(problem.Status, model.ProblemContentType, body)
+}
+
+// newProblem describes err as problem details
+func newProblem(err error) model.ProblemDetails {
+	code := errorCode(err)
+	problemType, ok := problemTypes[code]
+	if !ok {
+		code = model.CodeInternal
+		problemType = problemTypes[code]
+	}
+
+	problem := model.NewProblemDetails(problemType.status, code, problemType.title, err.Error())
+
+	var reqErr *requestError
+	var validationErr *model.ValidationError
+	switch {
+	case errors.As(err, &reqErr):
+		problem.Errors = reqErr.fields
+	case errors.As(err, &validationErr):
+		problem.Errors = validationErr.Fields
+	}
+	return problem
+}
+
+// errorCode returns the machine-readable code of err
+// Errors raised by the handlers themselves are checked before service errors
+func errorCode(err error) string {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return model.CodeInvalidRequest
+	case errors.Is(err, errRouteNotFound):
+		return model.CodeNotFound
+	case errors.Is(err, errIdempotentBodyTooLarge), errors.Is(err, errCSVTooLarge):
+		return model.CodeBodyTooLarge
+	case errors.Is(err, errIdempotencyKeyInUse):
+		return model.CodeIdempotencyInUse
+	case errors.Is(err, errIdempotencyKeyReused):
+		return model.CodeIdempotencyReused
+	case errors.Is(err, errIdempotencyStore):
+		return model.CodeRepositoryFailure
+	default:
+		return service.ErrorCode(err)
+	}
+}
+
+// errorStatus returns the HTTP status code of an error, for the web UI
+func errorStatus(err error) int {
+	if problemType, ok := problemTypes[errorCode(err)]; ok {
+		return problemType.status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestProblemDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		serviceErr error
+		want       model.ProblemDetails
+	}{
+		{
+			name:   "Binding rules report JSON field names",
+			method: "POST",
+			path:   "/api/calculate",
+			body:   `{"quantity": 0}`,
+			want: model.ProblemDetails{
+				Type:     "/problems/invalid_request",
+				Title:    "Invalid request",
+				Status:   http.StatusBadRequest,
+				Detail:   "quantity is required",
+				Instance: "/api/calculate",
+				Code:     model.CodeInvalidRequest,
+				Errors:   []model.FieldError{{Field: "quantity", Message: "is required"}},
+			},
+		},
+		{
+			name:   "Binding rule parameters",
+			method: "POST",
+			path:   "/api/orders/calculate",
+			body:   `{"lines": []}`,
+			want: model.ProblemDetails{
+				Type:     "/problems/invalid_request",
+				Title:    "Invalid request",
+				Status:   http.StatusBadRequest,
+				Detail:   "lines must be at least 1",
+				Instance: "/api/orders/calculate",
+				Code:     model.CodeInvalidRequest,
+				Errors:   []model.FieldError{{Field: "lines", Message: "must be at least 1"}},
+			},
+		},
+		{
+			name:   "Wrong JSON type",
+			method: "PUT",
+			path:   "/api/pack-sizes",
+			body:   `{"pack_sizes": "250"}`,
+			want: model.ProblemDetails{
+				Type:     "/problems/invalid_request",
+				Title:    "Invalid request",
+				Status:   http.StatusBadRequest,
+				Detail:   "pack_sizes must be []int, got: string",
+				Instance: "/api/pack-sizes",
+				Code:     model.CodeInvalidRequest,
+				Errors:   []model.FieldError{{Field: "pack_sizes", Message: "must be []int, got: string"}},
+			},
+		},
+		{
+			name:       "Service validation lists every field",
+			method:     "PUT",
+			path:       "/api/pack-sizes",
+			body:       `{"pack_sizes": [250, 500, -1, 0]}`,
+			serviceErr: model.ValidatePackSizes([]int{250, 500, -1, 0}),
+			want: model.ProblemDetails{
+				Type:     "/problems/validation_failed",
+				Title:    "Validation failed",
+				Status:   http.StatusBadRequest,
+				Detail:   "pack_sizes[2] must be positive, got: -1; pack_sizes[3] must be positive, got: 0",
+				Instance: "/api/pack-sizes",
+				Code:     model.CodeValidationFailed,
+				Errors: []model.FieldError{
+					{Field: "pack_sizes[2]", Message: "must be positive, got: -1"},
+					{Field: "pack_sizes[3]", Message: "must be positive, got: 0"},
+				},
+			},
+		},
+		{
+			name:   "Query parameters",
+			method: "GET",
+			path:   "/api/pack-sizes/revisions/diff?from=1&to=last",
+			want: model.ProblemDetails{
+				Type:     "/problems/invalid_request",
+				Title:    "Invalid request",
+				Status:   http.StatusBadRequest,
+				Detail:   "from and to must be revision versions",
+				Instance: "/api/pack-sizes/revisions/diff?from=1&to=last",
+				Code:     model.CodeInvalidRequest,
+				Errors:   []model.FieldError{{Field: "to", Message: "must be a revision version"}},
+			},
+		},
+		{
+			name:   "Unknown API route",
+			method: "GET",
+			path:   "/api/unknown",
+			want: model.ProblemDetails{
+				Type:     "/problems/not_found",
+				Title:    "Not found",
+				Status:   http.StatusNotFound,
+				Detail:   "no API endpoint matches this path",
+				Instance: "/api/unknown",
+				Code:     model.CodeNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				updatePackSizesFunc: func(sizes []int) error { return tt.serviceErr },
+			}
+			handler := NewPackHandler(mockService)
+
+			router := gin.New()
+			router.NoRoute(handler.NotFound)
+			api := router.Group("/api", handler.ProblemDetails)
+			api.POST("/calculate", handler.CalculatePacks)
+			api.POST("/orders/calculate", handler.CalculateOrder)
+			api.PUT("/pack-sizes", handler.UpdatePackSizes)
+			api.GET("/pack-sizes/revisions/diff", handler.DiffPackSizesRevisions)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Type"); got != model.ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, model.ProblemContentType)
+			}
+			if w.Code != tt.want.Status {
+				t.Errorf("Expected status %d, got %d", tt.want.Status, w.Code)
+			}
+
+			var problem model.ProblemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Invalid problem details %s: %v", w.Body.String(), err)
+			}
+			if !reflect.DeepEqual(problem, tt.want) {
+				t.Errorf("Problem = %+v, want %+v", problem, tt.want)
+			}
+		})
+	}
+}
+
+func TestProblemDetails_LogsOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantLevel  log.Level
+	}{
+		{
+			name:      "Request errors are warnings",
+			body:      `{"quantity": 0}`,
+			wantLevel: log.WarnLevel,
+		},
+		{
+			name:       "Server errors are errors",
+			body:       `{"quantity": 251}`,
+			serviceErr: errors.New("disk full"),
+			wantLevel:  log.ErrorLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			handler := NewPackHandler(&mockPackService{
+				calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+					return nil, tt.serviceErr
+				},
+			})
+			router := gin.New()
+			router.POST("/api/calculate", handler.ProblemDetails, handler.CalculatePacks)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/calculate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			entries := hook.AllEntries()
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 log entry, got %d", len(entries))
+			}
+			if entries[0].Level != tt.wantLevel {
+				t.Errorf("Log level = %v, want %v", entries[0].Level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestNotFound_OutsideAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.NoRoute(NewPackHandler(&mockPackService{}).NotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") == model.ProblemContentType {
+		t.Errorf("Expected a plain 404 outside the API, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
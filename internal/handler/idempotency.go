@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers of idempotent requests
+const (
+	// IdempotencyKeyHeader carries the key that makes a POST request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key accepted
+const maxIdempotencyKey = 255
+
+// maxIdempotentBody is the largest body of a request sent with an Idempotency-Key; the
+// body is read whole to fingerprint the request
+const maxIdempotentBody = 16 << 20
+
+// replayedHeaders are the response headers stored and replayed with the body
+var replayedHeaders = []string{"Content-Type", "Content-Disposition", "Location", "ETag"}
+
+// Errors of requests sent with an Idempotency-Key
+var (
+	errIdempotencyKeyInUse    = errors.New("a request with this Idempotency-Key is still in progress")
+	errIdempotencyKeyReused   = errors.New("this Idempotency-Key was used for a different request")
+	errIdempotencyStore       = errors.New("idempotency store failure")
+	errIdempotentBodyTooLarge = fmt.Errorf("the body of a request with an Idempotency-Key cannot exceed %d MiB", maxIdempotentBody>>20)
+)
+
+// idempotency replays the first response to requests sent with the same Idempotency-Key
+type idempotency struct {
+	store repository.IdempotencyRepository
+	ttl   time.Duration
+
+	mu sync.Mutex
+	// inFlight holds the keys of requests still being answered
+	inFlight map[string]bool
+}
+
+// WithIdempotency returns a copy of the handler replaying the responses of POST requests
+// sent with an Idempotency-Key, stored in store for ttl
+// A ttl of 0 disables idempotency keys
+func (h *PackHandler) WithIdempotency(store repository.IdempotencyRepository, ttl time.Duration) *PackHandler {
+	clone := *h
+	clone.idempotency = nil
+	if store != nil && ttl > 0 {
+		clone.idempotency = &idempotency{store: store, ttl: ttl, inFlight: make(map[string]bool)}
+	}
+	return &clone
+}
+
+// Idempotency is a middleware making POST requests sent with an Idempotency-Key safe
+// to retry: the first successful response is stored, and a retry gets it back byte for
+// byte instead of running again
+// A retry while the first request runs gets 409, a different request reusing the key
+// gets 422 and a body above 16 MiB gets 413. Failed responses are not stored, so they
+// can be retried with the key
+func (h *PackHandler) Idempotency(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if h.idempotency == nil || c.Request.Method != http.MethodPost || key == "" {
+		c.Next()
+		return
+	}
+	if !validIdempotencyKey(key) {
+		abortWithProblem(c, invalidField(IdempotencyKeyHeader, fmt.Sprintf("must be 1 to %d visible ASCII characters", maxIdempotencyKey)))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+	if isBodyTooLarge(err) {
+		abortWithProblem(c, errIdempotentBodyTooLarge)
+		return
+	}
+	if err != nil {
+		abortWithProblem(c, invalidRequest(fmt.Sprintf("failed to read body: %v", err)))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := requestFingerprint(c.Request, body)
+
+	if !h.idempotency.acquire(key) {
+		abortWithProblem(c, errIdempotencyKeyInUse)
+		return
+	}
+	defer h.idempotency.release(key)
+
+	stored, ok, err := h.idempotency.store.GetResponse(key)
+	if err != nil {
+		abortWithProblem(c, fmt.Errorf("%w: %v", errIdempotencyStore, err))
+		return
+	}
+	if ok {
+		if stored.Fingerprint != fingerprint {
+			abortWithProblem(c, errIdempotencyKeyReused)
+			return
+		}
+		replayResponse(c, stored)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+	c.Writer = recorder.ResponseWriter
+
+	status := c.Writer.Status()
+	if len(c.Errors) > 0 || status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return
+	}
+	now := time.Now().UTC()
+	response := &model.IdempotentResponse{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      status,
+		Header:      make(map[string][]string),
+		Body:        recorder.body.Bytes(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(h.idempotency.ttl),
+	}
+	for _, name := range replayedHeaders {
+		if values := c.Writer.Header().Values(name); len(values) > 0 {
+			response.Header[name] = values
+		}
+	}
+	if err := h.idempotency.store.SaveResponse(response); err != nil {
+		log.Errorf("Failed to store the response to Idempotency-Key %s: %v", key, err)
+	}
+}
+
+// acquire marks key as in flight; it reports false when it already is
+func (i *idempotency) acquire(key string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.inFlight[key] {
+		return false
+	}
+	i.inFlight[key] = true
+	return true
+}
+
+// release marks key as answered
+func (i *idempotency) release(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.inFlight, key)
+}
+
+// validIdempotencyKey reports whether key is 1 to 255 visible ASCII characters
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKey {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return key != ""
+}
+
+// requestFingerprint identifies a request by its method, URI, content type and body
+func requestFingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n%s\n", request.Method, request.URL.RequestURI(), request.Header.Get("Content-Type"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replayResponse writes a stored response and stops the request
+func replayResponse(c *gin.Context, response *model.IdempotentResponse) {
+	for name, values := range response.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(response.Status)
+	_, _ = c.Writer.Write(response.Body)
+	c.Abort()
+}
+
+// abortWithProblem writes err as a problem and stops the request
+func abortWithProblem(c *gin.Context, err error) {
+	_ = c.Error(err)
+	writeProblem(c)
+	c.Abort()
+}
+
+// responseRecorder keeps a copy of the body written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+)
+
+// newIdempotentRouter serves the calculation, CSV and recommendation endpoints of handler the
+// way the API router wraps them
+func newIdempotentRouter(handler *PackHandler) *gin.Engine {
+	router := gin.New()
+	api := router.Group("/api", handler.Idempotency, handler.ProblemDetails)
+	api.POST("/calculate", handler.CalculatePacks)
+	api.POST("/calculate/csv", handler.CalculateCSV)
+	api.POST("/recommendations", handler.StartRecommendation)
+	api.GET("/pack-sizes", handler.GetPackSizes)
+	return router
+}
+
+func idempotentRequest(method, path, key, body string) *http.Request {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestPackHandler_Idempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type call struct {
+		path, key, body string
+		wantStatus      int
+		wantCode        string
+		wantReplay      bool
+	}
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		calls     []call
+		wantCalls int
+	}{
+		{
+			name: "Retry is replayed",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "Key reused for a different body",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/calculate", "order-1", `{"quantity": 252}`, http.StatusUnprocessableEntity, model.CodeIdempotencyReused, false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "Key reused for another endpoint",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/recommendations", "order-1", `{"quantity": 251}`, http.StatusUnprocessableEntity, model.CodeIdempotencyReused, false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "Failures are not stored",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 0}`, http.StatusBadRequest, model.CodeInvalidRequest, false},
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "Different keys",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/calculate", "order-2", `{"quantity": 251}`, http.StatusOK, "", false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "No key",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/calculate", "", `{"quantity": 251}`, http.StatusOK, "", false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "Invalid key",
+			ttl:  time.Hour,
+			calls: []call{
+				{"/api/calculate", "order 1", `{"quantity": 251}`, http.StatusBadRequest, model.CodeInvalidRequest, false},
+				{"/api/calculate", strings.Repeat("k", maxIdempotencyKey+1), `{"quantity": 251}`, http.StatusBadRequest, model.CodeInvalidRequest, false},
+			},
+		},
+		{
+			name: "Disabled",
+			calls: []call{
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+				{"/api/calculate", "order-1", `{"quantity": 251}`, http.StatusOK, "", false},
+			},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mockService := &mockPackService{
+				calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+					calls++
+					return &model.PackResponse{
+						Quantity:      request.Quantity,
+						TotalItems:    500,
+						TotalPacks:    1,
+						PackBreakdown: map[int]int{500: 1},
+						QuoteID:       strings.Repeat("q", calls),
+					}, nil
+				},
+			}
+			handler := NewPackHandler(mockService).WithIdempotency(repository.NewInMemoryIdempotencyRepository(), tt.ttl)
+			router := newIdempotentRouter(handler)
+
+			var first []byte
+			for i, call := range tt.calls {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, idempotentRequest("POST", call.path, call.key, call.body))
+
+				if w.Code != call.wantStatus {
+					t.Fatalf("Call %d: expected status %d, got %d: %s", i, call.wantStatus, w.Code, w.Body.String())
+				}
+				if call.wantCode != "" {
+					var problem model.ProblemDetails
+					if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != call.wantCode {
+						t.Errorf("Call %d: expected code %s, got %s", i, call.wantCode, w.Body.String())
+					}
+				}
+				if replayed := w.Header().Get(IdempotentReplayedHeader) == "true"; replayed != call.wantReplay {
+					t.Errorf("Call %d: expected replayed %v, got %v", i, call.wantReplay, replayed)
+				}
+				if call.wantReplay && !bytes.Equal(w.Body.Bytes(), first) {
+					t.Errorf("Call %d: expected the first body %s, got %s", i, first, w.Body.String())
+				}
+				if i == 0 {
+					first = w.Body.Bytes()
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d calculations, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestPackHandler_IdempotencyReplaysHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantHeaders []string
+	}{
+		{
+			name:        "Started job",
+			path:        "/api/recommendations",
+			contentType: "application/json",
+			body:        `{"orders": [{"quantity": 300}], "min_size": 100, "max_size": 600, "max_sizes": 1}`,
+			wantStatus:  http.StatusAccepted,
+			wantHeaders: []string{"Location", "Content-Type"},
+		},
+		{
+			name:        "CSV download",
+			path:        "/api/calculate/csv",
+			contentType: "text/csv",
+			body:        "order_id,quantity\nSO-1,501\n",
+			wantStatus:  http.StatusOK,
+			wantHeaders: []string{"Content-Disposition", "Content-Type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				startRecommendFunc: func(request *model.RecommendationRequest) (*model.RecommendationJob, error) {
+					return &model.RecommendationJob{ID: "abc123", Status: model.JobQueued}, nil
+				},
+				calculateBatchFunc: echoBatch,
+			}
+			handler := NewPackHandler(mockService).WithIdempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+			router := newIdempotentRouter(handler)
+
+			var responses []*httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				req := idempotentRequest("POST", tt.path, "key-1", tt.body)
+				req.Header.Set("Content-Type", tt.contentType)
+				router.ServeHTTP(w, req)
+				responses = append(responses, w)
+			}
+
+			first, replay := responses[0], responses[1]
+			if replay.Header().Get(IdempotentReplayedHeader) != "true" {
+				t.Errorf("Expected the second response to be replayed")
+			}
+			if replay.Code != tt.wantStatus || !bytes.Equal(replay.Body.Bytes(), first.Body.Bytes()) {
+				t.Errorf("Expected the first response %d %s, got %d %s", first.Code, first.Body.String(), replay.Code, replay.Body.String())
+			}
+			for _, name := range tt.wantHeaders {
+				if got, want := replay.Header().Get(name), first.Header().Get(name); got != want || want == "" {
+					t.Errorf("Expected %s %q, got %q", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_IdempotencyBodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	mockService := &mockPackService{
+		calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+			calls++
+			return &model.PackResponse{Quantity: request.Quantity}, nil
+		},
+	}
+	handler := NewPackHandler(mockService).WithIdempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+	router := newIdempotentRouter(handler)
+
+	body := `{"quantity": 251, "note": "` + strings.Repeat("x", maxIdempotentBody) + `"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, idempotentRequest("POST", "/api/calculate", "order-1", body))
+
+	var problem model.ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Code != http.StatusRequestEntityTooLarge || problem.Code != model.CodeBodyTooLarge {
+		t.Errorf("Expected 413 %s, got %d %s", model.CodeBodyTooLarge, w.Code, w.Body.String())
+	}
+	if calls != 0 {
+		t.Errorf("Expected no calculation, got %d", calls)
+	}
+}
+
+func TestPackHandler_IdempotencyInProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	mockService := &mockPackService{
+		calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+			close(started)
+			<-finish
+			return &model.PackResponse{Quantity: request.Quantity}, nil
+		},
+	}
+	handler := NewPackHandler(mockService).WithIdempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+	router := newIdempotentRouter(handler)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, idempotentRequest("POST", "/api/calculate", "order-1", `{"quantity": 251}`))
+		done <- w
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, idempotentRequest("POST", "/api/calculate", "order-1", `{"quantity": 251}`))
+	close(finish)
+
+	var problem model.ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Code != http.StatusConflict || problem.Code != model.CodeIdempotencyInUse {
+		t.Errorf("Expected 409 %s while the first request runs, got %d %s", model.CodeIdempotencyInUse, w.Code, w.Body.String())
+	}
+	if first := <-done; first.Code != http.StatusOK {
+		t.Errorf("Expected the first request to succeed, got %d %s", first.Code, first.Body.String())
+	}
+}
+
+func TestPackHandler_IdempotencyIgnoresOtherMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	mockService := &mockPackService{
+		getPackSizesFunc: func() ([]int, error) {
+			calls++
+			return []int{250, 500}, nil
+		},
+	}
+	handler := NewPackHandler(mockService).WithIdempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+	router := newIdempotentRouter(handler)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, idempotentRequest("GET", "/api/pack-sizes", "read-1", ""))
+		if w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("Expected a fresh 200, got %d replayed %q", w.Code, w.Header().Get(IdempotentReplayedHeader))
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 reads, got %d", calls)
+	}
+}
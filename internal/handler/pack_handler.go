@@ -1,8 +1,18 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/marcellribeiro/awesomeProject/internal/model"
@@ -13,6 +23,8 @@ import (
 // PackHandler handles HTTP requests for pack calculations
 type PackHandler struct {
 	service service.PackService
+	// idempotency replays responses to retried POST requests; nil disables it
+	idempotency *idempotency
 }
 
 // NewPackHandler creates a new pack handler instance
@@ -24,62 +36,764 @@ func NewPackHandler(service service.PackService) *PackHandler {
 
 // CalculatePacks handles POST /api/calculate
 func (h *PackHandler) CalculatePacks(c *gin.Context) {
+	if response, ok := h.calculate(c); ok {
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// CalculatePacksV2 handles POST /api/v2/calculate
+// It takes the same request as /api/calculate and answers with model.PackResponseV2
+func (h *PackHandler) CalculatePacksV2(c *gin.Context) {
+	if response, ok := h.calculate(c); ok {
+		c.JSON(http.StatusOK, model.NewPackResponseV2(response))
+	}
+}
+
+// calculate runs the calculation requested by c; ok is false when the error was set on c
+func (h *PackHandler) calculate(c *gin.Context) (response *model.PackResponse, ok bool) {
 	var request model.PackRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Errorf("Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, model.NewErrorResponse("Invalid request", err.Error()))
+		_ = c.Error(bindingError(err))
+		return nil, false
+	}
+
+	// ?explain=true is a shorthand for "explain": true in the body
+	if c.Query("explain") != "" {
+		explain, err := strconv.ParseBool(c.Query("explain"))
+		if err != nil {
+			_ = c.Error(invalidField("explain", "must be true or false"))
+			return nil, false
+		}
+		request.Explain = request.Explain || explain
+	}
+
+	response, err := h.service.CalculatePackDistribution(c.Request.Context(), &request)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	return response, true
+}
+
+// CalculateOrder handles POST /api/orders/calculate
+// Line errors are reported per line, so a partially failing order still returns 200
+func (h *PackHandler) CalculateOrder(c *gin.Context) {
+	var request model.OrderRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(bindingError(err))
 		return
 	}
 
-	response, err := h.service.CalculatePackDistribution(&request)
+	response, err := h.service.CalculateOrder(c.Request.Context(), &request)
 	if err != nil {
-		log.Errorf("Calculation failed: %v", err)
-		c.JSON(http.StatusBadRequest, model.NewErrorResponse("Calculation failed", err.Error()))
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// maxBatchLine is the longest NDJSON line accepted in a batch
+const maxBatchLine = 1 << 20
+
+// maxBatchRequests is the most requests a batch may hold, as a JSON array, NDJSON or CSV
+const maxBatchRequests = 10_000
+
+// errBatchTooLarge rejects a batch holding more than maxBatchRequests requests
+var errBatchTooLarge = invalidRequest(fmt.Sprintf("a batch cannot have more than %d requests", maxBatchRequests))
+
+// CalculateBatch handles POST /api/calculate/batch
+// A JSON array of requests is answered with every result at once. An NDJSON body is
+// answered with one NDJSON result per request, streamed in request order as they are
+// calculated. Request errors are reported per request, so a partially failing batch
+// still returns 200
+func (h *PackHandler) CalculateBatch(c *gin.Context) {
+	if c.ContentType() == model.NDJSONContentType {
+		h.calculateBatchStream(c)
+		return
+	}
+
+	items, err := readBatchArray(c.Request.Body)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	requests := make([]service.BatchRequest, len(items))
+	for i, item := range items {
+		requests[i] = decodeBatchRequest(item)
+	}
+
+	response := &model.BatchResponse{Results: make([]model.BatchItemResult, 0, len(items))}
+	err = h.service.CalculateBatch(c.Request.Context(), service.BatchOf(requests), func(result model.BatchItemResult) error {
+		response.AddResult(result)
+		return nil
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readBatchArray reads the elements of a JSON array body one by one, so a batch
+// holding too many requests is rejected without reading all of them
+func readBatchArray(body io.Reader) ([]json.RawMessage, error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil {
+		return nil, bindingError(err)
+	} else if token != json.Delim('[') {
+		return nil, invalidRequest("batch must be a JSON array of requests")
+	}
+
+	var items []json.RawMessage
+	for decoder.More() {
+		if len(items) == maxBatchRequests {
+			return nil, errBatchTooLarge
+		}
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, bindingError(err)
+		}
+		items = append(items, item)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, bindingError(err)
+	}
+	return items, nil
+}
+
+// calculateBatchStream calculates an NDJSON batch while it is being read and writes
+// each result as soon as the results before it were written
+// Once a result was written the status was sent: a batch that cannot be read further
+// ends with a failed result, and other errors can only be logged
+func (h *PackHandler) calculateBatchStream(c *gin.Context) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// HTTP/1.1 servers close the request body once the response is flushed,
+	// unless it is read and written at the same time
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		_ = c.Error(fmt.Errorf("failed to stream batch: %w", err))
+		return
+	}
+
+	requests := make(chan service.BatchRequest)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(requests)
+		readErr <- readBatchLines(ctx, c.Request.Body, requests)
+	}()
+
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	emit := func(result model.BatchItemResult) error {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", model.NDJSONContentType)
+			c.Status(http.StatusOK)
+		}
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		written++
+		return nil
+	}
+	err := h.service.CalculateBatch(ctx, requests, emit)
+
+	// A batch failing early leaves the reader in the middle of the body, which must not
+	// be read once the handler returns, so the read is stopped and waited for
+	if err != nil {
+		cancel()
+		stopReading(controller, c.Request.Body)
+	}
+	// A batch reaching the end of the requests stopped because they could not be read
+	if readErr := <-readErr; readErr != nil && err == nil {
+		err = invalidRequest(fmt.Sprintf("failed to read batch: %v", readErr))
+		if written > 0 {
+			err = emit(model.BatchItemResult{Index: written, Error: err.Error(), Code: model.CodeInvalidRequest})
+		}
+	}
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// stopReading makes a read of the request body in progress return
+// Closing a server request body waits for the read, so its deadline is moved instead
+func stopReading(controller *http.ResponseController, body io.Closer) {
+	if err := controller.SetReadDeadline(time.Now()); err != nil {
+		_ = body.Close()
+	}
+}
+
+// readBatchLines sends the request of every non-empty line of body until ctx is done
+// A line past maxBatchRequests is sent as a failed request and ends the batch; a body
+// that cannot be read further is returned as an error
+func readBatchLines(ctx context.Context, body io.Reader, requests chan<- service.BatchRequest) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxBatchLine)
+	for count := 0; scanner.Scan(); {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		request := decodeBatchRequest(line)
+		if count == maxBatchRequests {
+			request = service.BatchRequest{Err: errBatchTooLarge}
+		}
+		select {
+		case requests <- request:
+		case <-ctx.Done():
+			return nil
+		}
+		if count == maxBatchRequests {
+			return nil
+		}
+		count++
+	}
+	return scanner.Err()
+}
+
+// decodeBatchRequest reads one request of a batch
+func decodeBatchRequest(data []byte) service.BatchRequest {
+	var request model.PackRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return service.BatchRequest{Err: bindingError(err)}
+	}
+	return service.BatchRequest{Request: &request}
+}
+
+// csvContentType is the media type of CSV uploads and results
+const csvContentType = "text/csv"
+
+// csvFilename is the name suggested for downloaded CSV results
+const csvFilename = "pack-calculations.csv"
+
+// maxCSVBody is the largest CSV upload read; maxBatchRequests orders fit well within it
+const maxCSVBody = 16 << 20
+
+// errCSVTooLarge rejects a CSV upload above maxCSVBody
+var errCSVTooLarge = fmt.Errorf("a CSV upload cannot exceed %d MiB", maxCSVBody>>20)
+
+// CalculateCSV handles POST /api/calculate/csv
+// The CSV is the request body (text/csv) or the file field of a multipart form,
+// and the results are returned as a CSV download with one row per order
+func (h *PackHandler) CalculateCSV(c *gin.Context) {
+	results, err := h.calculateCSV(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", csvFilename))
+	c.Data(http.StatusOK, csvContentType+"; charset=utf-8", results)
+}
+
+// calculateCSV calculates every order of the uploaded CSV and returns the CSV results
+func (h *PackHandler) calculateCSV(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCSVBody)
+	body := c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if isBodyTooLarge(err) {
+			return nil, errCSVTooLarge
+		}
+		if err != nil {
+			return nil, invalidField("file", "is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, requests, err := service.ReadCSVRequests(body, maxBatchRequests)
+	switch {
+	case errors.Is(err, service.ErrTooManyCSVRows):
+		return nil, errBatchTooLarge
+	case isBodyTooLarge(err):
+		return nil, errCSVTooLarge
+	case err != nil:
+		return nil, invalidRequest(err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, invalidRequest("CSV has no orders")
+	}
+
+	results := make([]model.BatchItemResult, 0, len(rows))
+	err = h.service.CalculateBatch(c.Request.Context(), service.BatchOf(requests), func(result model.BatchItemResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteCSVResults(&buf, rows, results); err != nil {
+		return nil, fmt.Errorf("failed to write CSV results: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// isBodyTooLarge reports whether err comes from reading a request body past its limit
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
 // GetPackSizes handles GET /api/pack-sizes
+// The ETag header carries the version to send back in If-Match when updating
 func (h *PackHandler) GetPackSizes(c *gin.Context) {
-	sizes, err := h.service.GetAvailablePackSizes()
+	snapshot, err := h.service.GetPackSizesSnapshot()
 	if err != nil {
-		log.Errorf("Failed to get pack sizes: %v", err)
-		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("Failed to get pack sizes", err.Error()))
+		_ = c.Error(err)
 		return
 	}
 
+	c.Header("ETag", versionETag(snapshot.Version))
 	c.JSON(http.StatusOK, gin.H{
-		"pack_sizes": sizes,
+		"pack_sizes": snapshot.PackSizes,
+		"version":    snapshot.Version,
 	})
 }
 
 // UpdatePackSizes handles PUT /api/pack-sizes
+// With an If-Match header the update only applies if the pack sizes are still at that version
 func (h *PackHandler) UpdatePackSizes(c *gin.Context) {
 	var request struct {
 		PackSizes []int `json:"pack_sizes" binding:"required"`
+		model.PackSizesChange
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Errorf("Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, model.NewErrorResponse("Invalid request", err.Error()))
+		_ = c.Error(bindingError(err))
 		return
 	}
+	change := model.PackSizesChange{Author: request.Author, Reason: request.Reason}
 
-	if err := h.service.UpdatePackSizes(request.PackSizes); err != nil {
-		log.Errorf("Failed to update pack sizes: %v", err)
-		c.JSON(http.StatusBadRequest, model.NewErrorResponse("Failed to update pack sizes", err.Error()))
+	var snapshot model.PackSizesSnapshot
+	var err error
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && ifMatch != "*" {
+		snapshot, err = h.service.UpdatePackSizesIfMatch(request.PackSizes, parseVersionETag(ifMatch), change)
+	} else if err = h.service.UpdatePackSizes(request.PackSizes, change); err == nil {
+		snapshot, err = h.service.GetPackSizesSnapshot()
+	}
+
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
+	c.Header("ETag", versionETag(snapshot.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Pack sizes updated successfully",
-		"pack_sizes": request.PackSizes,
+		"pack_sizes": snapshot.PackSizes,
+		"version":    snapshot.Version,
+	})
+}
+
+// ListPackSizesRevisions handles GET /api/pack-sizes/revisions
+func (h *PackHandler) ListPackSizesRevisions(c *gin.Context) {
+	revisions, err := h.service.ListPackSizesRevisions()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+	})
+}
+
+// DiffPackSizesRevisions handles GET /api/pack-sizes/revisions/diff?from=1&to=2
+func (h *PackHandler) DiffPackSizesRevisions(c *gin.Context) {
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		var fields []model.FieldError
+		if fromErr != nil {
+			fields = append(fields, model.FieldError{Field: "from", Message: "must be a revision version"})
+		}
+		if toErr != nil {
+			fields = append(fields, model.FieldError{Field: "to", Message: "must be a revision version"})
+		}
+		_ = c.Error(invalidRequest("from and to must be revision versions", fields...))
+		return
+	}
+
+	diff, err := h.service.DiffPackSizesRevisions(from, to)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RollbackPackSizes handles POST /api/pack-sizes/revisions/:version/rollback
+// The body is optional and may carry the author and reason of the rollback
+func (h *PackHandler) RollbackPackSizes(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		_ = c.Error(invalidField("version", "must be a number"))
+		return
+	}
+
+	var change model.PackSizesChange
+	if err := c.ShouldBindJSON(&change); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	snapshot, err := h.service.RollbackPackSizes(version, change)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(snapshot.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Pack sizes rolled back to revision %d", version),
+		"pack_sizes": snapshot.PackSizes,
+		"version":    snapshot.Version,
+	})
+}
+
+// AnalyzePackSizes handles POST /api/pack-sizes/analysis
+// The body is optional; without it the default pack sizes are analyzed over the default range
+func (h *PackHandler) AnalyzePackSizes(c *gin.Context) {
+	var request model.AnalysisRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	analysis, err := h.service.AnalyzePackSizes(c.Request.Context(), &request)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// ComparePackSizes handles POST /api/pack-sizes/compare
+// The current pack sizes are the base of the comparison and the proposed ones its candidate
+func (h *PackHandler) ComparePackSizes(c *gin.Context) {
+	var request model.ComparisonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	comparison, err := h.service.ComparePackSizes(c.Request.Context(), &request)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// StartRecommendation handles POST /api/recommendations
+// The search runs in the background; the job is answered with 202 and its Location
+func (h *PackHandler) StartRecommendation(c *gin.Context) {
+	var request model.RecommendationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	job, err := h.service.StartRecommendation(&request)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Location", "/api/recommendations/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetRecommendation handles GET /api/recommendations/:id
+func (h *PackHandler) GetRecommendation(c *gin.Context) {
+	job, err := h.service.GetRecommendation(c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetQuote handles GET /api/quotes/:id
+func (h *PackHandler) GetQuote(c *gin.Context) {
+	quote, err := h.service.GetQuote(c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// ListQuotes handles GET /api/quotes?profile=default&min_quantity=1&limit=20
+// Quotes are listed newest first; since and until are RFC 3339 timestamps
+func (h *PackHandler) ListQuotes(c *gin.Context) {
+	filter, err := parseQuoteFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	list, err := h.service.ListQuotes(filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// parseQuoteFilter reads the quote filter from the query, reporting every unreadable parameter
+func parseQuoteFilter(c *gin.Context) (model.QuoteFilter, error) {
+	filter := model.QuoteFilter{Profile: c.Query("profile")}
+	var fields []model.FieldError
+
+	numbers := []struct {
+		name   string
+		target *int
+	}{
+		{"min_quantity", &filter.MinQuantity},
+		{"max_quantity", &filter.MaxQuantity},
+		{"pack_sizes_version", &filter.PackSizesVersion},
+		{"limit", &filter.Limit},
+		{"offset", &filter.Offset},
+	}
+	for _, number := range numbers {
+		value := c.Query(number.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			fields = append(fields, model.FieldError{Field: number.name, Message: "must be a number"})
+			continue
+		}
+		*number.target = parsed
+	}
+
+	times := []struct {
+		name   string
+		target *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	}
+	for _, timestamp := range times {
+		value := c.Query(timestamp.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, model.FieldError{Field: timestamp.name, Message: "must be an RFC 3339 timestamp"})
+			continue
+		}
+		*timestamp.target = parsed
+	}
+
+	if len(fields) > 0 {
+		return filter, invalidRequest("invalid quote filter", fields...)
+	}
+	return filter, nil
+}
+
+// versionETag formats a pack sizes version as a strong entity tag
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseVersionETag reads the version from an If-Match entity tag
+// Weak or malformed tags never match, so they return -1
+func parseVersionETag(tag string) int {
+	unquoted, err := strconv.Unquote(strings.TrimSpace(tag))
+	if err != nil {
+		return -1
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 0 {
+		return -1
+	}
+	return version
+}
+
+// GetStock handles GET /api/stock
+func (h *PackHandler) GetStock(c *gin.Context) {
+	stock, err := h.service.GetStock()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock": stock,
+	})
+}
+
+// UpdateStock handles PUT /api/stock
+func (h *PackHandler) UpdateStock(c *gin.Context) {
+	var request struct {
+		Stock map[int]int `json:"stock" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	if err := h.service.UpdateStock(request.Stock); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Stock updated successfully",
+		"stock":   request.Stock,
+	})
+}
+
+// GetPackCosts handles GET /api/pack-costs
+func (h *PackHandler) GetPackCosts(c *gin.Context) {
+	costs, err := h.service.GetPackCosts()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pack_costs": costs,
 	})
 }
 
+// UpdatePackCosts handles PUT /api/pack-costs
+func (h *PackHandler) UpdatePackCosts(c *gin.Context) {
+	var request struct {
+		PackCosts map[int]int `json:"pack_costs" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	if err := h.service.UpdatePackCosts(request.PackCosts); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Pack costs updated successfully",
+		"pack_costs": request.PackCosts,
+	})
+}
+
+// GetPolicies handles GET /api/policies
+func (h *PackHandler) GetPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"policies": h.service.GetPolicies(),
+	})
+}
+
+// GetCacheStats handles GET /api/cache/stats
+func (h *PackHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.GetCacheStats())
+}
+
+// ListProfiles handles GET /api/profiles
+func (h *PackHandler) ListProfiles(c *gin.Context) {
+	profiles, err := h.service.ListProfiles()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profiles": profiles,
+	})
+}
+
+// GetProfile handles GET /api/profiles/:name
+func (h *PackHandler) GetProfile(c *gin.Context) {
+	profile, err := h.service.GetProfile(c.Param("name"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// CreateProfile handles POST /api/profiles
+func (h *PackHandler) CreateProfile(c *gin.Context) {
+	var profile model.PackProfile
+
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	if err := h.service.CreateProfile(&profile); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+// UpdateProfile handles PUT /api/profiles/:name
+// The name comes from the URL; a different name in the body is rejected
+func (h *PackHandler) UpdateProfile(c *gin.Context) {
+	var profile model.PackProfile
+
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		_ = c.Error(bindingError(err))
+		return
+	}
+
+	name := c.Param("name")
+	if profile.Name != "" && profile.Name != name {
+		_ = c.Error(invalidField("name", "cannot be changed"))
+		return
+	}
+	profile.Name = name
+
+	if err := h.service.UpdateProfile(&profile); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteProfile handles DELETE /api/profiles/:name
+func (h *PackHandler) DeleteProfile(c *gin.Context) {
+	if err := h.service.DeleteProfile(c.Param("name")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
 // GetDocs handles GET /docs
 // Renders API documentation page
 func (h *PackHandler) GetDocs(c *gin.Context) {
@@ -95,6 +809,69 @@ func (h *PackHandler) GetDocsJSON(c *gin.Context) {
 	c.JSON(http.StatusOK, docs)
 }
 
+// maxComparisonRows is how many changed quantities the web comparison lists
+const maxComparisonRows = 20
+
+// ComparePackSizesForm handles POST /pack-sizes/compare
+// Compares the proposed pack sizes with the current ones over a range of quantities
+func (h *PackHandler) ComparePackSizesForm(c *gin.Context) {
+	sizes, _ := h.service.GetAvailablePackSizes()
+	proposed := c.PostForm("proposed_sizes")
+	data := gin.H{
+		"title":          "Pack Calculator",
+		"pack_sizes":     sizes,
+		"proposed_sizes": proposed,
+		"compare_from":   c.PostForm("from"),
+		"compare_to":     c.PostForm("to"),
+	}
+
+	request := &model.ComparisonRequest{}
+	for _, field := range strings.FieldsFunc(proposed, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		size, err := strconv.Atoi(field)
+		if err != nil {
+			data["error"] = fmt.Sprintf("Proposed pack sizes must be numbers, got: %q", field)
+			c.HTML(http.StatusBadRequest, "index.html", data)
+			return
+		}
+		request.PackSizes = append(request.PackSizes, size)
+	}
+	from, fromErr := strconv.Atoi(c.PostForm("from"))
+	to, toErr := strconv.Atoi(c.PostForm("to"))
+	if fromErr != nil || toErr != nil {
+		data["error"] = "Please enter the quantities to compare as a range of numbers"
+		c.HTML(http.StatusBadRequest, "index.html", data)
+		return
+	}
+	request.From, request.To = from, to
+
+	comparison, err := h.service.ComparePackSizes(c.Request.Context(), request)
+	if err != nil {
+		log.Errorf("Pack sizes comparison failed: %v", err)
+		data["error"] = err.Error()
+		c.HTML(errorStatus(err), "index.html", data)
+		return
+	}
+
+	// Only the quantities whose distribution changes are listed
+	var changes []model.QuantityComparison
+	for _, entry := range comparison.Quantities {
+		if len(changes) == maxComparisonRows {
+			break
+		}
+		if compareOutcomeChanged(entry) {
+			changes = append(changes, entry)
+		}
+	}
+	data["comparison"] = comparison
+	data["comparison_changes"] = changes
+	c.HTML(http.StatusOK, "index.html", data)
+}
+
+// compareOutcomeChanged reports whether the proposed pack sizes change the items or packs of a quantity
+func compareOutcomeChanged(entry model.QuantityComparison) bool {
+	return entry.Base.Unreachable != entry.Candidate.Unreachable || entry.ItemsDiff != 0 || entry.PacksDiff != 0
+}
+
 // RenderHome handles GET /
 // Renders the main UI page
 func (h *PackHandler) RenderHome(c *gin.Context) {
@@ -153,11 +930,16 @@ func (h *PackHandler) UpdatePackSizesForm(c *gin.Context) {
 		return
 	}
 
-	err = h.service.UpdatePackSizes(packSizes)
+	change := model.PackSizesChange{
+		Author: strings.TrimSpace(c.PostForm("author")),
+		Reason: strings.TrimSpace(c.PostForm("reason")),
+	}
+	err = h.service.UpdatePackSizes(packSizes, change)
 	if err != nil {
 		sizes, _ := h.service.GetAvailablePackSizes()
 		log.Errorf("Failed to update pack sizes: %v", err)
-		c.HTML(http.StatusBadRequest, "index.html", gin.H{
+		status := errorStatus(err)
+		c.HTML(status, "index.html", gin.H{
 			"title":      "Pack Calculator",
 			"pack_sizes": sizes,
 			"error":      err.Error(),
@@ -172,6 +954,25 @@ func (h *PackHandler) UpdatePackSizesForm(c *gin.Context) {
 	})
 }
 
+// CalculateCSVForm handles POST /calculate/csv (file upload)
+// The results are downloaded; errors are shown on the page
+func (h *PackHandler) CalculateCSVForm(c *gin.Context) {
+	results, err := h.calculateCSV(c)
+	if err != nil {
+		sizes, _ := h.service.GetAvailablePackSizes()
+		log.Errorf("CSV calculation failed: %v", err)
+		c.HTML(errorStatus(err), "index.html", gin.H{
+			"title":      "Pack Calculator",
+			"pack_sizes": sizes,
+			"error":      err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", csvFilename))
+	c.Data(http.StatusOK, csvContentType+"; charset=utf-8", results)
+}
+
 // CalculatePacksForm handles POST /calculate (form submission)
 func (h *PackHandler) CalculatePacksForm(c *gin.Context) {
 	quantityStr := c.PostForm("quantity")
@@ -188,17 +989,20 @@ func (h *PackHandler) CalculatePacksForm(c *gin.Context) {
 
 	request := &model.PackRequest{
 		Quantity: quantity,
+		Explain:  c.PostForm("explain") == "on",
 	}
 
-	response, err := h.service.CalculatePackDistribution(request)
+	response, err := h.service.CalculatePackDistribution(c.Request.Context(), request)
 	if err != nil {
 		sizes, _ := h.service.GetAvailablePackSizes()
 		log.WithField("request", request).Errorf("Calculation failed: %v", err)
-		c.HTML(http.StatusBadRequest, "index.html", gin.H{
+		status := errorStatus(err)
+		c.HTML(status, "index.html", gin.H{
 			"title":      "Pack Calculator",
 			"pack_sizes": sizes,
 			"error":      err.Error(),
 			"quantity":   quantity,
+			"explain":    request.Explain,
 		})
 		return
 	}
@@ -214,6 +1018,7 @@ func (h *PackHandler) CalculatePacksForm(c *gin.Context) {
 		"title":      "Pack Calculator",
 		"pack_sizes": sizes,
 		"quantity":   quantity,
+		"explain":    request.Explain,
 		"result":     response,
 	})
 }
@@ -2,30 +2,125 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/service"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
 )
 
+// serve runs an API handler the way the problem details middleware wraps it
+func serve(c *gin.Context, h gin.HandlerFunc) {
+	h(c)
+	writeProblem(c)
+}
+
 // Mock service for testing
 type mockPackService struct {
 	calculateFunc       func(request *model.PackRequest) (*model.PackResponse, error)
+	calculateOrderFunc  func(request *model.OrderRequest) (*model.OrderResponse, error)
+	calculateBatchFunc  func(requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error
+	analyzeFunc         func(request *model.AnalysisRequest) (*model.PackSizesAnalysis, error)
+	compareFunc         func(request *model.ComparisonRequest) (*model.PackSizesComparison, error)
+	startRecommendFunc  func(request *model.RecommendationRequest) (*model.RecommendationJob, error)
+	getRecommendFunc    func(id string) (*model.RecommendationJob, error)
+	getQuoteFunc        func(id string) (*model.Quote, error)
+	listQuotesFunc      func(filter model.QuoteFilter) (*model.QuoteList, error)
 	getPackSizesFunc    func() ([]int, error)
 	updatePackSizesFunc func(sizes []int) error
+	updateIfMatchFunc   func(sizes []int, version int) (model.PackSizesSnapshot, error)
+	listRevisionsFunc   func() ([]model.PackSizesRevision, error)
+	diffRevisionsFunc   func(from, to int) (*model.PackSizesDiff, error)
+	rollbackFunc        func(version int, change model.PackSizesChange) (model.PackSizesSnapshot, error)
+	getStockFunc        func() (map[int]int, error)
+	updateStockFunc     func(stock map[int]int) error
+	getPackCostsFunc    func() (map[int]int, error)
+	updatePackCostsFunc func(costs map[int]int) error
+	getPoliciesFunc     func() []calculator.Policy
+	getCacheStatsFunc   func() calculator.CacheStats
+	listProfilesFunc    func() ([]model.PackProfile, error)
+	getProfileFunc      func(name string) (*model.PackProfile, error)
+	createProfileFunc   func(profile *model.PackProfile) error
+	updateProfileFunc   func(profile *model.PackProfile) error
+	deleteProfileFunc   func(name string) error
 }
 
-func (m *mockPackService) CalculatePackDistribution(request *model.PackRequest) (*model.PackResponse, error) {
+func (m *mockPackService) CalculatePackDistribution(ctx context.Context, request *model.PackRequest) (*model.PackResponse, error) {
 	if m.calculateFunc != nil {
 		return m.calculateFunc(request)
 	}
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockPackService) CalculateOrder(ctx context.Context, request *model.OrderRequest) (*model.OrderResponse, error) {
+	if m.calculateOrderFunc != nil {
+		return m.calculateOrderFunc(request)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) CalculateBatch(ctx context.Context, requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error {
+	if m.calculateBatchFunc != nil {
+		return m.calculateBatchFunc(requests, emit)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockPackService) AnalyzePackSizes(ctx context.Context, request *model.AnalysisRequest) (*model.PackSizesAnalysis, error) {
+	if m.analyzeFunc != nil {
+		return m.analyzeFunc(request)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) ComparePackSizes(ctx context.Context, request *model.ComparisonRequest) (*model.PackSizesComparison, error) {
+	if m.compareFunc != nil {
+		return m.compareFunc(request)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) StartRecommendation(request *model.RecommendationRequest) (*model.RecommendationJob, error) {
+	if m.startRecommendFunc != nil {
+		return m.startRecommendFunc(request)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) GetRecommendation(id string) (*model.RecommendationJob, error) {
+	if m.getRecommendFunc != nil {
+		return m.getRecommendFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) GetQuote(id string) (*model.Quote, error) {
+	if m.getQuoteFunc != nil {
+		return m.getQuoteFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) ListQuotes(filter model.QuoteFilter) (*model.QuoteList, error) {
+	if m.listQuotesFunc != nil {
+		return m.listQuotesFunc(filter)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m *mockPackService) GetAvailablePackSizes() ([]int, error) {
 	if m.getPackSizesFunc != nil {
 		return m.getPackSizesFunc()
@@ -33,13 +128,127 @@ func (m *mockPackService) GetAvailablePackSizes() ([]int, error) {
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockPackService) UpdatePackSizes(sizes []int) error {
+func (m *mockPackService) UpdatePackSizes(sizes []int, change model.PackSizesChange) error {
 	if m.updatePackSizesFunc != nil {
 		return m.updatePackSizesFunc(sizes)
 	}
 	return errors.New("not implemented")
 }
 
+// GetPackSizesSnapshot reports getPackSizesFunc's sizes at version 1
+func (m *mockPackService) GetPackSizesSnapshot() (model.PackSizesSnapshot, error) {
+	if m.getPackSizesFunc != nil {
+		sizes, err := m.getPackSizesFunc()
+		return model.PackSizesSnapshot{PackSizes: sizes, Version: 1}, err
+	}
+	return model.PackSizesSnapshot{}, errors.New("not implemented")
+}
+
+func (m *mockPackService) UpdatePackSizesIfMatch(sizes []int, version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	if m.updateIfMatchFunc != nil {
+		return m.updateIfMatchFunc(sizes, version)
+	}
+	return model.PackSizesSnapshot{}, errors.New("not implemented")
+}
+
+func (m *mockPackService) ListPackSizesRevisions() ([]model.PackSizesRevision, error) {
+	if m.listRevisionsFunc != nil {
+		return m.listRevisionsFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) DiffPackSizesRevisions(from, to int) (*model.PackSizesDiff, error) {
+	if m.diffRevisionsFunc != nil {
+		return m.diffRevisionsFunc(from, to)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) RollbackPackSizes(version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	if m.rollbackFunc != nil {
+		return m.rollbackFunc(version, change)
+	}
+	return model.PackSizesSnapshot{}, errors.New("not implemented")
+}
+
+func (m *mockPackService) GetStock() (map[int]int, error) {
+	if m.getStockFunc != nil {
+		return m.getStockFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) UpdateStock(stock map[int]int) error {
+	if m.updateStockFunc != nil {
+		return m.updateStockFunc(stock)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockPackService) GetPackCosts() (map[int]int, error) {
+	if m.getPackCostsFunc != nil {
+		return m.getPackCostsFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) UpdatePackCosts(costs map[int]int) error {
+	if m.updatePackCostsFunc != nil {
+		return m.updatePackCostsFunc(costs)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockPackService) GetPolicies() []calculator.Policy {
+	if m.getPoliciesFunc != nil {
+		return m.getPoliciesFunc()
+	}
+	return nil
+}
+
+func (m *mockPackService) GetCacheStats() calculator.CacheStats {
+	if m.getCacheStatsFunc != nil {
+		return m.getCacheStatsFunc()
+	}
+	return calculator.CacheStats{}
+}
+
+func (m *mockPackService) ListProfiles() ([]model.PackProfile, error) {
+	if m.listProfilesFunc != nil {
+		return m.listProfilesFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) GetProfile(name string) (*model.PackProfile, error) {
+	if m.getProfileFunc != nil {
+		return m.getProfileFunc(name)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockPackService) CreateProfile(profile *model.PackProfile) error {
+	if m.createProfileFunc != nil {
+		return m.createProfileFunc(profile)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockPackService) UpdateProfile(profile *model.PackProfile) error {
+	if m.updateProfileFunc != nil {
+		return m.updateProfileFunc(profile)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockPackService) DeleteProfile(name string) error {
+	if m.deleteProfileFunc != nil {
+		return m.deleteProfileFunc(name)
+	}
+	return errors.New("not implemented")
+}
+
 func TestNewPackHandler(t *testing.T) {
 	mockService := &mockPackService{}
 	handler := NewPackHandler(mockService)
@@ -63,6 +272,7 @@ func TestPackHandler_CalculatePacks(t *testing.T) {
 		mockError      error
 		expectedStatus int
 		checkError     bool
+		expectedCode   string
 	}{
 		{
 			name: "Valid request - successful calculation",
@@ -94,14 +304,95 @@ func TestPackHandler_CalculatePacks(t *testing.T) {
 			checkError:     false,
 		},
 		{
-			name: "Service returns error",
+			name: "Service returns unexpected error",
 			requestBody: map[string]interface{}{
 				"quantity": 1001,
 			},
 			mockResponse:   nil,
 			mockError:      errors.New("calculation failed"),
+			expectedStatus: http.StatusInternalServerError,
+			checkError:     true,
+			expectedCode:   model.CodeInternal,
+		},
+		{
+			name: "Service returns validation error",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      model.NewValidationError("unknown policy: fastest"),
 			expectedStatus: http.StatusBadRequest,
 			checkError:     true,
+			expectedCode:   model.CodeValidationFailed,
+		},
+		{
+			name: "No pack sizes",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      service.ErrNoPackSizes,
+			expectedStatus: http.StatusUnprocessableEntity,
+			checkError:     true,
+			expectedCode:   model.CodeNoPackSizes,
+		},
+		{
+			name: "Quantity too large",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("calculation failed: %w", calculator.ErrQuantityTooLarge),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			checkError:     true,
+			expectedCode:   model.CodeQuantityTooLarge,
+		},
+		{
+			name: "Table too large",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("calculation failed: %w", calculator.ErrTableTooLarge),
+			expectedStatus: http.StatusUnprocessableEntity,
+			checkError:     true,
+			expectedCode:   model.CodeTableTooLarge,
+		},
+		{
+			name: "Calculation timed out",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("calculation failed: %w", calculator.ErrTimeout),
+			expectedStatus: http.StatusServiceUnavailable,
+			checkError:     true,
+			expectedCode:   model.CodeTimeout,
+		},
+		{
+			name: "Insufficient stock",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("calculation failed: %w", calculator.ErrInsufficientStock),
+			expectedStatus: http.StatusConflict,
+			checkError:     true,
+			expectedCode:   model.CodeInsufficientStock,
+		},
+		{
+			name: "Unknown profile",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("failed to get profile: %w", model.ErrProfileNotFound),
+			expectedStatus: http.StatusNotFound,
+			checkError:     true,
+			expectedCode:   model.CodeProfileNotFound,
+		},
+		{
+			name: "Repository failure",
+			requestBody: map[string]interface{}{
+				"quantity": 1001,
+			},
+			mockError:      fmt.Errorf("failed to get profile: %w", service.ErrRepository),
+			expectedStatus: http.StatusInternalServerError,
+			checkError:     true,
+			expectedCode:   model.CodeRepositoryFailure,
 		},
 		{
 			name: "Invalid quantity - zero",
@@ -148,7 +439,7 @@ func TestPackHandler_CalculatePacks(t *testing.T) {
 			c.Request.Header.Set("Content-Type", "application/json")
 
 			// Call handler directly
-			handler.CalculatePacks(c)
+			serve(c, handler.CalculatePacks)
 
 			// Check status code
 			if w.Code != tt.expectedStatus {
@@ -160,9 +451,12 @@ func TestPackHandler_CalculatePacks(t *testing.T) {
 			json.Unmarshal(w.Body.Bytes(), &response)
 
 			if tt.checkError {
-				if _, hasError := response["error"]; !hasError {
+				if _, hasError := response["title"]; !hasError {
 					t.Error("Expected error in response")
 				}
+				if tt.expectedCode != "" && response["code"] != tt.expectedCode {
+					t.Errorf("Expected code %s, got %v", tt.expectedCode, response["code"])
+				}
 			} else {
 				if quantity, ok := response["quantity"]; ok {
 					if int(quantity.(float64)) != tt.mockResponse.Quantity {
@@ -174,6 +468,127 @@ func TestPackHandler_CalculatePacks(t *testing.T) {
 	}
 }
 
+func TestPackHandler_CalculatePacksV2(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	version := 3
+	calculated := &model.PackResponse{
+		Quantity:       501,
+		TotalItems:     750,
+		TotalPacks:     2,
+		PackBreakdown:  map[int]int{250: 1, 500: 1},
+		PackSizesUsed:  []int{250, 500, 1000},
+		Policy:         "default",
+		QuoteID:        "abc123",
+		PackSetVersion: &version,
+		Algorithm:      "dynamic",
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		handler    func(h *PackHandler) gin.HandlerFunc
+		mockError  error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Version 2",
+			path:       "/api/v2/calculate",
+			handler:    func(h *PackHandler) gin.HandlerFunc { return h.CalculatePacksV2 },
+			wantStatus: http.StatusOK,
+			wantBody: `{"quantity":501,"packs":[{"pack_size":500,"count":1,"items":500},{"pack_size":250,"count":1,"items":250}],` +
+				`"total_items":750,"total_packs":2,"overage":249,"overage_percent":49.7,"pack_sizes_used":[250,500,1000],` +
+				`"pack_set_version":3,"algorithm":"dynamic","policy":"default","quote_id":"abc123"}`,
+		},
+		{
+			name:       "Version 1 is unchanged",
+			path:       "/api/calculate",
+			handler:    func(h *PackHandler) gin.HandlerFunc { return h.CalculatePacks },
+			wantStatus: http.StatusOK,
+			wantBody: `{"quantity":501,"total_items":750,"total_packs":2,"pack_breakdown":{"250":1,"500":1},` +
+				`"pack_sizes_used":[250,500,1000],"policy":"default","quote_id":"abc123"}`,
+		},
+		{
+			name:       "Version 2 error",
+			path:       "/api/v2/calculate",
+			handler:    func(h *PackHandler) gin.HandlerFunc { return h.CalculatePacksV2 },
+			mockError:  service.ErrNoPackSizes,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+					return calculated, tt.mockError
+				},
+			}
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("POST", tt.path, strings.NewReader(`{"quantity": 501}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			serve(c, tt.handler(handler))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPackHandler_CalculatePacks_Explain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		expectedStatus int
+		wantExplain    bool
+	}{
+		{"Not asked", "", `{"quantity": 251}`, http.StatusOK, false},
+		{"Query parameter", "?explain=true", `{"quantity": 251}`, http.StatusOK, true},
+		{"Body field", "", `{"quantity": 251, "explain": true}`, http.StatusOK, true},
+		{"Query false keeps body", "?explain=false", `{"quantity": 251, "explain": true}`, http.StatusOK, true},
+		{"Invalid query parameter", "?explain=maybe", `{"quantity": 251}`, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var explain bool
+			mockService := &mockPackService{
+				calculateFunc: func(request *model.PackRequest) (*model.PackResponse, error) {
+					explain = request.Explain
+					return &model.PackResponse{Quantity: request.Quantity}, nil
+				},
+			}
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("POST", "/api/calculate"+tt.query, bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			serve(c, handler.CalculatePacks)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if explain != tt.wantExplain {
+				t.Errorf("Explain = %v, want %v", explain, tt.wantExplain)
+			}
+		})
+	}
+}
+
 func TestPackHandler_GetPackSizes(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -223,11 +638,14 @@ func TestPackHandler_GetPackSizes(t *testing.T) {
 			c, _ := gin.CreateTestContext(w)
 			c.Request, _ = http.NewRequest("GET", "/api/pack-sizes", nil)
 
-			handler.GetPackSizes(c)
+			serve(c, handler.GetPackSizes)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
 			}
+			if tt.mockError == nil && w.Header().Get("ETag") != `"1"` {
+				t.Errorf("Expected ETag \"1\", got %q", w.Header().Get("ETag"))
+			}
 
 			var response map[string]interface{}
 			json.Unmarshal(w.Body.Bytes(), &response)
@@ -274,7 +692,7 @@ func TestPackHandler_UpdatePackSizes(t *testing.T) {
 			requestBody: map[string]interface{}{
 				"pack_sizes": []int{250, 500, 1000},
 			},
-			mockError:      errors.New("validation error"),
+			mockError:      model.NewValidationError("validation error"),
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
@@ -290,7 +708,7 @@ func TestPackHandler_UpdatePackSizes(t *testing.T) {
 			requestBody: map[string]interface{}{
 				"pack_sizes": []int{},
 			},
-			mockError:      errors.New("pack sizes cannot be empty"),
+			mockError:      model.NewValidationError("pack sizes cannot be empty"),
 			expectedStatus: http.StatusBadRequest,
 		},
 	}
@@ -301,6 +719,9 @@ func TestPackHandler_UpdatePackSizes(t *testing.T) {
 				updatePackSizesFunc: func(sizes []int) error {
 					return tt.mockError
 				},
+				getPackSizesFunc: func() ([]int, error) {
+					return []int{250}, nil
+				},
 			}
 
 			handler := NewPackHandler(mockService)
@@ -312,7 +733,7 @@ func TestPackHandler_UpdatePackSizes(t *testing.T) {
 			c.Request, _ = http.NewRequest("PUT", "/api/pack-sizes", bytes.NewBuffer(body))
 			c.Request.Header.Set("Content-Type", "application/json")
 
-			handler.UpdatePackSizes(c)
+			serve(c, handler.UpdatePackSizes)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -326,10 +747,1313 @@ func TestPackHandler_UpdatePackSizes(t *testing.T) {
 					t.Error("Expected success message")
 				}
 			} else {
-				if _, hasError := response["error"]; !hasError {
+				if _, hasError := response["title"]; !hasError {
 					t.Error("Expected error in response")
 				}
 			}
 		})
 	}
 }
+
+func TestPackHandler_UpdatePackSizes_IfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		wantVersion    int
+		expectedStatus int
+	}{
+		{"Matching version", `"3"`, 3, http.StatusOK},
+		{"Stale version", `"2"`, 2, http.StatusPreconditionFailed},
+		{"Weak tag never matches", `W/"3"`, -1, http.StatusPreconditionFailed},
+		{"Malformed tag never matches", `3`, -1, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				updateIfMatchFunc: func(sizes []int, version int) (model.PackSizesSnapshot, error) {
+					if version != tt.wantVersion {
+						t.Errorf("Expected version %d, got %d", tt.wantVersion, version)
+					}
+					if version != 3 {
+						return model.PackSizesSnapshot{}, fmt.Errorf("%w: expected version %d, current version 3", model.ErrVersionConflict, version)
+					}
+					return model.PackSizesSnapshot{PackSizes: sizes, Version: 4}, nil
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("PUT", "/api/pack-sizes", bytes.NewBufferString(`{"pack_sizes": [250, 500]}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("If-Match", tt.ifMatch)
+
+			serve(c, handler.UpdatePackSizes)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && w.Header().Get("ETag") != `"4"` {
+				t.Errorf("Expected ETag \"4\", got %q", w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestPackHandler_PackSizesRevisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	notFound := fmt.Errorf("%w: 9", model.ErrRevisionNotFound)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mockError      error
+		expectedStatus int
+	}{
+		{"List revisions", "GET", "/api/pack-sizes/revisions", "", nil, http.StatusOK},
+		{"Diff revisions", "GET", "/api/pack-sizes/revisions/diff?from=1&to=2", "", nil, http.StatusOK},
+		{"Diff without versions", "GET", "/api/pack-sizes/revisions/diff?from=1", "", nil, http.StatusBadRequest},
+		{"Diff missing revision", "GET", "/api/pack-sizes/revisions/diff?from=1&to=9", "", notFound, http.StatusNotFound},
+		{"Rollback", "POST", "/api/pack-sizes/revisions/1/rollback", `{"author": "ops", "reason": "bad deploy"}`, nil, http.StatusOK},
+		{"Rollback without body", "POST", "/api/pack-sizes/revisions/1/rollback", "", nil, http.StatusOK},
+		{"Rollback invalid version", "POST", "/api/pack-sizes/revisions/first/rollback", "", nil, http.StatusBadRequest},
+		{"Rollback missing revision", "POST", "/api/pack-sizes/revisions/9/rollback", "", notFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				listRevisionsFunc: func() ([]model.PackSizesRevision, error) {
+					return []model.PackSizesRevision{{Version: 1, NewPackSizes: []int{250}}}, tt.mockError
+				},
+				diffRevisionsFunc: func(from, to int) (*model.PackSizesDiff, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &model.PackSizesDiff{From: from, To: to, Added: []int{500}, Removed: []int{}}, nil
+				},
+				rollbackFunc: func(version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+					if tt.body != "" && change.Author != "ops" {
+						t.Errorf("Expected author from body, got %+v", change)
+					}
+					return model.PackSizesSnapshot{PackSizes: []int{250}, Version: 3}, tt.mockError
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.GET("/api/pack-sizes/revisions", handler.ListPackSizesRevisions)
+			router.GET("/api/pack-sizes/revisions/diff", handler.DiffPackSizesRevisions)
+			router.POST("/api/pack-sizes/revisions/:version/rollback", handler.RollbackPackSizes)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPackHandler_AnalyzePackSizes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Default pack sizes", "", nil, http.StatusOK, ""},
+		{"Requested pack sizes", `{"pack_sizes": [6, 9, 20], "from": 1, "to": 100}`, nil, http.StatusOK, ""},
+		{"Invalid JSON", `{"pack_sizes": "6,9"}`, nil, http.StatusBadRequest, model.CodeInvalidRequest},
+		{"Invalid range", `{"from": 10, "to": 5}`, model.NewFieldError("to", "cannot be before from, got: 5"), http.StatusBadRequest, model.CodeValidationFailed},
+		{"No pack sizes", "", service.ErrNoPackSizes, http.StatusUnprocessableEntity, model.CodeNoPackSizes},
+		{"Range too large", `{"to": 1000000000}`, fmt.Errorf("analysis failed: %w", calculator.ErrTableTooLarge), http.StatusUnprocessableEntity, model.CodeTableTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				analyzeFunc: func(request *model.AnalysisRequest) (*model.PackSizesAnalysis, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &model.PackSizesAnalysis{PackSizes: request.PackSizes, GCD: 1, AllReachable: true, Conductor: 44}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.POST("/api/pack-sizes/analysis", handler.AnalyzePackSizes)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/pack-sizes/analysis", bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_ComparePackSizes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Listed quantities", `{"pack_sizes": [300, 600], "quantities": [251, 501]}`, nil, http.StatusOK, ""},
+		{"Range", `{"pack_sizes": [300, 600], "to": 1000, "summary": true}`, nil, http.StatusOK, ""},
+		{"Invalid JSON", `{"pack_sizes": "300"}`, nil, http.StatusBadRequest, model.CodeInvalidRequest},
+		{"Missing range", `{"pack_sizes": [300]}`, model.NewFieldError("to", "is required without quantities"), http.StatusBadRequest, model.CodeValidationFailed},
+		{"No current pack sizes", `{"pack_sizes": [300], "to": 10}`, service.ErrNoPackSizes, http.StatusUnprocessableEntity, model.CodeNoPackSizes},
+		{"Too large", `{"pack_sizes": [1], "quantities": [1000000000]}`, fmt.Errorf("comparison failed: %w", calculator.ErrTableTooLarge), http.StatusUnprocessableEntity, model.CodeTableTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				compareFunc: func(request *model.ComparisonRequest) (*model.PackSizesComparison, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &model.PackSizesComparison{Base: []int{250, 500}, Candidate: request.PackSizes}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.POST("/api/pack-sizes/compare", handler.ComparePackSizes)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/pack-sizes/compare", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_StartRecommendation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Job started", `{"orders": [{"quantity": 300, "count": 10}], "min_size": 100, "max_size": 600, "step": 100, "max_sizes": 2}`, nil, http.StatusAccepted, ""},
+		{"Invalid JSON", `{"orders": "300"}`, nil, http.StatusBadRequest, model.CodeInvalidRequest},
+		{"Invalid request", `{"orders": [], "min_size": 100, "max_size": 600, "max_sizes": 2}`, model.NewFieldError("orders", "cannot be empty"), http.StatusBadRequest, model.CodeValidationFailed},
+		{"Too many jobs", `{"orders": [{"quantity": 300}], "min_size": 100, "max_size": 600, "max_sizes": 2}`, model.ErrTooManyJobs, http.StatusTooManyRequests, model.CodeTooManyJobs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				startRecommendFunc: func(request *model.RecommendationRequest) (*model.RecommendationJob, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &model.RecommendationJob{ID: "abc123", Status: model.JobQueued, MaxEvaluations: calculator.DefaultMaxEvaluations}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.POST("/api/recommendations", handler.StartRecommendation)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/recommendations", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+				return
+			}
+			if location := w.Header().Get("Location"); location != "/api/recommendations/abc123" {
+				t.Errorf("Expected Location of the job, got %q", location)
+			}
+		})
+	}
+}
+
+func TestPackHandler_GetRecommendation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Finished job", "abc123", http.StatusOK, ""},
+		{"Missing job", "missing", http.StatusNotFound, model.CodeJobNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				getRecommendFunc: func(id string) (*model.RecommendationJob, error) {
+					if id != "abc123" {
+						return nil, fmt.Errorf("%w: %s", model.ErrJobNotFound, id)
+					}
+					return &model.RecommendationJob{
+						ID:     id,
+						Status: model.JobSucceeded,
+						Result: &model.RecommendationResult{Recommended: model.PackSetEvaluation{PackSizes: []int{300, 600}}},
+					}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.GET("/api/recommendations/:id", handler.GetRecommendation)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/recommendations/"+tt.id, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+				return
+			}
+			var job model.RecommendationJob
+			if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || job.Status != model.JobSucceeded || job.Result == nil {
+				t.Errorf("Expected a succeeded job, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPackHandler_GetQuote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Stored quote", "abc123", http.StatusOK, ""},
+		{"Missing quote", "missing", http.StatusNotFound, model.CodeQuoteNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				getQuoteFunc: func(id string) (*model.Quote, error) {
+					if id != "abc123" {
+						return nil, fmt.Errorf("%w: %s", model.ErrQuoteNotFound, id)
+					}
+					return &model.Quote{
+						ID:       id,
+						Profile:  model.DefaultProfile,
+						Request:  model.PackRequest{Quantity: 251},
+						Response: model.PackResponse{Quantity: 251, TotalItems: 500, PackBreakdown: map[int]int{500: 1}},
+					}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.GET("/api/quotes/:id", handler.GetQuote)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/quotes/"+tt.id, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Errorf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+				return
+			}
+			var quote model.Quote
+			if err := json.Unmarshal(w.Body.Bytes(), &quote); err != nil || quote.ID != tt.id || quote.Response.TotalItems != 500 {
+				t.Errorf("Expected quote %s, got %s", tt.id, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPackHandler_ListQuotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedFilter model.QuoteFilter
+		expectedStatus int
+		expectedCode   string
+		expectedFields []string
+	}{
+		{
+			name:           "Every filter",
+			query:          "?profile=eu&min_quantity=10&max_quantity=500&since=2026-10-01T00:00:00Z&pack_sizes_version=3&limit=5&offset=10",
+			expectedFilter: model.QuoteFilter{Profile: "eu", MinQuantity: 10, MaxQuantity: 500, Since: since, PackSizesVersion: 3, Limit: 5, Offset: 10},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "No filter",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unreadable parameters",
+			query:          "?limit=ten&until=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   model.CodeInvalidRequest,
+			expectedFields: []string{"limit", "until"},
+		},
+		{
+			name:           "Invalid filter",
+			query:          "?limit=1000",
+			expectedFilter: model.QuoteFilter{Limit: 1000},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   model.CodeValidationFailed,
+			expectedFields: []string{"limit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got model.QuoteFilter
+			mockService := &mockPackService{
+				listQuotesFunc: func(filter model.QuoteFilter) (*model.QuoteList, error) {
+					got = filter
+					if err := filter.Validate(); err != nil {
+						return nil, err
+					}
+					return &model.QuoteList{Quotes: []model.Quote{{ID: "abc123"}}, Total: 1, Limit: filter.Limit, Offset: filter.Offset}, nil
+				},
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.GET("/api/quotes", handler.ListQuotes)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/quotes"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				var problem model.ProblemDetails
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.expectedCode {
+					t.Fatalf("Expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+				var fields []string
+				for _, field := range problem.Errors {
+					fields = append(fields, field.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.expectedFields) {
+					t.Errorf("Expected fields %v, got %v", tt.expectedFields, fields)
+				}
+			}
+			if tt.expectedCode != model.CodeInvalidRequest && !reflect.DeepEqual(got, tt.expectedFilter) {
+				t.Errorf("Expected filter %+v, got %+v", tt.expectedFilter, got)
+			}
+		})
+	}
+}
+
+func TestPackHandler_GetStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		mockStock      map[int]int
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "Successfully get stock",
+			mockStock:      map[int]int{250: 10, 5000: 1},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Service returns error",
+			mockStock:      nil,
+			mockError:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				getStockFunc: func() (map[int]int, error) {
+					return tt.mockStock, tt.mockError
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/api/stock", nil)
+
+			serve(c, handler.GetStock)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if tt.mockError == nil {
+				stock, ok := response["stock"].(map[string]interface{})
+				if !ok || len(stock) != len(tt.mockStock) {
+					t.Errorf("Expected %d stock entries, got %v", len(tt.mockStock), response["stock"])
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_UpdateStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "Successfully update stock",
+			requestBody: map[string]interface{}{
+				"stock": map[string]int{"250": 10, "5000": 1},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Service returns error",
+			requestBody: map[string]interface{}{
+				"stock": map[string]int{"250": -1},
+			},
+			mockError:      model.NewValidationError("validation error"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Missing stock field",
+			requestBody: map[string]interface{}{
+				"wrong_field": map[string]int{"250": 1},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Non-numeric pack size",
+			requestBody: map[string]interface{}{
+				"stock": map[string]int{"large": 1},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received map[int]int
+			mockService := &mockPackService{
+				updateStockFunc: func(stock map[int]int) error {
+					received = stock
+					return tt.mockError
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(tt.requestBody)
+			c.Request, _ = http.NewRequest("PUT", "/api/stock", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			serve(c, handler.UpdateStock)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK && received[5000] != 1 {
+				t.Errorf("Expected stock to be passed to service, got %v", received)
+			}
+		})
+	}
+}
+
+func TestPackHandler_UpdatePackCosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "Successfully update pack costs",
+			requestBody: map[string]interface{}{
+				"pack_costs": map[string]int{"250": 40, "500": 70},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Service returns error",
+			requestBody: map[string]interface{}{
+				"pack_costs": map[string]int{"250": -1},
+			},
+			mockError:      model.NewValidationError("validation error"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Missing pack_costs field",
+			requestBody: map[string]interface{}{
+				"costs": map[string]int{"250": 1},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				updatePackCostsFunc: func(costs map[int]int) error {
+					return tt.mockError
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(tt.requestBody)
+			c.Request, _ = http.NewRequest("PUT", "/api/pack-costs", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			serve(c, handler.UpdatePackCosts)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if tt.expectedStatus == http.StatusOK {
+				if msg, ok := response["message"]; !ok || msg != "Pack costs updated successfully" {
+					t.Error("Expected success message")
+				}
+			} else {
+				if _, hasError := response["title"]; !hasError {
+					t.Error("Expected error in response")
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_GetPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := &mockPackService{
+		getPoliciesFunc: func() []calculator.Policy {
+			return []calculator.Policy{calculator.PolicyDefault, calculator.PolicyFewestPacks}
+		},
+	}
+
+	handler := NewPackHandler(mockService)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/policies", nil)
+
+	serve(c, handler.GetPolicies)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Policies []calculator.Policy `json:"policies"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Policies) != 2 || response.Policies[1].Name != "fewest-packs" {
+		t.Errorf("Unexpected policies: %+v", response.Policies)
+	}
+}
+
+func TestPackHandler_GetCacheStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		stats calculator.CacheStats
+	}{
+		{"Cache disabled", calculator.CacheStats{}},
+		{"Cache enabled", calculator.CacheStats{Enabled: true, Capacity: 100, TTLSeconds: 300, Entries: 2, Hits: 3, Misses: 1, HitRatio: 0.75}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				getCacheStatsFunc: func() calculator.CacheStats { return tt.stats },
+			}
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/api/cache/stats", nil)
+
+			serve(c, handler.GetCacheStats)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response calculator.CacheStats
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response != tt.stats {
+				t.Errorf("Cache stats = %+v, want %+v", response, tt.stats)
+			}
+		})
+	}
+}
+
+func TestPackHandler_CalculateOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "Valid order",
+			requestBody: map[string]interface{}{
+				"order_id": "SO-1",
+				"lines": []map[string]interface{}{
+					{"sku": "widget", "quantity": 501},
+					{"sku": "bolt", "quantity": 120, "pack_sizes": []int{50, 100}},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Missing lines",
+			requestBody: map[string]interface{}{
+				"order_id": "SO-1",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Service returns error",
+			requestBody: map[string]interface{}{
+				"lines": []map[string]interface{}{
+					{"sku": "widget", "quantity": 501},
+				},
+			},
+			mockError:      model.NewValidationError("order must have at least one line"),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *model.OrderRequest
+			mockService := &mockPackService{
+				calculateOrderFunc: func(request *model.OrderRequest) (*model.OrderResponse, error) {
+					received = request
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &model.OrderResponse{OrderID: request.OrderID}, nil
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(tt.requestBody)
+			c.Request, _ = http.NewRequest("POST", "/api/orders/calculate", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			serve(c, handler.CalculateOrder)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				if len(received.Lines) != 2 || received.Lines[1].SKU != "bolt" || received.Lines[1].Quantity != 120 {
+					t.Errorf("Order lines not passed to service: %+v", received.Lines)
+				}
+				if len(received.Lines[1].PackSizes) != 2 {
+					t.Errorf("Line pack sizes not passed to service: %+v", received.Lines[1])
+				}
+			}
+		})
+	}
+}
+
+func TestPackHandler_CalculateBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		mockError       error
+		expectedStatus  int
+		expectedResults []model.BatchItemResult // quantity in Result, code of failed requests
+	}{
+		{
+			name:           "JSON array",
+			contentType:    "application/json",
+			body:           `[{"quantity": 1}, {"quantity": "many"}, {"quantity": 501, "pack_sizes": [250, 500]}]`,
+			expectedStatus: http.StatusOK,
+			expectedResults: []model.BatchItemResult{
+				{Index: 0, Result: &model.PackResponse{Quantity: 1}},
+				{Index: 1, Code: model.CodeInvalidRequest},
+				{Index: 2, Result: &model.PackResponse{Quantity: 501}},
+			},
+		},
+		{
+			name:           "NDJSON stream",
+			contentType:    model.NDJSONContentType,
+			body:           "{\"quantity\": 1}\n\n{\"quantity\": \n{\"quantity\": 501}\n",
+			expectedStatus: http.StatusOK,
+			expectedResults: []model.BatchItemResult{
+				{Index: 0, Result: &model.PackResponse{Quantity: 1}},
+				{Index: 1, Code: model.CodeInvalidRequest},
+				{Index: 2, Result: &model.PackResponse{Quantity: 501}},
+			},
+		},
+		{
+			name:           "Not an array",
+			contentType:    "application/json",
+			body:           `{"quantity": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "NDJSON line too long",
+			contentType:    model.NDJSONContentType,
+			body:           `{"quantity": 1, "profile": "` + strings.Repeat("a", maxBatchLine) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "NDJSON line too long after a result",
+			contentType:    model.NDJSONContentType,
+			body:           "{\"quantity\": 1}\n{\"quantity\": 1, \"profile\": \"" + strings.Repeat("a", maxBatchLine) + "\"}",
+			expectedStatus: http.StatusOK,
+			expectedResults: []model.BatchItemResult{
+				{Index: 0, Result: &model.PackResponse{Quantity: 1}},
+				{Index: 1, Code: model.CodeInvalidRequest},
+			},
+		},
+		{
+			name:           "Too many requests",
+			contentType:    "application/json",
+			body:           "[" + strings.Repeat(`{"quantity": 1},`, maxBatchRequests) + `{"quantity": 1}]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty batch",
+			contentType:    "application/json",
+			body:           `[]`,
+			mockError:      model.NewFieldError("requests", "must have at least one request"),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				calculateBatchFunc: func(requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error {
+					index := 0
+					for request := range requests {
+						result := model.BatchItemResult{Index: index}
+						if request.Err != nil {
+							result.Error = request.Err.Error()
+							result.Code = model.CodeInvalidRequest
+						} else {
+							result.Result = &model.PackResponse{Quantity: request.Request.Quantity}
+						}
+						if err := emit(result); err != nil {
+							return err
+						}
+						index++
+					}
+					if index == 0 && tt.mockError == nil {
+						return model.NewFieldError("requests", "must have at least one request")
+					}
+					return tt.mockError
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request, _ = http.NewRequest("POST", "/api/calculate/batch", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", tt.contentType)
+
+			serve(c, handler.CalculateBatch)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var results []model.BatchItemResult
+			if tt.contentType == model.NDJSONContentType {
+				if contentType := w.Header().Get("Content-Type"); contentType != model.NDJSONContentType {
+					t.Errorf("Expected content type %s, got %s", model.NDJSONContentType, contentType)
+				}
+				decoder := json.NewDecoder(w.Body)
+				for decoder.More() {
+					var result model.BatchItemResult
+					if err := decoder.Decode(&result); err != nil {
+						t.Fatalf("Failed to decode result: %v", err)
+					}
+					results = append(results, result)
+				}
+			} else {
+				var response model.BatchResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Totals.Requests != len(tt.expectedResults) || response.Totals.Failed != 1 {
+					t.Errorf("Totals = %+v, want %d requests and 1 failed", response.Totals, len(tt.expectedResults))
+				}
+				results = response.Results
+			}
+
+			if len(results) != len(tt.expectedResults) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedResults), len(results))
+			}
+			for i, want := range tt.expectedResults {
+				got := results[i]
+				if got.Index != want.Index || got.Code != want.Code || (got.Result == nil) != (want.Result == nil) {
+					t.Errorf("Result %d = %+v, want %+v", i, got, want)
+					continue
+				}
+				if want.Result != nil && got.Result.Quantity != want.Result.Quantity {
+					t.Errorf("Result %d quantity = %d, want %d", i, got.Result.Quantity, want.Result.Quantity)
+				}
+				if want.Code != "" && got.Error == "" {
+					t.Errorf("Result %d has no error message", i)
+				}
+			}
+		})
+	}
+}
+
+// echoBatch answers every request of a batch with its quantity, like the service would
+func echoBatch(requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error {
+	index := 0
+	for request := range requests {
+		result := model.BatchItemResult{Index: index}
+		if request.Err != nil {
+			result.Error = request.Err.Error()
+			result.Code = model.CodeInvalidRequest
+		} else {
+			result.Result = &model.PackResponse{Quantity: request.Request.Quantity}
+		}
+		if err := emit(result); err != nil {
+			return err
+		}
+		index++
+	}
+	return nil
+}
+
+// blockingBody is a request body whose reads wait for the client, counting the reads
+// in progress
+type blockingBody struct {
+	*io.PipeReader
+	reading atomic.Int32
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	b.reading.Add(1)
+	defer b.reading.Add(-1)
+	return b.PipeReader.Read(p)
+}
+
+func TestPackHandler_CalculateBatch_StopsReadingOnFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The batch fails after the first result while the client is still sending
+	handler := NewPackHandler(&mockPackService{
+		calculateBatchFunc: func(requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error {
+			request := <-requests
+			if err := emit(model.BatchItemResult{Result: &model.PackResponse{Quantity: request.Request.Quantity}}); err != nil {
+				return err
+			}
+			return errors.New("client gone")
+		},
+	})
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	body := &blockingBody{PipeReader: reader}
+	go writer.Write([]byte("{\"quantity\": 1}\n"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/calculate/batch", body)
+	c.Request.Header.Set("Content-Type", model.NDJSONContentType)
+	serve(c, handler.CalculateBatch)
+
+	if reading := body.reading.Load(); reading != 0 {
+		t.Errorf("Expected no body read after the handler returned, got %d", reading)
+	}
+	if w.Code != http.StatusOK || len(c.Errors) != 1 {
+		t.Errorf("Expected the first result and the batch error recorded, got %d with %v", w.Code, c.Errors)
+	}
+}
+
+func TestPackHandler_CalculateBatch_StreamOverHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewPackHandler(&mockPackService{calculateBatchFunc: echoBatch})
+	router := gin.New()
+	router.POST("/api/calculate/batch", handler.ProblemDetails, handler.CalculateBatch)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		lines       int
+		wantResults int
+		wantLast    string // code of the last result
+	}{
+		{
+			name:        "Body still being read after the first result",
+			lines:       2000,
+			wantResults: 2000,
+		},
+		{
+			name:        "Too many requests",
+			lines:       maxBatchRequests + 5,
+			wantResults: maxBatchRequests + 1,
+			wantLast:    model.CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body strings.Builder
+			for i := 1; i <= tt.lines; i++ {
+				fmt.Fprintf(&body, "{\"quantity\": %d}\n", i)
+			}
+
+			resp, err := http.Post(server.URL+"/api/calculate/batch", model.NDJSONContentType, strings.NewReader(body.String()))
+			if err != nil {
+				t.Fatalf("POST error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			var results []model.BatchItemResult
+			decoder := json.NewDecoder(resp.Body)
+			for decoder.More() {
+				var result model.BatchItemResult
+				if err := decoder.Decode(&result); err != nil {
+					t.Fatalf("Failed to decode result %d: %v", len(results), err)
+				}
+				results = append(results, result)
+			}
+			if len(results) != tt.wantResults {
+				t.Fatalf("Expected %d results, got %d", tt.wantResults, len(results))
+			}
+			if last := results[len(results)-1]; last.Code != tt.wantLast {
+				t.Errorf("Last result code = %q, want %q", last.Code, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestPackHandler_CalculateCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	orders := "order_id,quantity\nSO-1,501\nSO-2,many\n"
+	wantCSV := "order_id,quantity,pack_250,pack_500,total_items,overage,total_packs,error\n" +
+		"SO-1,501,1,1,750,249,2,\n" +
+		"SO-2,many,,,,,,\"quantity must be a whole number, got: \"\"many\"\"\"\n"
+
+	// multipartBody uploads content as the file field of a form
+	multipartBody := func(content string) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if content != "" {
+			part, _ := writer.CreateFormFile("file", "orders.csv")
+			part.Write([]byte(content))
+		}
+		writer.Close()
+		return body, writer.FormDataContentType()
+	}
+
+	tests := []struct {
+		name           string
+		multipart      bool
+		body           string
+		expectedStatus int
+		expectedCSV    string
+	}{
+		{
+			name:           "CSV body",
+			body:           orders,
+			expectedStatus: http.StatusOK,
+			expectedCSV:    wantCSV,
+		},
+		{
+			name:           "Uploaded file",
+			multipart:      true,
+			body:           orders,
+			expectedStatus: http.StatusOK,
+			expectedCSV:    wantCSV,
+		},
+		{
+			name:           "Too many orders",
+			body:           "order_id,quantity\n" + strings.Repeat("SO-1,501\n", maxBatchRequests+1),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "CSV too large",
+			body:           "order_id,quantity\nSO-1," + strings.Repeat("1", maxCSVBody),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Uploaded file too large",
+			multipart:      true,
+			body:           "order_id,quantity\nSO-1," + strings.Repeat("1", maxCSVBody),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Missing file",
+			multipart:      true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "No orders",
+			body:           "order_id,quantity\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				calculateBatchFunc: func(requests <-chan service.BatchRequest, emit func(model.BatchItemResult) error) error {
+					index := 0
+					for request := range requests {
+						result := model.BatchItemResult{Index: index}
+						if request.Err != nil {
+							result.Error = request.Err.Error()
+							result.Code = model.CodeInvalidRequest
+						} else {
+							result.Result = model.NewPackResponse(request.Request.Quantity, map[int]int{250: 1, 500: 1}, []int{250, 500})
+						}
+						emit(result)
+						index++
+					}
+					return nil
+				},
+			}
+
+			handler := NewPackHandler(mockService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			if tt.multipart {
+				body, contentType := multipartBody(tt.body)
+				c.Request, _ = http.NewRequest("POST", "/api/calculate/csv", body)
+				c.Request.Header.Set("Content-Type", contentType)
+			} else {
+				c.Request, _ = http.NewRequest("POST", "/api/calculate/csv", strings.NewReader(tt.body))
+				c.Request.Header.Set("Content-Type", "text/csv")
+			}
+
+			serve(c, handler.CalculateCSV)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+				t.Errorf("Expected a CSV content type, got %s", contentType)
+			}
+			if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "attachment") {
+				t.Errorf("Expected an attachment, got %q", disposition)
+			}
+			if got := w.Body.String(); got != tt.expectedCSV {
+				t.Errorf("Expected CSV\n%s\ngot\n%s", tt.expectedCSV, got)
+			}
+		})
+	}
+}
+
+func TestPackHandler_Profiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	notFound := fmt.Errorf("%w: nuts", model.ErrProfileNotFound)
+	exists := fmt.Errorf("%w: bolts", model.ErrProfileExists)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		requestBody    map[string]interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{"List profiles", "GET", "/api/profiles", nil, nil, http.StatusOK},
+		{"Get profile", "GET", "/api/profiles/bolts", nil, nil, http.StatusOK},
+		{"Get missing profile", "GET", "/api/profiles/nuts", nil, notFound, http.StatusNotFound},
+		{"Create profile", "POST", "/api/profiles", map[string]interface{}{"name": "bolts", "pack_sizes": []int{50, 100}}, nil, http.StatusCreated},
+		{"Create existing profile", "POST", "/api/profiles", map[string]interface{}{"name": "bolts", "pack_sizes": []int{50}}, exists, http.StatusConflict},
+		{"Create invalid profile", "POST", "/api/profiles", map[string]interface{}{"name": "bolts"}, model.NewValidationError("pack sizes cannot be empty"), http.StatusBadRequest},
+		{"Update profile", "PUT", "/api/profiles/bolts", map[string]interface{}{"pack_sizes": []int{50, 100}}, nil, http.StatusOK},
+		{"Update renames profile", "PUT", "/api/profiles/bolts", map[string]interface{}{"name": "nuts", "pack_sizes": []int{50}}, nil, http.StatusBadRequest},
+		{"Update missing profile", "PUT", "/api/profiles/nuts", map[string]interface{}{"pack_sizes": []int{50}}, notFound, http.StatusNotFound},
+		{"Delete profile", "DELETE", "/api/profiles/bolts", nil, nil, http.StatusNoContent},
+		{"Delete missing profile", "DELETE", "/api/profiles/nuts", nil, notFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockPackService{
+				listProfilesFunc: func() ([]model.PackProfile, error) {
+					return []model.PackProfile{{Name: model.DefaultProfile}, {Name: "bolts"}}, tt.mockError
+				},
+				getProfileFunc: func(name string) (*model.PackProfile, error) {
+					return &model.PackProfile{Name: name, PackSizes: []int{50}}, tt.mockError
+				},
+				createProfileFunc: func(profile *model.PackProfile) error { return tt.mockError },
+				updateProfileFunc: func(profile *model.PackProfile) error {
+					if profile.Name != "bolts" && tt.mockError == nil {
+						t.Errorf("Expected name from URL, got %q", profile.Name)
+					}
+					return tt.mockError
+				},
+				deleteProfileFunc: func(name string) error { return tt.mockError },
+			}
+
+			router := gin.New()
+			handler := NewPackHandler(mockService)
+			router.Use(handler.ProblemDetails)
+			router.GET("/api/profiles", handler.ListProfiles)
+			router.POST("/api/profiles", handler.CreateProfile)
+			router.GET("/api/profiles/:name", handler.GetProfile)
+			router.PUT("/api/profiles/:name", handler.UpdateProfile)
+			router.DELETE("/api/profiles/:name", handler.DeleteProfile)
+
+			var body *bytes.Buffer
+			if tt.requestBody != nil {
+				data, _ := json.Marshal(tt.requestBody)
+				body = bytes.NewBuffer(data)
+			} else {
+				body = &bytes.Buffer{}
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
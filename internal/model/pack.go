@@ -1,28 +1,544 @@
 package model
 
+import "time"
+
+// DefaultProfile is the profile used when a request does not name one
+const DefaultProfile = "default"
+
+// PackProfile represents a named pack size configuration, e.g. for one product
+type PackProfile struct {
+	Name      string      `json:"name"`
+	PackSizes []int       `json:"pack_sizes"`
+	Stock     map[int]int `json:"stock,omitempty"`
+	PackCosts map[int]int `json:"pack_costs,omitempty"`
+}
+
 // PackSize represents a pack size configuration
 type PackSize struct {
 	ID   int `json:"id"`
 	Size int `json:"size"`
 }
 
+// PackSizesSnapshot is a consistent view of the default pack sizes and their version
+// The version grows with every change, so clients can detect concurrent updates
+type PackSizesSnapshot struct {
+	PackSizes []int `json:"pack_sizes"`
+	Version   int   `json:"version"`
+}
+
+// PackSizesChange describes who changes the default pack sizes and why
+type PackSizesChange struct {
+	Author string `json:"author,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// RollbackOf is the revision whose pack sizes are restored, if any
+	RollbackOf int `json:"rollback_of,omitempty"`
+}
+
+// PackSizesRevision records one change of the default pack sizes
+// Its version is the pack sizes version the change produced
+type PackSizesRevision struct {
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	OldPackSizes []int     `json:"old_pack_sizes"`
+	NewPackSizes []int     `json:"new_pack_sizes"`
+	PackSizesChange
+}
+
+// PackSizesDiff compares the pack sizes of two revisions
+type PackSizesDiff struct {
+	From          int   `json:"from"`
+	To            int   `json:"to"`
+	FromPackSizes []int `json:"from_pack_sizes"`
+	ToPackSizes   []int `json:"to_pack_sizes"`
+	Added         []int `json:"added"`
+	Removed       []int `json:"removed"`
+}
+
+// AnalysisRequest represents the request to analyze a pack size set
+// Without pack sizes the sizes of the profile (default when not given) are analyzed;
+// zero bounds analyze every quantity up to the conductor plus the largest pack
+type AnalysisRequest struct {
+	Profile   string `json:"profile,omitempty"`
+	PackSizes []int  `json:"pack_sizes,omitempty"`
+	From      int    `json:"from,omitempty"`
+	To        int    `json:"to,omitempty"`
+}
+
+// PackSizesAnalysis describes the amounts a pack size set reaches exactly and the
+// overage it causes over a range of quantities
+type PackSizesAnalysis struct {
+	PackSizes []int `json:"pack_sizes"`
+	// GCD divides every amount the pack sizes reach; above 1 the others are never reached exactly
+	GCD int `json:"gcd"`
+	// AllReachable reports whether every quantity is reached exactly, without overage
+	AllReachable bool `json:"all_reachable"`
+	// FrobeniusNumber is the largest multiple of GCD the pack sizes cannot reach, 0 when there is none
+	FrobeniusNumber int `json:"frobenius_number"`
+	// Conductor is the quantity from which every multiple of GCD is reached exactly
+	Conductor int `json:"conductor"`
+	// Gaps counts the multiples of GCD that are never reached exactly
+	Gaps       int              `json:"gaps"`
+	Quantities QuantityAnalysis `json:"quantities"`
+	// Sizes lists per pack size how many quantities of the range it packs optimally
+	Sizes          []PackSizeUsage `json:"sizes"`
+	RedundantSizes []int           `json:"redundant_sizes"`
+}
+
+// QuantityAnalysis describes the overage of a range of quantities
+type QuantityAnalysis struct {
+	From               int     `json:"from"`
+	To                 int     `json:"to"`
+	MaxOverage         int     `json:"max_overage"`
+	MaxOverageQuantity int     `json:"max_overage_quantity"`
+	AverageOverage     float64 `json:"average_overage"`
+	ExactQuantities    int     `json:"exact_quantities"`
+}
+
+// PackSizeUsage counts the quantities with an optimal breakdown using a pack size
+// A redundant size appears in no optimal breakdown of the range
+type PackSizeUsage struct {
+	Size       int  `json:"size"`
+	Quantities int  `json:"quantities"`
+	Redundant  bool `json:"redundant"`
+}
+
+// MaxComparisonQuantities is the most quantities a ComparisonRequest compares
+const MaxComparisonQuantities = 10_000
+
+// ComparisonRequest represents the request to compare a proposed pack size set with the
+// pack sizes of the profile (default when not given)
+// Quantities are listed, or run from From (1 when not given) to To in steps of Step
+// (1 when not given)
+type ComparisonRequest struct {
+	Profile    string `json:"profile,omitempty"`
+	PackSizes  []int  `json:"pack_sizes"`
+	Quantities []int  `json:"quantities,omitempty"`
+	From       int    `json:"from,omitempty"`
+	To         int    `json:"to,omitempty"`
+	Step       int    `json:"step,omitempty"`
+	// Summary leaves the comparison of each quantity out of the response
+	Summary bool `json:"summary,omitempty"`
+}
+
+// PackSizesComparison compares the distributions of the same quantities under the current
+// (base) and the proposed (candidate) pack sizes
+type PackSizesComparison struct {
+	Base       []int                `json:"base_pack_sizes"`
+	Candidate  []int                `json:"candidate_pack_sizes"`
+	Quantities []QuantityComparison `json:"quantities,omitempty"`
+	Summary    ComparisonSummary    `json:"summary"`
+}
+
+// QuantityComparison compares the distributions of one quantity
+// The differences are candidate minus base, and zero unless both sets reach the quantity
+type QuantityComparison struct {
+	Quantity    int               `json:"quantity"`
+	Base        ComparisonOutcome `json:"base"`
+	Candidate   ComparisonOutcome `json:"candidate"`
+	ItemsDiff   int               `json:"items_diff"`
+	OverageDiff int               `json:"overage_diff"`
+	PacksDiff   int               `json:"packs_diff"`
+}
+
+// ComparisonOutcome is the distribution of one quantity under one pack size set
+type ComparisonOutcome struct {
+	PackBreakdown map[int]int `json:"pack_breakdown,omitempty"`
+	TotalItems    int         `json:"total_items"`
+	Overage       int         `json:"overage"`
+	TotalPacks    int         `json:"total_packs"`
+	Unreachable   bool        `json:"unreachable,omitempty"`
+}
+
+// ComparisonSummary aggregates a comparison
+// Better, Worse and Same count the quantities where the candidate ships fewer, more or as
+// many items, then packs, as the base
+type ComparisonSummary struct {
+	Quantities  int              `json:"quantities"`
+	Base        ComparisonTotals `json:"base"`
+	Candidate   ComparisonTotals `json:"candidate"`
+	ItemsDiff   int              `json:"items_diff"`
+	OverageDiff int              `json:"overage_diff"`
+	PacksDiff   int              `json:"packs_diff"`
+	Better      int              `json:"better"`
+	Worse       int              `json:"worse"`
+	Same        int              `json:"same"`
+}
+
+// ComparisonTotals sums the outcomes of one pack size set over the quantities it reaches
+type ComparisonTotals struct {
+	TotalItems     int     `json:"total_items"`
+	TotalOverage   int     `json:"total_overage"`
+	MaxOverage     int     `json:"max_overage"`
+	AverageOverage float64 `json:"average_overage"`
+	TotalPacks     int     `json:"total_packs"`
+	Unreachable    int     `json:"unreachable"`
+}
+
+// Limits of a RecommendationRequest, keeping a search within reasonable time
+const (
+	MaxRecommendationOrders      = 10_000
+	MaxRecommendationCandidates  = 200
+	MaxRecommendationSizes       = 10
+	MaxRecommendationEvaluations = 20_000
+)
+
+// RecommendationRequest represents the request to search for the pack size set packing a
+// history of orders best
+// The orders are uploaded, or built from the quotes History matches (its Limit and Offset
+// are ignored). Candidates run from MinSize to MaxSize in steps of Step (1 when not given).
+// The search minimizes the overage times OverageWeight plus the packs times PackWeight;
+// when both weights are 0 only the overage counts
+type RecommendationRequest struct {
+	Orders         []OrderQuantity `json:"orders"`
+	History        *QuoteFilter    `json:"history,omitempty"`
+	MinSize        int             `json:"min_size"`
+	MaxSize        int             `json:"max_size"`
+	Step           int             `json:"step,omitempty"`
+	MaxSizes       int             `json:"max_sizes"`
+	OverageWeight  int             `json:"overage_weight,omitempty"`
+	PackWeight     int             `json:"pack_weight,omitempty"`
+	MaxEvaluations int             `json:"max_evaluations,omitempty"`
+}
+
+// OrderQuantity is a quantity ordered Count times; a zero count counts once
+type OrderQuantity struct {
+	Quantity int `json:"quantity"`
+	Count    int `json:"count,omitempty"`
+}
+
+// Statuses of a RecommendationJob
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// RecommendationJob tracks a pack size recommendation running in the background
+// Result is set once it succeeded, Error and Code once it failed
+type RecommendationJob struct {
+	ID             string                `json:"id"`
+	Status         string                `json:"status"`
+	CreatedAt      time.Time             `json:"created_at"`
+	StartedAt      *time.Time            `json:"started_at,omitempty"`
+	FinishedAt     *time.Time            `json:"finished_at,omitempty"`
+	Evaluated      int                   `json:"evaluated"`
+	MaxEvaluations int                   `json:"max_evaluations"`
+	Result         *RecommendationResult `json:"result,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	Code           string                `json:"code,omitempty"`
+}
+
+// RecommendationResult compares the recommended pack sizes with the current default ones
+// Current and Change are omitted when there are no default pack sizes
+type RecommendationResult struct {
+	Recommended PackSetEvaluation  `json:"recommended"`
+	Current     *PackSetEvaluation `json:"current,omitempty"`
+	Change      *EvaluationChange  `json:"change,omitempty"`
+	Candidates  []int              `json:"candidates"`
+	// Exhaustive reports whether every set of the candidates was evaluated
+	Exhaustive bool `json:"exhaustive"`
+}
+
+// PackSetEvaluation is how a pack size set packs a history of orders
+type PackSetEvaluation struct {
+	PackSizes      []int   `json:"pack_sizes"`
+	Orders         int     `json:"orders"`
+	TotalItems     int     `json:"total_items"`
+	TotalOverage   int     `json:"total_overage"`
+	TotalPacks     int     `json:"total_packs"`
+	AverageOverage float64 `json:"average_overage"`
+	Score          int     `json:"score"`
+}
+
+// EvaluationChange is the recommended evaluation minus the current one
+// Negative values are savings
+type EvaluationChange struct {
+	TotalItems   int `json:"total_items"`
+	TotalOverage int `json:"total_overage"`
+	TotalPacks   int `json:"total_packs"`
+}
+
+// Objectives accepted by PackRequest, applied after minimizing items
+const (
+	ObjectivePacks = "packs" // fewest packs (default)
+	ObjectiveCost  = "cost"  // lowest cost, then fewest packs
+)
+
 // PackRequest represents the request to calculate pack distribution
 type PackRequest struct {
-	Quantity  int   `json:"quantity" binding:"required,min=1"`
-	PackSizes []int `json:"pack_sizes,omitempty"`
+	Quantity  int         `json:"quantity" binding:"required,min=1"`
+	Profile   string      `json:"profile,omitempty"`
+	PackSizes []int       `json:"pack_sizes,omitempty"`
+	Stock     map[int]int `json:"stock,omitempty"`
+	PackCosts map[int]int `json:"pack_costs,omitempty"`
+	Objective string      `json:"objective,omitempty"`
+	// Policy names the ranking rules to apply; objective is kept as a shorthand
+	Policy            string  `json:"policy,omitempty"`
+	MaxOveragePercent float64 `json:"max_overage_percent,omitempty"`
+	// Alternatives asks for up to this many distributions ranked by the same rules and
+	// within the same stock as the breakdown, which is ranked first
+	Alternatives int `json:"alternatives,omitempty"`
+	// Explain asks for a trace of why the breakdown was chosen
+	Explain bool `json:"explain,omitempty"`
 }
 
 // PackResponse represents the response with pack distribution
 type PackResponse struct {
-	Quantity      int         `json:"quantity"`
+	Quantity      int               `json:"quantity"`
+	TotalItems    int               `json:"total_items"`
+	TotalPacks    int               `json:"total_packs"`
+	PackBreakdown map[int]int       `json:"pack_breakdown"`
+	PackSizesUsed []int             `json:"pack_sizes_used"`
+	TotalCost     int               `json:"total_cost,omitempty"`
+	Policy        string            `json:"policy,omitempty"`
+	Alternatives  []PackAlternative `json:"alternatives,omitempty"`
+	Explanation   *PackExplanation  `json:"explanation,omitempty"`
+	// QuoteID identifies the quote the calculation was recorded as
+	QuoteID string `json:"quote_id,omitempty"`
+	// PackSetVersion is the revision of the default pack sizes used, nil when the sizes
+	// came from the request or another profile
+	// It and Algorithm are only reported by PackResponseV2, so this response stays as it was
+	PackSetVersion *int `json:"-"`
+	// Algorithm names the calculator that chose the breakdown
+	Algorithm string `json:"-"`
+}
+
+// PackResponseV2 is the response of /api/v2/calculate: the packs are an array ordered
+// from the largest pack size down, and the overage is worked out
+type PackResponseV2 struct {
+	Quantity       int     `json:"quantity"`
+	Packs          []Pack  `json:"packs"`
+	TotalItems     int     `json:"total_items"`
+	TotalPacks     int     `json:"total_packs"`
+	Overage        int     `json:"overage"`
+	OveragePercent float64 `json:"overage_percent"`
+	PackSizesUsed  []int   `json:"pack_sizes_used"`
+	// PackSetVersion is left out when the sizes came from the request or another profile
+	PackSetVersion *int                `json:"pack_set_version,omitempty"`
+	Algorithm      string              `json:"algorithm"`
+	TotalCost      int                 `json:"total_cost,omitempty"`
+	Policy         string              `json:"policy,omitempty"`
+	Alternatives   []PackAlternativeV2 `json:"alternatives,omitempty"`
+	Explanation    *PackExplanation    `json:"explanation,omitempty"`
+	QuoteID        string              `json:"quote_id,omitempty"`
+}
+
+// Pack is the number of packs of one size sent and the items they hold
+type Pack struct {
+	PackSize int `json:"pack_size"`
+	Count    int `json:"count"`
+	Items    int `json:"items"`
+}
+
+// PackAlternativeV2 is a PackAlternative with its packs as an ordered array
+type PackAlternativeV2 struct {
+	Rank       int    `json:"rank"`
+	Packs      []Pack `json:"packs"`
+	TotalItems int    `json:"total_items"`
+	Overage    int    `json:"overage"`
+	TotalPacks int    `json:"total_packs"`
+}
+
+// PackAlternative is one candidate pack distribution; rank 1 is the best
+type PackAlternative struct {
+	Rank          int         `json:"rank"`
+	PackBreakdown map[int]int `json:"pack_breakdown"`
 	TotalItems    int         `json:"total_items"`
+	ItemsOver     int         `json:"items_over"`
 	TotalPacks    int         `json:"total_packs"`
-	PackBreakdown map[int]int `json:"pack_breakdown"`
-	PackSizesUsed []int       `json:"pack_sizes_used"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+// PackExplanation traces why the default rules chose a breakdown
+type PackExplanation struct {
+	// MinimumAmount is the least amount of items the pack sizes can make for the quantity
+	MinimumAmount int               `json:"minimum_amount"`
+	Candidates    []AmountCandidate `json:"candidates"`
+	PackCounts    []PackCountOption `json:"pack_counts"`
+}
+
+// AmountCandidate is an amount, or a range of unreachable amounts, considered for the order
+type AmountCandidate struct {
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Reachable bool   `json:"reachable"`
+	Chosen    bool   `json:"chosen"`
+	Reason    string `json:"reason"`
+}
+
+// PackCountOption is the best distribution of the minimum amount ending with one pack size
+type PackCountOption struct {
+	LastPack      int         `json:"last_pack"`
+	TotalPacks    int         `json:"total_packs,omitempty"`
+	PackBreakdown map[int]int `json:"pack_breakdown,omitempty"`
+	Chosen        bool        `json:"chosen"`
+	Reason        string      `json:"reason"`
+}
+
+// OrderLine represents one product of a multi-line order
+// Each line accepts the same options as a single PackRequest
+type OrderLine struct {
+	SKU string `json:"sku"`
+	PackRequest
+}
+
+// OrderRequest represents the request to calculate packs for several products at once
+type OrderRequest struct {
+	OrderID string      `json:"order_id,omitempty"`
+	Lines   []OrderLine `json:"lines" binding:"required,min=1"`
+}
+
+// OrderLineResult represents the outcome of one order line
+// Either Result or Error is set
+type OrderLineResult struct {
+	SKU    string        `json:"sku"`
+	Result *PackResponse `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Code   string        `json:"code,omitempty"`
+}
+
+// OrderTotals represents the totals of the successfully calculated lines of an order
+type OrderTotals struct {
+	Lines         int `json:"lines"`
+	FailedLines   int `json:"failed_lines"`
+	TotalQuantity int `json:"total_quantity"`
+	TotalItems    int `json:"total_items"`
+	TotalPacks    int `json:"total_packs"`
+	TotalCost     int `json:"total_cost,omitempty"`
+}
+
+// OrderResponse represents the response with pack distributions per order line
+type OrderResponse struct {
+	OrderID string            `json:"order_id,omitempty"`
+	Lines   []OrderLineResult `json:"lines"`
+	Totals  OrderTotals       `json:"totals"`
+}
+
+// BatchItemResult represents the outcome of one request of a batch
+// Index is the position of the request in the batch; either Result or Error is set
+type BatchItemResult struct {
+	Index  int           `json:"index"`
+	Result *PackResponse `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Code   string        `json:"code,omitempty"`
+}
+
+// BatchTotals counts the requests of a batch
+type BatchTotals struct {
+	Requests int `json:"requests"`
+	Failed   int `json:"failed"`
+}
+
+// Quote is a successful calculation kept so it can be looked up later
+// PackSizesVersion is the revision of the default pack sizes used; it is omitted when the
+// sizes came from the request or another profile
+type Quote struct {
+	ID               string       `json:"id"`
+	CreatedAt        time.Time    `json:"created_at"`
+	Profile          string       `json:"profile"`
+	PackSizesVersion *int         `json:"pack_sizes_version,omitempty"`
+	Request          PackRequest  `json:"request"`
+	Response         PackResponse `json:"response"`
+}
+
+// Page sizes of a quote listing
+const (
+	DefaultQuoteLimit = 20
+	MaxQuoteLimit     = 100
+)
+
+// QuoteFilter selects the quotes of a listing, newest first
+// Zero fields match every quote; Since and Until bound the creation time inclusively.
+// A zero Limit lists every matching quote
+type QuoteFilter struct {
+	Profile          string    `json:"profile,omitempty"`
+	MinQuantity      int       `json:"min_quantity,omitempty"`
+	MaxQuantity      int       `json:"max_quantity,omitempty"`
+	Since            time.Time `json:"since,omitempty"`
+	Until            time.Time `json:"until,omitempty"`
+	PackSizesVersion int       `json:"pack_sizes_version,omitempty"`
+	Limit            int       `json:"limit,omitempty"`
+	Offset           int       `json:"offset,omitempty"`
+}
+
+// QuoteList is one page of the quotes matching a filter
+// Total counts every matching quote, not only the ones of the page
+type QuoteList struct {
+	Quotes []Quote `json:"quotes"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// IdempotentResponse is the first response to a request sent with an Idempotency-Key,
+// replayed to retries of the same request until it expires
+// Fingerprint identifies the request, so a different request reusing the key is told apart
+type IdempotentResponse struct {
+	Key         string              `json:"key"`
+	Fingerprint string              `json:"fingerprint"`
+	Status      int                 `json:"status"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+}
+
+// BatchResponse represents the results of a batch in request order
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	Totals  BatchTotals       `json:"totals"`
+}
+
+// Machine-readable error codes returned in ProblemDetails
+const (
+	CodeInvalidRequest    = "invalid_request"
+	CodeValidationFailed  = "validation_failed"
+	CodeNotFound          = "not_found"
+	CodeNoPackSizes       = "no_pack_sizes"
+	CodeQuantityTooLarge  = "quantity_too_large"
+	CodeBodyTooLarge      = "body_too_large"
+	CodeTableTooLarge     = "table_too_large"
+	CodeTimeout           = "calculation_timeout"
+	CodeCanceled          = "calculation_canceled"
+	CodeUnreachable       = "unreachable"
+	CodeInsufficientStock = "insufficient_stock"
+	CodeOverageLimit      = "overage_limit"
+	CodeProfileNotFound   = "profile_not_found"
+	CodeProfileExists     = "profile_exists"
+	CodeRevisionNotFound  = "revision_not_found"
+	CodeVersionConflict   = "version_conflict"
+	CodeJobNotFound       = "job_not_found"
+	CodeTooManyJobs       = "too_many_jobs"
+	CodeQuoteNotFound     = "quote_not_found"
+	CodeIdempotencyInUse  = "idempotency_key_in_use"
+	CodeIdempotencyReused = "idempotency_key_reused"
+	CodeRepositoryFailure = "repository_failure"
+	CodeInternal          = "internal_error"
+)
+
+// ProblemContentType is the media type of ProblemDetails responses
+const ProblemContentType = "application/problem+json"
+
+// NDJSONContentType is the media type of newline-delimited JSON batches
+const NDJSONContentType = "application/x-ndjson"
+
+// ProblemTypePrefix is prepended to an error code to form the problem type URI
+const ProblemTypePrefix = "/problems/"
+
+// ProblemDetails represents an RFC 7807 error response
+// Code and Errors are extension members
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request, e.g. pack_sizes[2]
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
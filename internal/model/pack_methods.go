@@ -1,13 +1,354 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors returned when working with pack profiles
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrProfileExists   = errors.New("profile already exists")
+)
+
+// ErrVersionConflict is returned when the pack sizes changed since the version a client read
+var ErrVersionConflict = errors.New("pack sizes were changed by another request")
+
+// ErrRevisionNotFound is returned when a pack sizes revision does not exist
+var ErrRevisionNotFound = errors.New("pack sizes revision not found")
+
+// Errors returned when working with background jobs
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrTooManyJobs = errors.New("too many jobs running, try again later")
+)
+
+// ErrQuoteNotFound is returned when a quote does not exist
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// profileNamePattern keeps profile names safe to use in URLs
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
 // Validate validates the PackRequest
+// Every invalid field is reported, not only the first one
 func (r *PackRequest) Validate() error {
+	var fields []FieldError
 	if r.Quantity <= 0 {
-		return NewValidationError("quantity must be greater than 0")
+		fields = append(fields, FieldError{"quantity", "must be greater than 0"})
+	}
+	if r.Profile != "" && !profileNamePattern.MatchString(r.Profile) {
+		fields = append(fields, FieldError{"profile", profileNameMessage(r.Profile)})
+	}
+	if r.Objective != "" && r.Objective != ObjectivePacks && r.Objective != ObjectiveCost {
+		fields = append(fields, FieldError{"objective", fmt.Sprintf("must be %q or %q, got: %q", ObjectivePacks, ObjectiveCost, r.Objective)})
+	}
+	if r.Objective != "" && r.Policy != "" {
+		fields = append(fields, FieldError{"objective", "cannot be used together with policy"})
+	}
+	if r.MaxOveragePercent < 0 {
+		fields = append(fields, FieldError{"max_overage_percent", fmt.Sprintf("cannot be negative, got: %v", r.MaxOveragePercent)})
+	}
+	if r.Alternatives < 0 {
+		fields = append(fields, FieldError{"alternatives", fmt.Sprintf("cannot be negative, got: %d", r.Alternatives)})
+	}
+	fields = append(fields, countFieldErrors("pack_costs", r.PackCosts)...)
+	fields = append(fields, countFieldErrors("stock", r.Stock)...)
+	return NewFieldErrors(fields)
+}
+
+// Validate validates the AnalysisRequest
+func (r *AnalysisRequest) Validate() error {
+	var fields []FieldError
+	if r.Profile != "" && !profileNamePattern.MatchString(r.Profile) {
+		fields = append(fields, FieldError{"profile", profileNameMessage(r.Profile)})
+	}
+	if len(r.PackSizes) > 0 {
+		fields = append(fields, packSizesFieldErrors("pack_sizes", r.PackSizes)...)
+	}
+	if r.From < 0 {
+		fields = append(fields, FieldError{"from", fmt.Sprintf("cannot be negative, got: %d", r.From)})
+	}
+	if r.To < 0 {
+		fields = append(fields, FieldError{"to", fmt.Sprintf("cannot be negative, got: %d", r.To)})
+	}
+	if r.To > 0 && r.To < max(r.From, 1) {
+		fields = append(fields, FieldError{"to", fmt.Sprintf("cannot be before from, got: %d", r.To)})
+	}
+	return NewFieldErrors(fields)
+}
+
+// Validate validates the ComparisonRequest
+func (r *ComparisonRequest) Validate() error {
+	var fields []FieldError
+	if r.Profile != "" && !profileNamePattern.MatchString(r.Profile) {
+		fields = append(fields, FieldError{"profile", profileNameMessage(r.Profile)})
+	}
+	fields = append(fields, packSizesFieldErrors("pack_sizes", r.PackSizes)...)
+
+	if len(r.Quantities) > 0 {
+		if r.From != 0 || r.To != 0 || r.Step != 0 {
+			fields = append(fields, FieldError{"quantities", "cannot be used together with from, to and step"})
+		}
+		if len(r.Quantities) > MaxComparisonQuantities {
+			fields = append(fields, FieldError{"quantities", fmt.Sprintf("cannot hold more than %d quantities, got: %d", MaxComparisonQuantities, len(r.Quantities))})
+		}
+		for i, quantity := range r.Quantities {
+			if quantity <= 0 {
+				fields = append(fields, FieldError{fmt.Sprintf("quantities[%d]", i), fmt.Sprintf("must be positive, got: %d", quantity)})
+			}
+		}
+		return NewFieldErrors(fields)
+	}
+
+	switch {
+	case r.From < 0:
+		fields = append(fields, FieldError{"from", fmt.Sprintf("cannot be negative, got: %d", r.From)})
+	case r.To <= 0:
+		fields = append(fields, FieldError{"to", "is required without quantities"})
+	case r.To < max(r.From, 1):
+		fields = append(fields, FieldError{"to", fmt.Sprintf("cannot be before from, got: %d", r.To)})
+	case r.Step < 0:
+		fields = append(fields, FieldError{"step", fmt.Sprintf("cannot be negative, got: %d", r.Step)})
+	default:
+		if count := (r.To-max(r.From, 1))/max(r.Step, 1) + 1; count > MaxComparisonQuantities {
+			fields = append(fields, FieldError{"to", fmt.Sprintf("gives %d quantities, more than %d", count, MaxComparisonQuantities)})
+		}
+	}
+	return NewFieldErrors(fields)
+}
+
+// Validate validates the RecommendationRequest
+func (r *RecommendationRequest) Validate() error {
+	var fields []FieldError
+	switch {
+	case r.History != nil && len(r.Orders) > 0:
+		fields = append(fields, FieldError{"history", "cannot be combined with orders"})
+	case r.History != nil:
+		for _, field := range r.History.fieldErrors() {
+			fields = append(fields, FieldError{"history." + field.Field, field.Message})
+		}
+	case len(r.Orders) == 0:
+		fields = append(fields, FieldError{"orders", "cannot be empty"})
+	case len(r.Orders) > MaxRecommendationOrders:
+		fields = append(fields, FieldError{"orders", fmt.Sprintf("cannot hold more than %d quantities, got: %d", MaxRecommendationOrders, len(r.Orders))})
+	}
+	for i, order := range r.Orders {
+		if order.Quantity <= 0 {
+			fields = append(fields, FieldError{fmt.Sprintf("orders[%d].quantity", i), fmt.Sprintf("must be positive, got: %d", order.Quantity)})
+		}
+		if order.Count < 0 {
+			fields = append(fields, FieldError{fmt.Sprintf("orders[%d].count", i), fmt.Sprintf("cannot be negative, got: %d", order.Count)})
+		}
+	}
+
+	if r.MinSize <= 0 {
+		fields = append(fields, FieldError{"min_size", fmt.Sprintf("must be positive, got: %d", r.MinSize)})
+	}
+	if r.MaxSize < r.MinSize {
+		fields = append(fields, FieldError{"max_size", fmt.Sprintf("cannot be below min_size, got: %d", r.MaxSize)})
+	}
+	if r.Step < 0 {
+		fields = append(fields, FieldError{"step", fmt.Sprintf("cannot be negative, got: %d", r.Step)})
+	}
+	if candidates := r.CandidateCount(); candidates > MaxRecommendationCandidates {
+		fields = append(fields, FieldError{"max_size", fmt.Sprintf("gives %d candidate sizes, more than %d", candidates, MaxRecommendationCandidates)})
+	}
+	if r.MaxSizes < 1 || r.MaxSizes > MaxRecommendationSizes {
+		fields = append(fields, FieldError{"max_sizes", fmt.Sprintf("must be between 1 and %d, got: %d", MaxRecommendationSizes, r.MaxSizes)})
+	}
+
+	if r.OverageWeight < 0 {
+		fields = append(fields, FieldError{"overage_weight", fmt.Sprintf("cannot be negative, got: %d", r.OverageWeight)})
+	}
+	if r.PackWeight < 0 {
+		fields = append(fields, FieldError{"pack_weight", fmt.Sprintf("cannot be negative, got: %d", r.PackWeight)})
+	}
+	if r.MaxEvaluations < 0 || r.MaxEvaluations > MaxRecommendationEvaluations {
+		fields = append(fields, FieldError{"max_evaluations", fmt.Sprintf("must be between 0 and %d, got: %d", MaxRecommendationEvaluations, r.MaxEvaluations)})
+	}
+	return NewFieldErrors(fields)
+}
+
+// CandidateCount returns how many candidate sizes the request range holds
+func (r *RecommendationRequest) CandidateCount() int {
+	if r.MinSize <= 0 || r.MaxSize < r.MinSize || r.Step < 0 {
+		return 0
+	}
+	return (r.MaxSize-r.MinSize)/max(r.Step, 1) + 1
+}
+
+// Candidates lists the candidate sizes of the request range
+func (r *RecommendationRequest) Candidates() []int {
+	candidates := make([]int, 0, r.CandidateCount())
+	for size := r.MinSize; size <= r.MaxSize && len(candidates) < cap(candidates); size += max(r.Step, 1) {
+		candidates = append(candidates, size)
+	}
+	return candidates
+}
+
+// Validate validates the QuoteFilter
+func (f *QuoteFilter) Validate() error {
+	return NewFieldErrors(f.fieldErrors())
+}
+
+// fieldErrors lists the invalid fields of the QuoteFilter
+func (f *QuoteFilter) fieldErrors() []FieldError {
+	var fields []FieldError
+	if f.Profile != "" && !profileNamePattern.MatchString(f.Profile) {
+		fields = append(fields, FieldError{"profile", profileNameMessage(f.Profile)})
+	}
+	if f.MinQuantity < 0 {
+		fields = append(fields, FieldError{"min_quantity", fmt.Sprintf("cannot be negative, got: %d", f.MinQuantity)})
+	}
+	if f.MaxQuantity < 0 {
+		fields = append(fields, FieldError{"max_quantity", fmt.Sprintf("cannot be negative, got: %d", f.MaxQuantity)})
+	} else if f.MaxQuantity > 0 && f.MaxQuantity < f.MinQuantity {
+		fields = append(fields, FieldError{"max_quantity", fmt.Sprintf("cannot be below min_quantity, got: %d", f.MaxQuantity)})
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		fields = append(fields, FieldError{"until", "cannot be before since"})
+	}
+	if f.PackSizesVersion < 0 {
+		fields = append(fields, FieldError{"pack_sizes_version", fmt.Sprintf("cannot be negative, got: %d", f.PackSizesVersion)})
+	}
+	if f.Limit < 0 || f.Limit > MaxQuoteLimit {
+		fields = append(fields, FieldError{"limit", fmt.Sprintf("must be between 1 and %d, got: %d", MaxQuoteLimit, f.Limit)})
+	}
+	if f.Offset < 0 {
+		fields = append(fields, FieldError{"offset", fmt.Sprintf("cannot be negative, got: %d", f.Offset)})
+	}
+	return fields
+}
+
+// Matches reports whether quote passes every criterion of the filter
+// Limit and Offset select a page and are ignored
+func (f *QuoteFilter) Matches(quote *Quote) bool {
+	quantity := quote.Request.Quantity
+	switch {
+	case f.Profile != "" && quote.Profile != f.Profile:
+		return false
+	case f.MinQuantity > 0 && quantity < f.MinQuantity:
+		return false
+	case f.MaxQuantity > 0 && quantity > f.MaxQuantity:
+		return false
+	case !f.Since.IsZero() && quote.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && quote.CreatedAt.After(f.Until):
+		return false
+	case f.PackSizesVersion > 0 && (quote.PackSizesVersion == nil || *quote.PackSizesVersion != f.PackSizesVersion):
+		return false
+	}
+	return true
+}
+
+// Expired reports whether the response can no longer be replayed at now
+func (r *IdempotentResponse) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// ValidateStock validates that stock is keyed by positive pack sizes with non-negative counts
+func ValidateStock(stock map[int]int) error {
+	return NewFieldErrors(countFieldErrors("stock", stock))
+}
+
+// ValidatePackCosts validates that costs are keyed by positive pack sizes with non-negative costs
+func ValidatePackCosts(costs map[int]int) error {
+	return NewFieldErrors(countFieldErrors("pack_costs", costs))
+}
+
+// ValidatePackSizes validates that sizes is not empty and only holds positive sizes
+func ValidatePackSizes(sizes []int) error {
+	return NewFieldErrors(packSizesFieldErrors("pack_sizes", sizes))
+}
+
+// countFieldErrors checks a map keyed by pack size, e.g. stock, in pack size order
+func countFieldErrors(field string, counts map[int]int) []FieldError {
+	sizes := make([]int, 0, len(counts))
+	for size := range counts {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	var fields []FieldError
+	for _, size := range sizes {
+		name := fmt.Sprintf("%s[%d]", field, size)
+		if size <= 0 {
+			fields = append(fields, FieldError{name, "pack size must be positive"})
+		}
+		if counts[size] < 0 {
+			fields = append(fields, FieldError{name, fmt.Sprintf("cannot be negative, got: %d", counts[size])})
+		}
+	}
+	return fields
+}
+
+// packSizesFieldErrors checks that sizes is not empty and reports every non-positive size by index
+func packSizesFieldErrors(field string, sizes []int) []FieldError {
+	if len(sizes) == 0 {
+		return []FieldError{{field, "cannot be empty"}}
+	}
+
+	var fields []FieldError
+	for i, size := range sizes {
+		if size <= 0 {
+			fields = append(fields, FieldError{fmt.Sprintf("%s[%d]", field, i), fmt.Sprintf("must be positive, got: %d", size)})
+		}
+	}
+	return fields
+}
+
+// Validate validates the PackProfile
+func (p *PackProfile) Validate() error {
+	var fields []FieldError
+	if !profileNamePattern.MatchString(p.Name) {
+		fields = append(fields, FieldError{"name", profileNameMessage(p.Name)})
+	}
+	fields = append(fields, packSizesFieldErrors("pack_sizes", p.PackSizes)...)
+	fields = append(fields, countFieldErrors("stock", p.Stock)...)
+	fields = append(fields, countFieldErrors("pack_costs", p.PackCosts)...)
+	return NewFieldErrors(fields)
+}
+
+// ValidateProfileName validates that a profile name is non-empty and URL safe
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return NewFieldError("name", profileNameMessage(name))
 	}
 	return nil
 }
 
+func profileNameMessage(name string) string {
+	return fmt.Sprintf("must be 1-64 letters, digits, '.', '-' or '_', got: %q", name)
+}
+
+// Copy returns a deep copy of the profile with sorted pack sizes
+func (p *PackProfile) Copy() *PackProfile {
+	sizes := make([]int, len(p.PackSizes))
+	copy(sizes, p.PackSizes)
+	sort.Ints(sizes)
+
+	return &PackProfile{
+		Name:      p.Name,
+		PackSizes: sizes,
+		Stock:     CopyCounts(p.Stock),
+		PackCosts: CopyCounts(p.PackCosts),
+	}
+}
+
+// CopyCounts returns a copy of a map keyed by pack size
+func CopyCounts(counts map[int]int) map[int]int {
+	result := make(map[int]int, len(counts))
+	for size, count := range counts {
+		result[size] = count
+	}
+	return result
+}
+
 // GetValidPackSizes returns only valid (positive) pack sizes from the request
 func (r *PackRequest) GetValidPackSizes() []int {
 	if len(r.PackSizes) == 0 {
@@ -28,6 +369,16 @@ func (r *PackRequest) HasPackSizes() bool {
 	return len(r.PackSizes) > 0
 }
 
+// HasStock returns true if the request limits the available packs
+func (r *PackRequest) HasStock() bool {
+	return len(r.Stock) > 0
+}
+
+// HasPackCosts returns true if the request contains pack costs
+func (r *PackRequest) HasPackCosts() bool {
+	return len(r.PackCosts) > 0
+}
+
 // CalculateTotals calculates and sets TotalItems and TotalPacks from PackBreakdown
 func (r *PackResponse) CalculateTotals() {
 	totalItems := 0
@@ -42,6 +393,15 @@ func (r *PackResponse) CalculateTotals() {
 	r.TotalPacks = totalPacks
 }
 
+// CalculateCost sets TotalCost from PackBreakdown using the cost of one pack per size
+func (r *PackResponse) CalculateCost(costs map[int]int) {
+	totalCost := 0
+	for packSize, count := range r.PackBreakdown {
+		totalCost += costs[packSize] * count
+	}
+	r.TotalCost = totalCost
+}
+
 // NewPackResponse creates a new PackResponse with calculated totals
 func NewPackResponse(quantity int, breakdown map[int]int, packSizesUsed []int) *PackResponse {
 	response := &PackResponse{
@@ -53,11 +413,105 @@ func NewPackResponse(quantity int, breakdown map[int]int, packSizesUsed []int) *
 	return response
 }
 
-// NewErrorResponse creates a new ErrorResponse
-func NewErrorResponse(error, message string) ErrorResponse {
-	return ErrorResponse{
-		Error:   error,
-		Message: message,
+// NewPackResponseV2 returns the /api/v2 view of a response
+func NewPackResponseV2(r *PackResponse) *PackResponseV2 {
+	response := &PackResponseV2{
+		Quantity:       r.Quantity,
+		Packs:          NewPacks(r.PackBreakdown),
+		TotalItems:     r.TotalItems,
+		TotalPacks:     r.TotalPacks,
+		Overage:        r.TotalItems - r.Quantity,
+		PackSizesUsed:  r.PackSizesUsed,
+		PackSetVersion: r.PackSetVersion,
+		Algorithm:      r.Algorithm,
+		TotalCost:      r.TotalCost,
+		Policy:         r.Policy,
+		Explanation:    r.Explanation,
+		QuoteID:        r.QuoteID,
+	}
+	if r.Quantity > 0 {
+		// Rounded to two decimals
+		response.OveragePercent = math.Round(float64(response.Overage)*10_000/float64(r.Quantity)) / 100
+	}
+	for _, alternative := range r.Alternatives {
+		response.Alternatives = append(response.Alternatives, PackAlternativeV2{
+			Rank:       alternative.Rank,
+			Packs:      NewPacks(alternative.PackBreakdown),
+			TotalItems: alternative.TotalItems,
+			Overage:    alternative.ItemsOver,
+			TotalPacks: alternative.TotalPacks,
+		})
+	}
+	return response
+}
+
+// NewPacks lists the sizes of a breakdown with at least one pack, largest first
+func NewPacks(breakdown map[int]int) []Pack {
+	packs := make([]Pack, 0, len(breakdown))
+	for size, count := range breakdown {
+		if count > 0 {
+			packs = append(packs, Pack{PackSize: size, Count: count, Items: size * count})
+		}
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].PackSize > packs[j].PackSize })
+	return packs
+}
+
+// NewPackAlternative creates an alternative distribution with its totals
+func NewPackAlternative(rank, quantity int, breakdown map[int]int) PackAlternative {
+	alternative := PackAlternative{
+		Rank:          rank,
+		PackBreakdown: breakdown,
+	}
+	for size, count := range breakdown {
+		alternative.TotalItems += size * count
+		alternative.TotalPacks += count
+	}
+	alternative.ItemsOver = alternative.TotalItems - quantity
+	return alternative
+}
+
+// Validate validates the OrderLine on its own
+func (l *OrderLine) Validate() error {
+	if l.SKU == "" {
+		return NewFieldError("sku", "is required")
+	}
+	return l.PackRequest.Validate()
+}
+
+// AddLine appends a line result to the order and updates the totals
+func (r *OrderResponse) AddLine(line OrderLineResult) {
+	r.Lines = append(r.Lines, line)
+	r.Totals.Lines++
+
+	if line.Result == nil {
+		r.Totals.FailedLines++
+		return
+	}
+
+	r.Totals.TotalQuantity += line.Result.Quantity
+	r.Totals.TotalItems += line.Result.TotalItems
+	r.Totals.TotalPacks += line.Result.TotalPacks
+	r.Totals.TotalCost += line.Result.TotalCost
+}
+
+// AddResult appends a request result to the batch and updates the totals
+func (r *BatchResponse) AddResult(result BatchItemResult) {
+	r.Results = append(r.Results, result)
+	r.Totals.Requests++
+	if result.Result == nil {
+		r.Totals.Failed++
+	}
+}
+
+// NewProblemDetails creates problem details whose type is identified by code
+func NewProblemDetails(status int, code, title, detail string) ProblemDetails {
+	return ProblemDetails{
+		Type:   ProblemTypePrefix + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
 	}
 }
 
@@ -66,9 +520,30 @@ func NewValidationError(message string) error {
 	return &ValidationError{Message: message}
 }
 
-// ValidationError represents a validation error
+// NewFieldError creates a validation error for a single invalid field
+func NewFieldError(field, message string) error {
+	return NewFieldErrors([]FieldError{{Field: field, Message: message}})
+}
+
+// NewFieldErrors creates a validation error listing every invalid field
+// It returns nil when there are no invalid fields
+func NewFieldErrors(fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return &ValidationError{Message: strings.Join(messages, "; "), Fields: fields}
+}
+
+// ValidationError is returned when a request or configuration is invalid
+// Fields lists the invalid fields when they are known
 type ValidationError struct {
 	Message string
+	Fields  []FieldError
 }
 
 func (e *ValidationError) Error() string {
@@ -76,7 +551,42 @@ func (e *ValidationError) Error() string {
 }
 
 // IsValidationError checks if an error is a ValidationError
+// Wrapped errors are checked too
 func IsValidationError(err error) bool {
-	_, ok := err.(*ValidationError)
-	return ok
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// NewPackSizesDiff lists the pack sizes added and removed between two revisions
+func NewPackSizesDiff(from, to *PackSizesRevision) *PackSizesDiff {
+	fromSizes := make(map[int]bool, len(from.NewPackSizes))
+	for _, size := range from.NewPackSizes {
+		fromSizes[size] = true
+	}
+	toSizes := make(map[int]bool, len(to.NewPackSizes))
+	for _, size := range to.NewPackSizes {
+		toSizes[size] = true
+	}
+
+	diff := &PackSizesDiff{
+		From:          from.Version,
+		To:            to.Version,
+		FromPackSizes: from.NewPackSizes,
+		ToPackSizes:   to.NewPackSizes,
+		Added:         []int{},
+		Removed:       []int{},
+	}
+	for size := range toSizes {
+		if !fromSizes[size] {
+			diff.Added = append(diff.Added, size)
+		}
+	}
+	for size := range fromSizes {
+		if !toSizes[size] {
+			diff.Removed = append(diff.Removed, size)
+		}
+	}
+	sort.Ints(diff.Added)
+	sort.Ints(diff.Removed)
+	return diff
 }
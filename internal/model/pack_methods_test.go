@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestPackRequest_Validate(t *testing.T) {
@@ -117,13 +118,267 @@ func TestNewPackResponse(t *testing.T) {
 	}
 }
 
-func TestNewErrorResponse(t *testing.T) {
-	resp := NewErrorResponse("error", "message")
-	if resp.Error != "error" || resp.Message != "message" {
-		t.Errorf("NewErrorResponse() = %v, want error:error message:message", resp)
+func TestNewProblemDetails(t *testing.T) {
+	problem := NewProblemDetails(400, CodeValidationFailed, "Validation failed", "quantity must be greater than 0")
+	want := ProblemDetails{
+		Type:   "/problems/validation_failed",
+		Title:  "Validation failed",
+		Status: 400,
+		Detail: "quantity must be greater than 0",
+		Code:   CodeValidationFailed,
+	}
+	if !reflect.DeepEqual(problem, want) {
+		t.Errorf("NewProblemDetails() = %+v, want %+v", problem, want)
+	}
+}
+
+func TestPackRequest_Validate_FieldErrors(t *testing.T) {
+	request := &PackRequest{
+		Quantity:     0,
+		Alternatives: -1,
+		Stock:        map[int]int{500: -2, -1: 3},
+		PackCosts:    map[int]int{250: 10},
+	}
+
+	err := request.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want a validation error", err)
+	}
+
+	want := []FieldError{
+		{"quantity", "must be greater than 0"},
+		{"alternatives", "cannot be negative, got: -1"},
+		{"stock[-1]", "pack size must be positive"},
+		{"stock[500]", "cannot be negative, got: -2"},
+	}
+	if !reflect.DeepEqual(validationErr.Fields, want) {
+		t.Errorf("Fields = %+v, want %+v", validationErr.Fields, want)
+	}
+	if err.Error() != "quantity must be greater than 0; alternatives cannot be negative, got: -1; stock[-1] pack size must be positive; stock[500] cannot be negative, got: -2" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestValidatePackSizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []int
+		want  []FieldError
+	}{
+		{"Valid", []int{250, 500}, nil},
+		{"Empty", nil, []FieldError{{"pack_sizes", "cannot be empty"}}},
+		{"Every invalid size", []int{250, 0, 500, -3}, []FieldError{
+			{"pack_sizes[1]", "must be positive, got: 0"},
+			{"pack_sizes[3]", "must be positive, got: -3"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePackSizes(tt.sizes)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidatePackSizes() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("ValidatePackSizes() error = %#v, want fields %+v", err, tt.want)
+			}
+		})
 	}
 }
 
+func TestAnalysisRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request AnalysisRequest
+		want    []FieldError
+	}{
+		{"Empty", AnalysisRequest{}, nil},
+		{"Range", AnalysisRequest{PackSizes: []int{6, 9}, From: 10, To: 100}, nil},
+		{"Only end", AnalysisRequest{To: 1}, nil},
+		{"Every invalid field", AnalysisRequest{Profile: "bad name", PackSizes: []int{6, 0}, From: -1, To: -2}, []FieldError{
+			{"profile", profileNameMessage("bad name")},
+			{"pack_sizes[1]", "must be positive, got: 0"},
+			{"from", "cannot be negative, got: -1"},
+			{"to", "cannot be negative, got: -2"},
+		}},
+		{"End before start", AnalysisRequest{From: 10, To: 5}, []FieldError{{"to", "cannot be before from, got: 5"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("Validate() error = %#v, want fields %+v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparisonRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ComparisonRequest
+		want    []FieldError
+	}{
+		{"Quantities", ComparisonRequest{PackSizes: []int{300}, Quantities: []int{1, 251}}, nil},
+		{"Range", ComparisonRequest{PackSizes: []int{300}, From: 10, To: 100, Step: 10}, nil},
+		{"Only end", ComparisonRequest{PackSizes: []int{300}, To: 10_000}, nil},
+		{"Every invalid field", ComparisonRequest{Profile: "bad name", PackSizes: []int{0}, Quantities: []int{5, -1}, To: 10}, []FieldError{
+			{"profile", profileNameMessage("bad name")},
+			{"pack_sizes[0]", "must be positive, got: 0"},
+			{"quantities", "cannot be used together with from, to and step"},
+			{"quantities[1]", "must be positive, got: -1"},
+		}},
+		{"No pack sizes or quantities", ComparisonRequest{}, []FieldError{
+			{"pack_sizes", "cannot be empty"},
+			{"to", "is required without quantities"},
+		}},
+		{"End before start", ComparisonRequest{PackSizes: []int{300}, From: 10, To: 5}, []FieldError{{"to", "cannot be before from, got: 5"}}},
+		{"Too many quantities", ComparisonRequest{PackSizes: []int{300}, To: 10_001}, []FieldError{{"to", "gives 10001 quantities, more than 10000"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("Validate() error = %#v, want fields %+v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendationRequest_Validate(t *testing.T) {
+	orders := []OrderQuantity{{Quantity: 300, Count: 10}, {Quantity: 250}}
+
+	tests := []struct {
+		name    string
+		request RecommendationRequest
+		want    []FieldError
+	}{
+		{"Valid", RecommendationRequest{Orders: orders, MinSize: 100, MaxSize: 600, Step: 100, MaxSizes: 2}, nil},
+		{"Single candidate", RecommendationRequest{Orders: orders, MinSize: 250, MaxSize: 250, MaxSizes: 1, PackWeight: 1}, nil},
+		{"Every invalid field", RecommendationRequest{
+			Orders:  []OrderQuantity{{Quantity: 0}, {Quantity: 5, Count: -1}},
+			MinSize: 0, MaxSize: -1, Step: -1, MaxSizes: 11, OverageWeight: -1, PackWeight: -2, MaxEvaluations: -3,
+		}, []FieldError{
+			{"orders[0].quantity", "must be positive, got: 0"},
+			{"orders[1].count", "cannot be negative, got: -1"},
+			{"min_size", "must be positive, got: 0"},
+			{"max_size", "cannot be below min_size, got: -1"},
+			{"step", "cannot be negative, got: -1"},
+			{"max_sizes", "must be between 1 and 10, got: 11"},
+			{"overage_weight", "cannot be negative, got: -1"},
+			{"pack_weight", "cannot be negative, got: -2"},
+			{"max_evaluations", "must be between 0 and 20000, got: -3"},
+		}},
+		{"No orders", RecommendationRequest{MinSize: 1, MaxSize: 10, MaxSizes: 1}, []FieldError{{"orders", "cannot be empty"}}},
+		{"History", RecommendationRequest{History: &QuoteFilter{Profile: "default"}, MinSize: 1, MaxSize: 10, MaxSizes: 1}, nil},
+		{"History with orders", RecommendationRequest{Orders: orders, History: &QuoteFilter{}, MinSize: 1, MaxSize: 10, MaxSizes: 1}, []FieldError{
+			{"history", "cannot be combined with orders"},
+		}},
+		{"Invalid history", RecommendationRequest{History: &QuoteFilter{MinQuantity: -1}, MinSize: 1, MaxSize: 10, MaxSizes: 1}, []FieldError{
+			{"history.min_quantity", "cannot be negative, got: -1"},
+		}},
+		{"Too many candidates", RecommendationRequest{Orders: orders, MinSize: 1, MaxSize: 1000, Step: 2, MaxSizes: 3}, []FieldError{
+			{"max_size", "gives 500 candidate sizes, more than 200"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("Validate() error = %#v, want fields %+v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendationRequest_Candidates(t *testing.T) {
+	request := RecommendationRequest{MinSize: 100, MaxSize: 550, Step: 150}
+	if got, want := request.Candidates(), []int{100, 250, 400, 550}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates() = %v, want %v", got, want)
+	}
+
+	request = RecommendationRequest{MinSize: 5, MaxSize: 7}
+	if got, want := request.Candidates(), []int{5, 6, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates() = %v, want %v", got, want)
+	}
+}
+
+func TestQuoteFilter_Validate(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter QuoteFilter
+		want   []FieldError
+	}{
+		{"Empty", QuoteFilter{}, nil},
+		{"Every criterion", QuoteFilter{Profile: "eu", MinQuantity: 1, MaxQuantity: 1, Since: since, Until: since, PackSizesVersion: 1, Limit: MaxQuoteLimit, Offset: 20}, nil},
+		{"Only a maximum", QuoteFilter{MinQuantity: 0, MaxQuantity: 10}, nil},
+		{"Every invalid field", QuoteFilter{Profile: "bad name", MinQuantity: -1, MaxQuantity: -1, PackSizesVersion: -1, Limit: -1, Offset: -1}, []FieldError{
+			{"profile", profileNameMessage("bad name")},
+			{"min_quantity", "cannot be negative, got: -1"},
+			{"max_quantity", "cannot be negative, got: -1"},
+			{"pack_sizes_version", "cannot be negative, got: -1"},
+			{"limit", "must be between 1 and 100, got: -1"},
+			{"offset", "cannot be negative, got: -1"},
+		}},
+		{"Ranges reversed", QuoteFilter{MinQuantity: 10, MaxQuantity: 5, Since: since, Until: since.Add(-time.Second)}, []FieldError{
+			{"max_quantity", "cannot be below min_quantity, got: 5"},
+			{"until", "cannot be before since"},
+		}},
+		{"Page too large", QuoteFilter{Limit: MaxQuoteLimit + 1}, []FieldError{{"limit", "must be between 1 and 100, got: 101"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Fields, tt.want) {
+				t.Errorf("Validate() error = %#v, want fields %+v", err, tt.want)
+			}
+		})
+	}
+}
 func TestNewValidationError(t *testing.T) {
 	msg := "validation failed"
 	err := NewValidationError(msg)
@@ -158,3 +413,233 @@ func TestIsValidationError(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateStock(t *testing.T) {
+	tests := []struct {
+		name    string
+		stock   map[int]int
+		wantErr bool
+	}{
+		{"Nil", nil, false},
+		{"Valid", map[int]int{250: 1, 500: 0}, false},
+		{"Negative count", map[int]int{250: -1}, true},
+		{"Invalid size", map[int]int{0: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStock(tt.stock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !IsValidationError(err) {
+				t.Errorf("ValidateStock() error is not a ValidationError: %v", err)
+			}
+		})
+	}
+}
+
+func TestPackRequest_Validate_Objective(t *testing.T) {
+	tests := []struct {
+		name      string
+		objective string
+		costs     map[int]int
+		wantErr   bool
+	}{
+		{"Default objective", "", nil, false},
+		{"Packs objective", ObjectivePacks, nil, false},
+		{"Cost objective", ObjectiveCost, map[int]int{250: 10}, false},
+		{"Unknown objective", "speed", nil, true},
+		{"Negative cost", ObjectiveCost, map[int]int{250: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &PackRequest{Quantity: 100, Objective: tt.objective, PackCosts: tt.costs}
+			err := req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPackResponse_CalculateCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		breakdown map[int]int
+		costs     map[int]int
+		want      int
+	}{
+		{"No costs", map[int]int{250: 2}, nil, 0},
+		{"Known costs", map[int]int{250: 2, 500: 1}, map[int]int{250: 10, 500: 15}, 35},
+		{"Missing cost is free", map[int]int{250: 2, 500: 1}, map[int]int{500: 15}, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &PackResponse{PackBreakdown: tt.breakdown}
+			resp.CalculateCost(tt.costs)
+			if resp.TotalCost != tt.want {
+				t.Errorf("CalculateCost() = %d, want %d", resp.TotalCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackRequest_Validate_Policy(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     PackRequest
+		wantErr bool
+	}{
+		{"Named policy", PackRequest{Quantity: 100, Policy: "fewest-packs"}, false},
+		{"Overage limit", PackRequest{Quantity: 100, MaxOveragePercent: 12.5}, false},
+		{"Negative overage limit", PackRequest{Quantity: 100, MaxOveragePercent: -1}, true},
+		{"Objective with policy", PackRequest{Quantity: 100, Objective: ObjectiveCost, Policy: "cost"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderResponse_AddLine(t *testing.T) {
+	resp := &OrderResponse{}
+	resp.AddLine(OrderLineResult{SKU: "a", Result: NewPackResponse(251, map[int]int{500: 1}, []int{250, 500})})
+	resp.AddLine(OrderLineResult{SKU: "b", Error: "quantity must be greater than 0"})
+
+	want := OrderTotals{Lines: 2, FailedLines: 1, TotalQuantity: 251, TotalItems: 500, TotalPacks: 1}
+	if resp.Totals != want {
+		t.Errorf("Totals = %+v, want %+v", resp.Totals, want)
+	}
+}
+
+func TestBatchResponse_AddResult(t *testing.T) {
+	resp := &BatchResponse{}
+	resp.AddResult(BatchItemResult{Index: 0, Result: NewPackResponse(251, map[int]int{500: 1}, []int{250, 500})})
+	resp.AddResult(BatchItemResult{Index: 1, Error: "quantity must be greater than 0", Code: CodeValidationFailed})
+
+	want := BatchTotals{Requests: 2, Failed: 1}
+	if resp.Totals != want {
+		t.Errorf("Totals = %+v, want %+v", resp.Totals, want)
+	}
+}
+
+func TestOrderLine_Validate(t *testing.T) {
+	if err := (&OrderLine{PackRequest: PackRequest{Quantity: 1}}).Validate(); err == nil {
+		t.Error("Expected error for missing sku")
+	}
+	if err := (&OrderLine{SKU: "a", PackRequest: PackRequest{Quantity: 0}}).Validate(); err == nil {
+		t.Error("Expected error for invalid quantity")
+	}
+	if err := (&OrderLine{SKU: "a", PackRequest: PackRequest{Quantity: 1}}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestPackProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile PackProfile
+		wantErr bool
+	}{
+		{"Valid", PackProfile{Name: "bolts", PackSizes: []int{50, 100}}, false},
+		{"Valid with stock and costs", PackProfile{Name: "bolts-2.x", PackSizes: []int{50}, Stock: map[int]int{50: 3}, PackCosts: map[int]int{50: 2}}, false},
+		{"Missing name", PackProfile{PackSizes: []int{50}}, true},
+		{"Name with spaces", PackProfile{Name: "my bolts", PackSizes: []int{50}}, true},
+		{"Name starting with dash", PackProfile{Name: "-bolts", PackSizes: []int{50}}, true},
+		{"No pack sizes", PackProfile{Name: "bolts"}, true},
+		{"Negative pack size", PackProfile{Name: "bolts", PackSizes: []int{50, -1}}, true},
+		{"Negative stock", PackProfile{Name: "bolts", PackSizes: []int{50}, Stock: map[int]int{50: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPackProfile_Copy(t *testing.T) {
+	profile := &PackProfile{Name: "bolts", PackSizes: []int{100, 50}, Stock: map[int]int{50: 1}}
+	copied := profile.Copy()
+	copied.PackSizes[0] = 1
+	copied.Stock[50] = 9
+
+	if profile.PackSizes[0] != 100 || profile.Stock[50] != 1 {
+		t.Errorf("Copy() shares data with the original: %+v", profile)
+	}
+	if copied.PackSizes[1] != 100 {
+		t.Errorf("Copy() sizes = %v, want sorted", copied.PackSizes)
+	}
+}
+
+func TestNewPackSizesDiff(t *testing.T) {
+	from := &PackSizesRevision{Version: 1, NewPackSizes: []int{250, 500, 1000}}
+	to := &PackSizesRevision{Version: 4, NewPackSizes: []int{250, 750, 2000}}
+
+	diff := NewPackSizesDiff(from, to)
+	if diff.From != 1 || diff.To != 4 {
+		t.Errorf("NewPackSizesDiff() versions = %d..%d, want 1..4", diff.From, diff.To)
+	}
+	if !reflect.DeepEqual(diff.Added, []int{750, 2000}) || !reflect.DeepEqual(diff.Removed, []int{500, 1000}) {
+		t.Errorf("NewPackSizesDiff() = added %v removed %v", diff.Added, diff.Removed)
+	}
+
+	same := NewPackSizesDiff(from, from)
+	if len(same.Added) != 0 || len(same.Removed) != 0 {
+		t.Errorf("NewPackSizesDiff() of a revision with itself = %+v", same)
+	}
+}
+
+func TestNewPackAlternative(t *testing.T) {
+	alternative := NewPackAlternative(2, 501, map[int]int{250: 3})
+	want := PackAlternative{Rank: 2, PackBreakdown: map[int]int{250: 3}, TotalItems: 750, ItemsOver: 249, TotalPacks: 3}
+	if !reflect.DeepEqual(alternative, want) {
+		t.Errorf("NewPackAlternative() = %+v, want %+v", alternative, want)
+	}
+}
+
+func TestNewPackResponseV2(t *testing.T) {
+	version := 3
+	response := NewPackResponse(501, map[int]int{250: 1, 500: 1, 1000: 0}, []int{250, 500, 1000})
+	response.Policy = "default"
+	response.PackSetVersion = &version
+	response.Algorithm = "dynamic"
+	response.QuoteID = "abc123"
+	response.Alternatives = []PackAlternative{NewPackAlternative(1, 501, map[int]int{250: 3})}
+
+	want := &PackResponseV2{
+		Quantity:       501,
+		Packs:          []Pack{{PackSize: 500, Count: 1, Items: 500}, {PackSize: 250, Count: 1, Items: 250}},
+		TotalItems:     750,
+		TotalPacks:     2,
+		Overage:        249,
+		OveragePercent: 49.7,
+		PackSizesUsed:  []int{250, 500, 1000},
+		PackSetVersion: &version,
+		Algorithm:      "dynamic",
+		Policy:         "default",
+		Alternatives: []PackAlternativeV2{
+			{Rank: 1, Packs: []Pack{{PackSize: 250, Count: 3, Items: 750}}, TotalItems: 750, Overage: 249, TotalPacks: 3},
+		},
+		QuoteID: "abc123",
+	}
+	if got := NewPackResponseV2(response); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewPackResponseV2() = %+v, want %+v", got, want)
+	}
+
+	exact := NewPackResponseV2(NewPackResponse(500, map[int]int{500: 1}, []int{500}))
+	if exact.Overage != 0 || exact.OveragePercent != 0 || exact.PackSetVersion != nil {
+		t.Errorf("NewPackResponseV2() of an exact fit = %+v", exact)
+	}
+}
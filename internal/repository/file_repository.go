@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// Storage backends accepted by NewPackRepository
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+// DefaultStorePath is where the file store keeps its data when no path is configured
+const DefaultStorePath = "data/pack-store.json"
+
+// schemaVersion is the layout written by FilePackRepository
+const schemaVersion = 1
+
+// migrations[v-1] upgrades a document from schema version v to v+1
+// Version 1 is the first layout, so there is nothing to migrate yet
+var migrations = []func(doc map[string]json.RawMessage) error{}
+
+// storeDocument is the on-disk layout of the current schema version
+type storeDocument struct {
+	Version            int                       `json:"version"`
+	PackSizesVersion   int                       `json:"pack_sizes_version,omitempty"`
+	PackSizesRevisions []model.PackSizesRevision `json:"pack_sizes_revisions,omitempty"`
+	Profiles           []model.PackProfile       `json:"profiles"`
+}
+
+// FilePackRepository implements PackRepository on top of the in-memory repository
+// and saves every change to a JSON file, so the configuration survives restarts
+// A change that cannot be saved is rolled back, so memory and file never disagree
+type FilePackRepository struct {
+	*InMemoryPackRepository
+	path string
+}
+
+// NewPackRepository creates the repository for the given store
+// An empty store means memory; path is only used by the file store
+func NewPackRepository(store, path string) (PackRepository, error) {
+	switch store {
+	case "", StoreMemory:
+		return NewInMemoryPackRepository(), nil
+	case StoreFile:
+		return NewFilePackRepository(path)
+	default:
+		return nil, fmt.Errorf("unknown pack store: %s", store)
+	}
+}
+
+// NewFilePackRepository opens the store at path, migrating it to the current schema version
+// A missing file starts with an empty default profile
+func NewFilePackRepository(path string) (*FilePackRepository, error) {
+	if path == "" {
+		path = DefaultStorePath
+	}
+
+	r := &FilePackRepository{
+		InMemoryPackRepository: NewInMemoryPackRepository(),
+		path:                   path,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.persist = r.save
+	return r, nil
+}
+
+// Path returns the file backing the repository
+func (r *FilePackRepository) Path() string {
+	return r.path
+}
+
+// load reads the store file, upgrading it to the current schema version
+func (r *FilePackRepository) load() error {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pack store: %w", err)
+	}
+
+	doc, err := migrate(data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate pack store %s: %w", r.path, err)
+	}
+
+	profiles := make(map[string]*model.PackProfile, len(doc.Profiles))
+	for i := range doc.Profiles {
+		profile := doc.Profiles[i].Copy()
+		profiles[profile.Name] = profile
+	}
+	if _, ok := profiles[model.DefaultProfile]; !ok {
+		return fmt.Errorf("pack store %s has no %s profile", r.path, model.DefaultProfile)
+	}
+	r.profiles = profiles
+	r.version = doc.PackSizesVersion
+	r.revisions = doc.PackSizesRevisions
+	return nil
+}
+
+// save writes the store atomically; it runs with the repository lock held
+func (r *FilePackRepository) save() error {
+	doc := storeDocument{
+		Version:            schemaVersion,
+		PackSizesVersion:   r.version,
+		PackSizesRevisions: r.revisions,
+		Profiles:           r.listProfiles(),
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pack store: %w", err)
+	}
+
+	if err := writeFileAtomic(r.path, data); err != nil {
+		return fmt.Errorf("failed to write pack store: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path,
+// so a crash never leaves a half-written file behind
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// migrate decodes a store document of any known version into the current layout
+func migrate(data []byte) (*storeDocument, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var version int
+	if err := json.Unmarshal(doc["version"], &version); err != nil {
+		return nil, fmt.Errorf("invalid version: %w", err)
+	}
+	if version < 1 {
+		return nil, fmt.Errorf("unknown schema version %d", version)
+	}
+	if version > schemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than supported version %d", version, schemaVersion)
+	}
+
+	for ; version < schemaVersion; version++ {
+		if err := migrations[version-1](doc); err != nil {
+			return nil, fmt.Errorf("migration from version %d: %w", version, err)
+		}
+	}
+	doc["version"] = json.RawMessage(fmt.Sprint(schemaVersion))
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var result storeDocument
+	if err := json.Unmarshal(upgraded, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+func newTestFileRepository(t *testing.T) (*FilePackRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store", "packs.json")
+	repo, err := NewFilePackRepository(path)
+	if err != nil {
+		t.Fatalf("NewFilePackRepository() error = %v", err)
+	}
+	return repo, path
+}
+
+func TestNewFilePackRepository(t *testing.T) {
+	repo, path := newTestFileRepository(t)
+
+	if repo.Path() != path {
+		t.Errorf("Path() = %s, want %s", repo.Path(), path)
+	}
+	sizes, err := repo.GetAllPackSizes()
+	if err != nil {
+		t.Fatalf("GetAllPackSizes() error = %v", err)
+	}
+	if len(sizes) != 0 {
+		t.Errorf("Expected empty packSizes, got %v", sizes)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no file before the first write, got %v", err)
+	}
+}
+
+func TestFilePackRepository_SetPackSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []int
+		expected []int
+	}{
+		{"Single", []int{250}, []int{250}},
+		{"Multiple", []int{250, 500, 1000}, []int{250, 500, 1000}},
+		{"Unsorted", []int{5000, 250, 1000}, []int{250, 1000, 5000}},
+		{"Duplicates", []int{250, 500, 250}, []int{250, 250, 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, path := newTestFileRepository(t)
+			if err := repo.SetPackSizes(tt.input); err != nil {
+				t.Fatalf("SetPackSizes() error = %v", err)
+			}
+
+			reopened, err := NewFilePackRepository(path)
+			if err != nil {
+				t.Fatalf("NewFilePackRepository() error = %v", err)
+			}
+			sizes, _ := reopened.GetAllPackSizes()
+			if !reflect.DeepEqual(sizes, tt.expected) {
+				t.Errorf("After reopening, got %v, want %v", sizes, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilePackRepository_SurvivesRestart(t *testing.T) {
+	repo, path := newTestFileRepository(t)
+
+	repo.SetPackSizes([]int{250, 500, 1000})
+	repo.SetStock(map[int]int{1000: 2})
+	repo.SetPackCosts(map[int]int{250: 40})
+	if err := repo.CreateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{50, 100}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	if err := repo.CreateProfile(&model.PackProfile{Name: "nuts", PackSizes: []int{10}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	if err := repo.UpdateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{20, 40}}); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if err := repo.DeleteProfile("nuts"); err != nil {
+		t.Fatalf("DeleteProfile() error = %v", err)
+	}
+
+	reopened, err := NewFilePackRepository(path)
+	if err != nil {
+		t.Fatalf("NewFilePackRepository() error = %v", err)
+	}
+
+	want, _ := repo.ListProfiles()
+	got, _ := reopened.ListProfiles()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("After reopening, profiles = %+v, want %+v", got, want)
+	}
+	if _, err := reopened.GetProfile("nuts"); !errors.Is(err, model.ErrProfileNotFound) {
+		t.Errorf("GetProfile() deleted profile error = %v, want %v", err, model.ErrProfileNotFound)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if len(matches) != 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+}
+
+func TestFilePackRepository_FailedWriteKeepsState(t *testing.T) {
+	repo, path := newTestFileRepository(t)
+	repo.SetPackSizes([]int{250, 500})
+
+	// A directory in place of the store file makes the rename fail
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "keep"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SetPackSizes([]int{1000}); err == nil {
+		t.Fatal("SetPackSizes() expected an error when the store cannot be written")
+	}
+	sizes, _ := repo.GetAllPackSizes()
+	if !reflect.DeepEqual(sizes, []int{250, 500}) {
+		t.Errorf("After failed write, got %v, want [250 500]", sizes)
+	}
+}
+
+func TestFilePackRepository_SchemaVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantSizes []int
+		wantStock map[int]int
+		wantErr   string
+	}{
+		{
+			name:      "Current version",
+			content:   `{"version": 1, "profiles": [{"name": "default", "pack_sizes": [23, 31, 53]}]}`,
+			wantSizes: []int{23, 31, 53},
+			wantStock: map[int]int{},
+		},
+		{
+			name:    "Missing version",
+			content: `{"profiles": [{"name": "default", "pack_sizes": [250]}]}`,
+			wantErr: "invalid version",
+		},
+		{
+			name:    "Newer version",
+			content: `{"version": 99, "profiles": []}`,
+			wantErr: "newer than supported",
+		},
+		{
+			name:    "Missing default profile",
+			content: `{"version": 1, "profiles": [{"name": "bolts", "pack_sizes": [50]}]}`,
+			wantErr: "no default profile",
+		},
+		{
+			name:    "Invalid JSON",
+			content: `{"pack_sizes": [`,
+			wantErr: "failed to migrate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "packs.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			repo, err := NewFilePackRepository(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewFilePackRepository() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFilePackRepository() error = %v", err)
+			}
+
+			sizes, _ := repo.GetAllPackSizes()
+			stock, _ := repo.GetStock()
+			if !reflect.DeepEqual(sizes, tt.wantSizes) || !reflect.DeepEqual(stock, tt.wantStock) {
+				t.Errorf("After loading, sizes = %v stock = %v, want %v %v", sizes, stock, tt.wantSizes, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestNewPackRepository(t *testing.T) {
+	tests := []struct {
+		store   string
+		want    string
+		wantErr bool
+	}{
+		{"", "*repository.InMemoryPackRepository", false},
+		{StoreMemory, "*repository.InMemoryPackRepository", false},
+		{StoreFile, "*repository.FilePackRepository", false},
+		{"sqlite", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			repo, err := NewPackRepository(tt.store, filepath.Join(t.TempDir(), "packs.json"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPackRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := reflect.TypeOf(repo).String(); got != tt.want {
+				t.Errorf("NewPackRepository(%q) = %s, want %s", tt.store, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilePackRepository_KeepsVersion(t *testing.T) {
+	repo, path := newTestFileRepository(t)
+	repo.SetPackSizes([]int{250})
+	repo.SetPackSizes([]int{250, 500})
+
+	reopened, err := NewFilePackRepository(path)
+	if err != nil {
+		t.Fatalf("NewFilePackRepository() error = %v", err)
+	}
+	snapshot, _ := reopened.GetPackSizesSnapshot()
+	if snapshot.Version != 2 {
+		t.Errorf("Version after reopening = %d, want 2", snapshot.Version)
+	}
+	want, _ := repo.ListPackSizesRevisions()
+	got, _ := reopened.ListPackSizesRevisions()
+	if len(got) != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("Revisions after reopening = %+v, want %+v", got, want)
+	}
+	if _, err := reopened.CompareAndSetPackSizes([]int{1000}, 1, model.PackSizesChange{}); !errors.Is(err, model.ErrVersionConflict) {
+		t.Errorf("CompareAndSetPackSizes() stale error = %v, want %v", err, model.ErrVersionConflict)
+	}
+}
+
+// TestFilePackRepository_ConcurrentWrites is meant to run with -race
+func TestFilePackRepository_ConcurrentWrites(t *testing.T) {
+	repo, path := newTestFileRepository(t)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				repo.SetPackSizes([]int{w + 1, i + 1})
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				repo.GetPackSizesSnapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	reopened, err := NewFilePackRepository(path)
+	if err != nil {
+		t.Fatalf("NewFilePackRepository() error = %v", err)
+	}
+	want, _ := repo.GetPackSizesSnapshot()
+	got, _ := reopened.GetPackSizesSnapshot()
+	if !reflect.DeepEqual(got, want) || got.Version != 80 {
+		t.Errorf("After reopening = %+v, want %+v at version 80", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// DefaultIdempotencyStorePath is where the file store keeps idempotent responses when no
+// path is configured
+const DefaultIdempotencyStorePath = "data/idempotency.jsonl"
+
+// compactSlack is how many lines of dropped entries, such as expired responses or quotes
+// beyond the retention, a file store holds beyond the kept ones before it is rewritten
+const compactSlack = 1_000
+
+// FileIdempotencyRepository implements IdempotencyRepository on top of the in-memory
+// repository and appends every response to a file of JSON lines, so retries are still
+// recognized after a restart
+// The file is rewritten without the expired responses when it opens and once they
+// outnumber the live ones
+type FileIdempotencyRepository struct {
+	*InMemoryIdempotencyRepository
+	lines jsonLines
+	// count is the number of lines in the file
+	count int
+}
+
+// NewIdempotencyRepository creates the idempotency repository for the given store, keeping
+// the latest maxResponses responses (0 keeps every response until it expires)
+// An empty store means memory; path is only used by the file store
+func NewIdempotencyRepository(store, path string, maxResponses int) (IdempotencyRepository, error) {
+	switch store {
+	case "", StoreMemory:
+		repo := NewInMemoryIdempotencyRepository()
+		repo.maxResponses = maxResponses
+		return repo, nil
+	case StoreFile:
+		return NewFileIdempotencyRepository(path, maxResponses)
+	default:
+		return nil, fmt.Errorf("unknown idempotency store: %s", store)
+	}
+}
+
+// NewFileIdempotencyRepository opens the responses at path, keeping the latest maxResponses
+// responses (0 keeps every response until it expires)
+// A missing file starts without responses; a last line cut short by a crash is dropped
+func NewFileIdempotencyRepository(path string, maxResponses int) (*FileIdempotencyRepository, error) {
+	if path == "" {
+		path = DefaultIdempotencyStorePath
+	}
+
+	r := &FileIdempotencyRepository{
+		InMemoryIdempotencyRepository: NewInMemoryIdempotencyRepository(),
+		lines:                         jsonLines{path: path},
+	}
+	r.maxResponses = maxResponses
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.persist = r.appendResponse
+	return r, nil
+}
+
+// Path returns the file backing the repository
+func (r *FileIdempotencyRepository) Path() string {
+	return r.lines.path
+}
+
+// Close closes the file responses are appended to
+func (r *FileIdempotencyRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lines.close()
+}
+
+// load reads the unexpired responses of the file and compacts it
+func (r *FileIdempotencyRepository) load() error {
+	now := time.Now()
+	err := r.lines.read(func(line int, data []byte) error {
+		r.count++
+		var response model.IdempotentResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if !response.Expired(now) {
+			r.add(&response)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read idempotency store %s: %w", r.lines.path, err)
+	}
+	r.prune(now)
+
+	if r.count > len(r.responses) {
+		return r.compact(now)
+	}
+	return nil
+}
+
+// appendResponse writes one response line at the end of the file, compacting the file
+// first when it mostly holds expired responses
+// It runs with the repository lock held
+func (r *FileIdempotencyRepository) appendResponse(response *model.IdempotentResponse) error {
+	if r.count > 2*len(r.responses)+compactSlack {
+		if err := r.compact(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	if err := r.lines.append(data); err != nil {
+		return fmt.Errorf("failed to write idempotency store: %w", err)
+	}
+	r.count++
+	return nil
+}
+
+// compact rewrites the file with the unexpired responses only
+// The caller must hold the lock
+func (r *FileIdempotencyRepository) compact(now time.Time) error {
+	live := r.live(now)
+	lines := make([][]byte, len(live))
+	for i, response := range live {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return fmt.Errorf("failed to encode response: %w", err)
+		}
+		lines[i] = data
+	}
+
+	if err := r.lines.replace(lines); err != nil {
+		return fmt.Errorf("failed to compact idempotency store: %w", err)
+	}
+	r.count = len(lines)
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestIdempotencyRepository(t *testing.T) (*FileIdempotencyRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store", "idempotency.jsonl")
+	repo, err := NewFileIdempotencyRepository(path, DefaultMaxIdempotentResponses)
+	if err != nil {
+		t.Fatalf("NewFileIdempotencyRepository() error = %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo, path
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestFileIdempotencyRepository_SurvivesRestart(t *testing.T) {
+	repo, path := newTestIdempotencyRepository(t)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no file before the first response, got %v", err)
+	}
+
+	live := testResponse("order-1", `{"quantity":251}`, time.Hour)
+	if err := repo.SaveResponse(live); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	if err := repo.SaveResponse(testResponse("order-2", `{"quantity":252}`, -time.Second)); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	repo.Close()
+
+	reopened, err := NewFileIdempotencyRepository(path, DefaultMaxIdempotentResponses)
+	if err != nil {
+		t.Fatalf("NewFileIdempotencyRepository() after restart error = %v", err)
+	}
+	defer reopened.Close()
+
+	if got, ok, err := reopened.GetResponse("order-1"); err != nil || !ok || !reflect.DeepEqual(got, live) {
+		t.Errorf("GetResponse() after restart = %+v, %v, %v, want %+v", got, ok, err, live)
+	}
+	if _, ok, _ := reopened.GetResponse("order-2"); ok {
+		t.Error("GetResponse() of an expired response after restart expected none")
+	}
+	// Opening dropped the expired response from the file
+	if lines := countLines(t, path); lines != 1 {
+		t.Errorf("Expected 1 line after opening, got %d", lines)
+	}
+}
+
+func TestFileIdempotencyRepository_Compacts(t *testing.T) {
+	repo, path := newTestIdempotencyRepository(t)
+	for i := 0; i <= compactSlack; i++ {
+		if err := repo.SaveResponse(testResponse(fmt.Sprintf("old-%d", i), "{}", -time.Second)); err != nil {
+			t.Fatalf("SaveResponse() error = %v", err)
+		}
+	}
+	if lines := countLines(t, path); lines != compactSlack+1 {
+		t.Fatalf("Expected %d lines, got %d", compactSlack+1, lines)
+	}
+
+	if err := repo.SaveResponse(testResponse("new", "{}", time.Hour)); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	if lines := countLines(t, path); lines != 1 {
+		t.Errorf("Expected 1 line after compacting, got %d", lines)
+	}
+	if _, ok, _ := repo.GetResponse("new"); !ok {
+		t.Error("GetResponse() after compacting expected the new response")
+	}
+}
+
+func TestFileIdempotencyRepository_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency.jsonl")
+	if err := os.WriteFile(path, []byte("not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileIdempotencyRepository(path, DefaultMaxIdempotentResponses); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("NewFileIdempotencyRepository() error = %v, want an error on line 1", err)
+	}
+}
+
+func TestNewIdempotencyRepository(t *testing.T) {
+	if repo, err := NewIdempotencyRepository("", "", 0); err != nil {
+		t.Errorf("NewIdempotencyRepository() error = %v", err)
+	} else if memory, ok := repo.(*InMemoryIdempotencyRepository); !ok || memory.maxResponses != 0 {
+		t.Errorf("NewIdempotencyRepository() = %T, want *InMemoryIdempotencyRepository without a limit", repo)
+	}
+
+	path := filepath.Join(t.TempDir(), "idempotency.jsonl")
+	if repo, err := NewIdempotencyRepository(StoreFile, path, 5); err != nil {
+		t.Errorf("NewIdempotencyRepository() error = %v", err)
+	} else if file, ok := repo.(*FileIdempotencyRepository); !ok || file.Path() != path || file.maxResponses != 5 {
+		t.Errorf("NewIdempotencyRepository() = %T, want *FileIdempotencyRepository at %s keeping 5 responses", repo, path)
+	}
+
+	if _, err := NewIdempotencyRepository("redis", "", DefaultMaxIdempotentResponses); err == nil {
+		t.Error("NewIdempotencyRepository() with an unknown store expected error, got nil")
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// IdempotencyRepository defines the interface for storing the responses replayed to
+// requests sent with an Idempotency-Key
+// Expired responses are never returned; implementations must be safe for concurrent use
+type IdempotencyRepository interface {
+	// GetResponse returns the response stored for key; ok is false when there is none
+	GetResponse(key string) (response *model.IdempotentResponse, ok bool, err error)
+	// SaveResponse stores response under its key, unless a response is stored there already
+	SaveResponse(response *model.IdempotentResponse) error
+}
+
+// DefaultMaxIdempotentResponses is how many responses a repository keeps by default; the
+// oldest go first, even before they expire
+const DefaultMaxIdempotentResponses = 10_000
+
+// storedResponse is a response with the order it was saved in
+type storedResponse struct {
+	response model.IdempotentResponse
+	seq      int
+}
+
+// responseRef points at the response saved under key in position seq
+type responseRef struct {
+	key string
+	seq int
+}
+
+// InMemoryIdempotencyRepository implements IdempotencyRepository using in-memory storage
+type InMemoryIdempotencyRepository struct {
+	mu        sync.Mutex
+	responses map[string]storedResponse
+	// order lists the saved responses, oldest first; entries replaced since are skipped
+	order []responseRef
+	seq   int
+	// maxResponses bounds len(responses); 0 keeps every response until it expires
+	maxResponses int
+
+	// persist is called with the lock held before a response is added
+	// When it fails the response is not added
+	persist func(response *model.IdempotentResponse) error
+}
+
+// NewInMemoryIdempotencyRepository creates an empty idempotency repository
+// It keeps the latest DefaultMaxIdempotentResponses responses until they expire
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		responses:    make(map[string]storedResponse),
+		maxResponses: DefaultMaxIdempotentResponses,
+	}
+}
+
+// GetResponse returns the response stored for key; ok is false when there is none
+func (r *InMemoryIdempotencyRepository) GetResponse(key string) (*model.IdempotentResponse, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.responses[key]
+	if !ok || stored.response.Expired(time.Now()) {
+		return nil, false, nil
+	}
+	return copyResponse(&stored.response), true, nil
+}
+
+// SaveResponse stores response under its key, unless a response is stored there already
+func (r *InMemoryIdempotencyRepository) SaveResponse(response *model.IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.prune(now)
+	if stored, ok := r.responses[response.Key]; ok && !stored.response.Expired(now) {
+		return nil
+	}
+	if r.persist != nil {
+		if err := r.persist(response); err != nil {
+			return err
+		}
+	}
+	r.add(copyResponse(response))
+	r.prune(now)
+	return nil
+}
+
+// add stores response; the caller must hold the lock
+func (r *InMemoryIdempotencyRepository) add(response *model.IdempotentResponse) {
+	r.seq++
+	r.responses[response.Key] = storedResponse{response: *response, seq: r.seq}
+	r.order = append(r.order, responseRef{key: response.Key, seq: r.seq})
+}
+
+// prune drops the oldest responses while they expired or exceed the limit
+// Responses expire in the order they were saved unless the lifetime changed, so a
+// response expiring early may stay until the ones before it go; GetResponse skips it
+// The caller must hold the lock
+func (r *InMemoryIdempotencyRepository) prune(now time.Time) {
+	for len(r.order) > 0 {
+		ref := r.order[0]
+		stored, ok := r.responses[ref.key]
+		current := ok && stored.seq == ref.seq
+		if current && !stored.response.Expired(now) && (r.maxResponses == 0 || len(r.responses) <= r.maxResponses) {
+			return
+		}
+		if current {
+			delete(r.responses, ref.key)
+		}
+		r.order[0] = responseRef{}
+		r.order = r.order[1:]
+	}
+}
+
+// live returns the unexpired responses, oldest first; the caller must hold the lock
+func (r *InMemoryIdempotencyRepository) live(now time.Time) []*model.IdempotentResponse {
+	responses := make([]*model.IdempotentResponse, 0, len(r.responses))
+	for _, ref := range r.order {
+		if stored, ok := r.responses[ref.key]; ok && stored.seq == ref.seq && !stored.response.Expired(now) {
+			response := stored.response
+			responses = append(responses, &response)
+		}
+	}
+	return responses
+}
+
+// copyResponse returns a copy of response sharing nothing with it
+func copyResponse(response *model.IdempotentResponse) *model.IdempotentResponse {
+	clone := *response
+	clone.Body = bytes.Clone(response.Body)
+	if response.Header != nil {
+		clone.Header = make(map[string][]string, len(response.Header))
+		for name, values := range response.Header {
+			clone.Header[name] = slices.Clone(values)
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// testResponse returns a response stored under key that expires after ttl
+func testResponse(key string, body string, ttl time.Duration) *model.IdempotentResponse {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &model.IdempotentResponse{
+		Key:         key,
+		Fingerprint: "fp-" + body,
+		Status:      200,
+		Header:      map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:        []byte(body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+func TestInMemoryIdempotencyRepository(t *testing.T) {
+	repo := NewInMemoryIdempotencyRepository()
+	first := testResponse("order-1", `{"quantity":251}`, time.Hour)
+
+	if _, ok, err := repo.GetResponse("order-1"); ok || err != nil {
+		t.Fatalf("GetResponse() before saving = %v, %v, want none", ok, err)
+	}
+	if err := repo.SaveResponse(first); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	// The first response is kept
+	if err := repo.SaveResponse(testResponse("order-1", `{"quantity":252}`, time.Hour)); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+
+	got, ok, err := repo.GetResponse("order-1")
+	if err != nil || !ok || !reflect.DeepEqual(got, first) {
+		t.Fatalf("GetResponse() = %+v, %v, %v, want %+v", got, ok, err, first)
+	}
+
+	// The stored response is not shared with callers
+	got.Body[0] = 'x'
+	got.Header["Content-Type"][0] = "text/plain"
+	if again, _, _ := repo.GetResponse("order-1"); !reflect.DeepEqual(again, first) {
+		t.Errorf("GetResponse() after changing a returned response = %+v, want %+v", again, first)
+	}
+}
+
+func TestInMemoryIdempotencyRepository_Expiry(t *testing.T) {
+	repo := NewInMemoryIdempotencyRepository()
+	if err := repo.SaveResponse(testResponse("order-1", `{"quantity":251}`, -time.Second)); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	if _, ok, _ := repo.GetResponse("order-1"); ok {
+		t.Error("GetResponse() of an expired response expected none")
+	}
+
+	// An expired key can be used again
+	replacement := testResponse("order-1", `{"quantity":252}`, time.Hour)
+	if err := repo.SaveResponse(replacement); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	if got, ok, _ := repo.GetResponse("order-1"); !ok || !reflect.DeepEqual(got, replacement) {
+		t.Errorf("GetResponse() = %+v, want %+v", got, replacement)
+	}
+	if len(repo.responses) != 1 || len(repo.order) != 1 {
+		t.Errorf("Expected 1 response kept, got %d in %d entries", len(repo.responses), len(repo.order))
+	}
+}
+
+func TestInMemoryIdempotencyRepository_DropsOldest(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxResponses int
+		wantKept     []string
+		wantDropped  []string
+	}{
+		{
+			name:         "default limit",
+			maxResponses: DefaultMaxIdempotentResponses,
+			wantKept:     []string{"key-1", "key-2", "key-3"},
+		},
+		{
+			name:         "oldest beyond the limit",
+			maxResponses: 2,
+			wantKept:     []string{"key-2", "key-3"},
+			wantDropped:  []string{"key-1"},
+		},
+		{
+			name:         "no limit",
+			maxResponses: 0,
+			wantKept:     []string{"key-1", "key-2", "key-3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewInMemoryIdempotencyRepository()
+			repo.maxResponses = tt.maxResponses
+			for i := 1; i <= 3; i++ {
+				if err := repo.SaveResponse(testResponse(fmt.Sprintf("key-%d", i), "{}", time.Hour)); err != nil {
+					t.Fatalf("SaveResponse() error = %v", err)
+				}
+			}
+
+			for _, key := range tt.wantKept {
+				if _, ok, _ := repo.GetResponse(key); !ok {
+					t.Errorf("GetResponse(%s) expected a response", key)
+				}
+			}
+			for _, key := range tt.wantDropped {
+				if _, ok, _ := repo.GetResponse(key); ok {
+					t.Errorf("GetResponse(%s) expected none", key)
+				}
+			}
+			if len(repo.responses) != len(tt.wantKept) {
+				t.Errorf("Expected %d responses kept, got %d", len(tt.wantKept), len(repo.responses))
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// jsonLines is a file of JSON documents, one per line, written by appending to its end
+// It is not safe for concurrent use; the repository using it holds its own lock
+type jsonLines struct {
+	path string
+	// file is opened on the first append; size is the length of the complete lines
+	file *os.File
+	size int64
+}
+
+// read calls fn with every complete line of the file, numbered from 1
+// A missing file has no lines. A last line without its newline was cut short while being
+// appended, so it is skipped and overwritten by the next append
+func (l *jsonLines) read(fn func(line int, data []byte) error) error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		l.size += int64(len(data))
+		if err := fn(line, bytes.TrimSuffix(data, []byte("\n"))); err != nil {
+			return err
+		}
+	}
+}
+
+// append writes data as one line at the end of the file
+// A failed write is truncated away, so the next line starts on a line of its own
+func (l *jsonLines) append(data []byte) error {
+	if l.file == nil {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		// Drops a line cut short before the file was read
+		if err := file.Truncate(l.size); err != nil {
+			file.Close()
+			return err
+		}
+		l.file = file
+	}
+
+	line := append(data[:len(data):len(data)], '\n')
+	if _, err := l.file.WriteAt(line, l.size); err != nil {
+		_ = l.file.Truncate(l.size)
+		return err
+	}
+	l.size += int64(len(line))
+	return nil
+}
+
+// replace atomically replaces the file with lines
+func (l *jsonLines) replace(lines [][]byte) error {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := writeFileAtomic(l.path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	// The open file was renamed over, so the next append reopens the new one
+	if err := l.close(); err != nil {
+		return err
+	}
+	l.size = int64(buf.Len())
+	return nil
+}
+
+// close closes the file; the next append opens it again
+func (l *jsonLines) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -1,37 +1,133 @@
 package repository
 
 import (
+	"fmt"
+	"slices"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/marcellribeiro/awesomeProject/internal/model"
 )
 
 // PackRepository defines the interface for pack size storage operations
+// The pack size, stock and cost methods work on the default profile
+// Implementations must be safe for concurrent use
 type PackRepository interface {
 	GetAllPackSizes() ([]int, error)
 	SetPackSizes(sizes []int) error
+	GetPackSizesSnapshot() (model.PackSizesSnapshot, error)
+	UpdatePackSizes(sizes []int, change model.PackSizesChange) (model.PackSizesSnapshot, error)
+	CompareAndSetPackSizes(sizes []int, version int, change model.PackSizesChange) (model.PackSizesSnapshot, error)
+	ListPackSizesRevisions() ([]model.PackSizesRevision, error)
+	GetPackSizesRevision(version int) (*model.PackSizesRevision, error)
+	GetStock() (map[int]int, error)
+	SetStock(stock map[int]int) error
+	GetPackCosts() (map[int]int, error)
+	SetPackCosts(costs map[int]int) error
+
+	ListProfiles() ([]model.PackProfile, error)
+	GetProfile(name string) (*model.PackProfile, error)
+	CreateProfile(profile *model.PackProfile) error
+	UpdateProfile(profile *model.PackProfile) error
+	DeleteProfile(name string) error
 }
 
 // InMemoryPackRepository implements PackRepository using in-memory storage
 type InMemoryPackRepository struct {
-	packSizes []int
+	mu       sync.RWMutex
+	profiles map[string]*model.PackProfile
+	// version counts the changes to the default pack sizes
+	version int
+	// revisions holds one entry per version, oldest first
+	revisions []model.PackSizesRevision
+
+	// persist is called with the lock held after every change
+	// When it fails the change is rolled back
+	persist func() error
 }
 
 // NewInMemoryPackRepository creates a new in-memory pack repository with empty sizes
 // Users must configure pack sizes before calculating
 func NewInMemoryPackRepository() *InMemoryPackRepository {
 	return &InMemoryPackRepository{
-		packSizes: []int{}, // Start empty - users must configure
+		profiles: map[string]*model.PackProfile{
+			model.DefaultProfile: {
+				Name:      model.DefaultProfile,
+				PackSizes: []int{},       // Start empty - users must configure
+				Stock:     map[int]int{}, // Empty stock means unlimited packs
+				PackCosts: map[int]int{}, // Empty costs means every pack is free
+			},
+		},
+	}
+}
+
+// defaultProfile returns the profile behind the global pack size configuration
+func (r *InMemoryPackRepository) defaultProfile() *model.PackProfile {
+	return r.profiles[model.DefaultProfile]
+}
+
+// commit applies change and persists the result; the caller must hold the write lock
+func (r *InMemoryPackRepository) commit(change func() error) error {
+	if r.persist == nil {
+		return change()
+	}
+
+	profiles := make(map[string]*model.PackProfile, len(r.profiles))
+	for name, profile := range r.profiles {
+		profiles[name] = profile.Copy()
+	}
+	version, revisions := r.version, len(r.revisions)
+
+	if err := change(); err != nil {
+		return err
+	}
+	if err := r.persist(); err != nil {
+		r.profiles, r.version, r.revisions = profiles, version, r.revisions[:revisions]
+		return err
+	}
+	return nil
+}
+
+// setPackSizes stores a sorted copy of sizes and records the change as a new revision
+// The caller must hold the write lock
+func (r *InMemoryPackRepository) setPackSizes(sizes []int, change model.PackSizesChange) {
+	packSizes := make([]int, len(sizes))
+	copy(packSizes, sizes)
+	sort.Ints(packSizes)
+
+	r.recordRevision(r.defaultProfile().PackSizes, packSizes, change)
+	r.defaultProfile().PackSizes = packSizes
+}
+
+// recordRevision bumps the version and appends the matching revision
+// The caller must hold the write lock
+func (r *InMemoryPackRepository) recordRevision(oldSizes, newSizes []int, change model.PackSizesChange) {
+	r.version++
+	r.revisions = append(r.revisions, model.PackSizesRevision{
+		Version:         r.version,
+		CreatedAt:       time.Now().UTC(),
+		OldPackSizes:    slices.Clone(oldSizes),
+		NewPackSizes:    slices.Clone(newSizes),
+		PackSizesChange: change,
+	})
+}
+
+// snapshot returns the current pack sizes and version; the caller must hold the lock
+func (r *InMemoryPackRepository) snapshot() model.PackSizesSnapshot {
+	return model.PackSizesSnapshot{
+		PackSizes: r.defaultProfile().Copy().PackSizes,
+		Version:   r.version,
 	}
 }
 
 // GetAllPackSizes returns all configured pack sizes
 func (r *InMemoryPackRepository) GetAllPackSizes() ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	// Return a copy to prevent external modification
-	sizes := make([]int, len(r.packSizes))
-	copy(sizes, r.packSizes)
-	sort.Ints(sizes)
-	return sizes, nil
+	return r.defaultProfile().Copy().PackSizes, nil
 }
 
 // SetPackSizes updates the pack sizes configuration
@@ -40,25 +136,215 @@ func (r *InMemoryPackRepository) SetPackSizes(sizes []int) error {
 		return nil
 	}
 
-	// Validate all sizes are positive
-	for _, size := range sizes {
-		if size <= 0 {
-			continue
+	_, err := r.UpdatePackSizes(sizes, model.PackSizesChange{})
+	return err
+}
+
+// GetPackSizesSnapshot returns the pack sizes together with their version
+func (r *InMemoryPackRepository) GetPackSizesSnapshot() (model.PackSizesSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.snapshot(), nil
+}
+
+// UpdatePackSizes replaces the pack sizes and records who changed them and why
+func (r *InMemoryPackRepository) UpdatePackSizes(sizes []int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.commit(func() error {
+		r.setPackSizes(sizes, change)
+		return nil
+	})
+	if err != nil {
+		return model.PackSizesSnapshot{}, err
+	}
+	return r.snapshot(), nil
+}
+
+// CompareAndSetPackSizes replaces the pack sizes only if they are still at version
+// It returns model.ErrVersionConflict when another change got there first
+func (r *InMemoryPackRepository) CompareAndSetPackSizes(sizes []int, version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.version != version {
+		return model.PackSizesSnapshot{}, fmt.Errorf("%w: expected version %d, current version %d",
+			model.ErrVersionConflict, version, r.version)
+	}
+
+	err := r.commit(func() error {
+		r.setPackSizes(sizes, change)
+		return nil
+	})
+	if err != nil {
+		return model.PackSizesSnapshot{}, err
+	}
+	return r.snapshot(), nil
+}
+
+// ListPackSizesRevisions returns every pack sizes revision, oldest first
+func (r *InMemoryPackRepository) ListPackSizesRevisions() ([]model.PackSizesRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revisions := make([]model.PackSizesRevision, len(r.revisions))
+	for i, revision := range r.revisions {
+		revisions[i] = copyRevision(revision)
+	}
+	return revisions, nil
+}
+
+// GetPackSizesRevision returns the revision that produced the given version
+func (r *InMemoryPackRepository) GetPackSizesRevision(version int) (*model.PackSizesRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, revision := range r.revisions {
+		if revision.Version == version {
+			revision = copyRevision(revision)
+			return &revision, nil
 		}
 	}
+	return nil, fmt.Errorf("%w: %d", model.ErrRevisionNotFound, version)
+}
 
-	// Create a copy and sort
-	r.packSizes = make([]int, len(sizes))
-	copy(r.packSizes, sizes)
-	sort.Ints(r.packSizes)
+func copyRevision(revision model.PackSizesRevision) model.PackSizesRevision {
+	revision.OldPackSizes = slices.Clone(revision.OldPackSizes)
+	revision.NewPackSizes = slices.Clone(revision.NewPackSizes)
+	return revision
+}
 
-	return nil
+// GetStock returns the number of packs available per pack size
+// Pack sizes without an entry are not limited
+func (r *InMemoryPackRepository) GetStock() (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Return a copy to prevent external modification
+	return model.CopyCounts(r.defaultProfile().Stock), nil
+}
+
+// SetStock replaces the available packs per pack size
+func (r *InMemoryPackRepository) SetStock(stock map[int]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.commit(func() error {
+		r.defaultProfile().Stock = model.CopyCounts(stock)
+		return nil
+	})
+}
+
+// GetPackCosts returns the cost of one pack per pack size
+func (r *InMemoryPackRepository) GetPackCosts() (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return model.CopyCounts(r.defaultProfile().PackCosts), nil
+}
+
+// SetPackCosts replaces the cost of one pack per pack size
+func (r *InMemoryPackRepository) SetPackCosts(costs map[int]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.commit(func() error {
+		r.defaultProfile().PackCosts = model.CopyCounts(costs)
+		return nil
+	})
+}
+
+// ListProfiles returns all profiles sorted by name
+func (r *InMemoryPackRepository) ListProfiles() ([]model.PackProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listProfiles(), nil
+}
+
+func (r *InMemoryPackRepository) listProfiles() []model.PackProfile {
+	profiles := make([]model.PackProfile, 0, len(r.profiles))
+	for _, profile := range r.profiles {
+		profiles = append(profiles, *profile.Copy())
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// GetProfile returns the profile with the given name
+func (r *InMemoryPackRepository) GetProfile(name string) (*model.PackProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profile, ok := r.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrProfileNotFound, name)
+	}
+	return profile.Copy(), nil
+}
+
+// CreateProfile stores a new profile
+func (r *InMemoryPackRepository) CreateProfile(profile *model.PackProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[profile.Name]; ok {
+		return fmt.Errorf("%w: %s", model.ErrProfileExists, profile.Name)
+	}
+	return r.commit(func() error {
+		r.profiles[profile.Name] = profile.Copy()
+		return nil
+	})
+}
+
+// UpdateProfile replaces an existing profile
+func (r *InMemoryPackRepository) UpdateProfile(profile *model.PackProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[profile.Name]; !ok {
+		return fmt.Errorf("%w: %s", model.ErrProfileNotFound, profile.Name)
+	}
+	return r.commit(func() error {
+		updated := profile.Copy()
+		if profile.Name == model.DefaultProfile && !slices.Equal(updated.PackSizes, r.defaultProfile().PackSizes) {
+			r.recordRevision(r.defaultProfile().PackSizes, updated.PackSizes, model.PackSizesChange{Reason: "default profile updated"})
+		}
+		r.profiles[profile.Name] = updated
+		return nil
+	})
+}
+
+// DeleteProfile removes a profile
+// The default profile backs the global configuration and cannot be deleted
+func (r *InMemoryPackRepository) DeleteProfile(name string) error {
+	if name == model.DefaultProfile {
+		return model.NewValidationError("the default profile cannot be deleted")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", model.ErrProfileNotFound, name)
+	}
+	return r.commit(func() error {
+		delete(r.profiles, name)
+		return nil
+	})
 }
 
 // GetDefaultPackSizes returns the default pack sizes
 func (r *InMemoryPackRepository) GetDefaultPackSizes() []model.PackSize {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	sizes := []model.PackSize{}
-	for i, size := range r.packSizes {
+	for i, size := range r.defaultProfile().PackSizes {
 		sizes = append(sizes, model.PackSize{
 			ID:   i + 1,
 			Size: size,
@@ -1,18 +1,30 @@
 package repository
 
 import (
+	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
 )
 
+// newRepositoryWithSizes stores sizes as-is in the default profile, bypassing SetPackSizes
+func newRepositoryWithSizes(sizes []int) *InMemoryPackRepository {
+	repo := NewInMemoryPackRepository()
+	repo.defaultProfile().PackSizes = sizes
+	return repo
+}
+
 func TestNewInMemoryPackRepository(t *testing.T) {
 	repo := NewInMemoryPackRepository()
 
 	if repo == nil {
 		t.Fatal("Expected repository to be created, got nil")
 	}
-	if len(repo.packSizes) != 0 {
-		t.Errorf("Expected empty packSizes, got length %d", len(repo.packSizes))
+	if len(repo.defaultProfile().PackSizes) != 0 {
+		t.Errorf("Expected empty packSizes, got length %d", len(repo.defaultProfile().PackSizes))
 	}
 }
 
@@ -31,7 +43,7 @@ func TestInMemoryPackRepository_GetAllPackSizes(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := &InMemoryPackRepository{packSizes: tt.initial}
+			repo := newRepositoryWithSizes(tt.initial)
 			sizes, err := repo.GetAllPackSizes()
 
 			if err != nil {
@@ -87,7 +99,7 @@ func TestInMemoryPackRepository_GetDefaultPackSizes(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := &InMemoryPackRepository{packSizes: tt.sizes}
+			repo := newRepositoryWithSizes(tt.sizes)
 			result := repo.GetDefaultPackSizes()
 
 			if len(result) != tt.count {
@@ -105,3 +117,264 @@ func TestInMemoryPackRepository_GetDefaultPackSizes(t *testing.T) {
 		})
 	}
 }
+
+func TestInMemoryPackRepository_Stock(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+
+	stock, err := repo.GetStock()
+	if err != nil {
+		t.Fatalf("GetStock() error = %v", err)
+	}
+	if len(stock) != 0 {
+		t.Errorf("Expected empty stock, got %v", stock)
+	}
+
+	input := map[int]int{250: 10, 5000: 0}
+	if err := repo.SetStock(input); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	// Changing the input must not change the stored stock
+	input[250] = 1
+
+	stock, _ = repo.GetStock()
+	if !reflect.DeepEqual(stock, map[int]int{250: 10, 5000: 0}) {
+		t.Errorf("After SetStock(), got %v", stock)
+	}
+
+	// Changing the returned stock must not change the stored stock
+	stock[5000] = 3
+	stock, _ = repo.GetStock()
+	if stock[5000] != 0 {
+		t.Errorf("GetStock() returned shared map, got %v", stock)
+	}
+}
+
+func TestInMemoryPackRepository_PackCosts(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+
+	costs, err := repo.GetPackCosts()
+	if err != nil {
+		t.Fatalf("GetPackCosts() error = %v", err)
+	}
+	if len(costs) != 0 {
+		t.Errorf("Expected empty costs, got %v", costs)
+	}
+
+	input := map[int]int{250: 40, 500: 70}
+	if err := repo.SetPackCosts(input); err != nil {
+		t.Fatalf("SetPackCosts() error = %v", err)
+	}
+	input[250] = 1
+
+	costs, _ = repo.GetPackCosts()
+	if !reflect.DeepEqual(costs, map[int]int{250: 40, 500: 70}) {
+		t.Errorf("After SetPackCosts(), got %v", costs)
+	}
+}
+
+func TestInMemoryPackRepository_Profiles(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500})
+
+	profiles, err := repo.ListProfiles()
+	if err != nil {
+		t.Fatalf("ListProfiles() error = %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != model.DefaultProfile {
+		t.Fatalf("ListProfiles() = %+v, want only the default profile", profiles)
+	}
+	if !reflect.DeepEqual(profiles[0].PackSizes, []int{250, 500}) {
+		t.Errorf("Default profile sizes = %v, want global sizes [250 500]", profiles[0].PackSizes)
+	}
+
+	bolts := &model.PackProfile{Name: "bolts", PackSizes: []int{100, 50}, Stock: map[int]int{100: 3}}
+	if err := repo.CreateProfile(bolts); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	if err := repo.CreateProfile(bolts); !errors.Is(err, model.ErrProfileExists) {
+		t.Errorf("CreateProfile() duplicate error = %v, want %v", err, model.ErrProfileExists)
+	}
+
+	// Changing the input must not change the stored profile
+	bolts.PackSizes[0] = 1
+
+	got, err := repo.GetProfile("bolts")
+	if err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.PackSizes, []int{50, 100}) || got.Stock[100] != 3 {
+		t.Errorf("GetProfile() = %+v", got)
+	}
+
+	got.PackSizes = []int{10, 20}
+	if err := repo.UpdateProfile(got); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	got, _ = repo.GetProfile("bolts")
+	if !reflect.DeepEqual(got.PackSizes, []int{10, 20}) {
+		t.Errorf("After UpdateProfile(), sizes = %v", got.PackSizes)
+	}
+
+	if err := repo.UpdateProfile(&model.PackProfile{Name: "nuts"}); !errors.Is(err, model.ErrProfileNotFound) {
+		t.Errorf("UpdateProfile() missing error = %v, want %v", err, model.ErrProfileNotFound)
+	}
+
+	if err := repo.DeleteProfile(model.DefaultProfile); err == nil {
+		t.Error("DeleteProfile() allowed deleting the default profile")
+	}
+	if err := repo.DeleteProfile("bolts"); err != nil {
+		t.Fatalf("DeleteProfile() error = %v", err)
+	}
+	if _, err := repo.GetProfile("bolts"); !errors.Is(err, model.ErrProfileNotFound) {
+		t.Errorf("GetProfile() after delete error = %v, want %v", err, model.ErrProfileNotFound)
+	}
+	if err := repo.DeleteProfile("bolts"); !errors.Is(err, model.ErrProfileNotFound) {
+		t.Errorf("DeleteProfile() missing error = %v, want %v", err, model.ErrProfileNotFound)
+	}
+}
+
+func TestInMemoryPackRepository_CompareAndSetPackSizes(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+
+	snapshot, _ := repo.GetPackSizesSnapshot()
+	if snapshot.Version != 0 {
+		t.Fatalf("Initial version = %d, want 0", snapshot.Version)
+	}
+
+	updated, err := repo.CompareAndSetPackSizes([]int{500, 250}, snapshot.Version, model.PackSizesChange{})
+	if err != nil {
+		t.Fatalf("CompareAndSetPackSizes() error = %v", err)
+	}
+	if updated.Version != 1 || !reflect.DeepEqual(updated.PackSizes, []int{250, 500}) {
+		t.Errorf("CompareAndSetPackSizes() = %+v, want version 1 with [250 500]", updated)
+	}
+
+	// A second writer still holding version 0 loses
+	if _, err := repo.CompareAndSetPackSizes([]int{1000}, snapshot.Version, model.PackSizesChange{}); !errors.Is(err, model.ErrVersionConflict) {
+		t.Errorf("CompareAndSetPackSizes() stale error = %v, want %v", err, model.ErrVersionConflict)
+	}
+
+	// Unconditional writes and default profile edits move the version too
+	repo.SetPackSizes([]int{1000})
+	repo.SetStock(map[int]int{1000: 1})
+	repo.UpdateProfile(&model.PackProfile{Name: model.DefaultProfile, PackSizes: []int{1000}, Stock: map[int]int{1000: 2}})
+	if snapshot, _ = repo.GetPackSizesSnapshot(); snapshot.Version != 2 {
+		t.Errorf("Version after pack size changes = %d, want 2", snapshot.Version)
+	}
+	repo.UpdateProfile(&model.PackProfile{Name: model.DefaultProfile, PackSizes: []int{1000, 2000}})
+	if snapshot, _ = repo.GetPackSizesSnapshot(); snapshot.Version != 3 {
+		t.Errorf("Version after default profile update = %d, want 3", snapshot.Version)
+	}
+}
+
+// TestInMemoryPackRepository_ConcurrentAccess is meant to run with -race
+func TestInMemoryPackRepository_ConcurrentAccess(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500})
+
+	const workers = 8
+	const iterations = 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				repo.SetPackSizes([]int{250 * (w + 1), 500 * (i + 1)})
+				repo.SetStock(map[int]int{250: i})
+				repo.UpdateProfile(&model.PackProfile{Name: model.DefaultProfile, PackSizes: []int{w + 1}})
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if sizes, _ := repo.GetAllPackSizes(); len(sizes) == 0 {
+					t.Error("GetAllPackSizes() returned no sizes")
+					return
+				}
+				repo.GetStock()
+				repo.ListProfiles()
+				repo.GetDefaultPackSizes()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestInMemoryPackRepository_ConcurrentCompareAndSet checks that exactly one
+// of several writers holding the same version wins
+func TestInMemoryPackRepository_ConcurrentCompareAndSet(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+	snapshot, _ := repo.GetPackSizesSnapshot()
+
+	const writers = 16
+	var wg sync.WaitGroup
+	var wins, conflicts atomic.Int32
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			_, err := repo.CompareAndSetPackSizes([]int{w + 1}, snapshot.Version, model.PackSizesChange{})
+			switch {
+			case err == nil:
+				wins.Add(1)
+			case errors.Is(err, model.ErrVersionConflict):
+				conflicts.Add(1)
+			default:
+				t.Errorf("CompareAndSetPackSizes() error = %v", err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if wins.Load() != 1 || conflicts.Load() != writers-1 {
+		t.Errorf("wins = %d, conflicts = %d, want 1 and %d", wins.Load(), conflicts.Load(), writers-1)
+	}
+	if snapshot, _ = repo.GetPackSizesSnapshot(); snapshot.Version != 1 {
+		t.Errorf("Version = %d, want 1", snapshot.Version)
+	}
+}
+
+func TestInMemoryPackRepository_PackSizesRevisions(t *testing.T) {
+	repo := NewInMemoryPackRepository()
+
+	if revisions, _ := repo.ListPackSizesRevisions(); len(revisions) != 0 {
+		t.Fatalf("Expected no revisions, got %+v", revisions)
+	}
+
+	repo.SetPackSizes([]int{500, 250})
+	repo.SetStock(map[int]int{250: 1}) // stock changes are not pack size revisions
+	repo.UpdatePackSizes([]int{1000}, model.PackSizesChange{Author: "alice", Reason: "new boxes"})
+
+	revisions, err := repo.ListPackSizesRevisions()
+	if err != nil {
+		t.Fatalf("ListPackSizesRevisions() error = %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %+v", revisions)
+	}
+
+	second := revisions[1]
+	if second.Version != 2 || second.Author != "alice" || second.Reason != "new boxes" || second.CreatedAt.IsZero() {
+		t.Errorf("Revision 2 = %+v", second)
+	}
+	if !reflect.DeepEqual(second.OldPackSizes, []int{250, 500}) || !reflect.DeepEqual(second.NewPackSizes, []int{1000}) {
+		t.Errorf("Revision 2 sizes = %v -> %v, want [250 500] -> [1000]", second.OldPackSizes, second.NewPackSizes)
+	}
+
+	// Changing a returned revision must not change the history
+	revisions[0].NewPackSizes[0] = 1
+	revision, err := repo.GetPackSizesRevision(1)
+	if err != nil {
+		t.Fatalf("GetPackSizesRevision() error = %v", err)
+	}
+	if !reflect.DeepEqual(revision.NewPackSizes, []int{250, 500}) {
+		t.Errorf("GetPackSizesRevision(1) sizes = %v, want [250 500]", revision.NewPackSizes)
+	}
+
+	if _, err := repo.GetPackSizesRevision(3); !errors.Is(err, model.ErrRevisionNotFound) {
+		t.Errorf("GetPackSizesRevision(3) error = %v, want %v", err, model.ErrRevisionNotFound)
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// DefaultQuoteStorePath is where the file store keeps quotes when no path is configured
+const DefaultQuoteStorePath = "data/quotes.jsonl"
+
+// FileQuoteRepository implements QuoteRepository on top of the in-memory repository
+// and appends every quote to a file of JSON lines, so quotes survive restarts
+// Like the memory store it keeps the latest quotes only; the file is rewritten without
+// the dropped ones when it opens and once they outnumber the kept ones. Appends are not
+// synced one by one, so a machine crash may lose the latest quotes, but never corrupts
+// the older ones
+type FileQuoteRepository struct {
+	*InMemoryQuoteRepository
+	// writeMu serializes writes to the file, so lookups and listings never wait for the disk
+	writeMu sync.Mutex
+	lines   jsonLines
+	// count is the number of lines in the file
+	count int
+}
+
+// NewQuoteRepository creates the quote repository for the given store, keeping the
+// latest maxQuotes quotes (0 keeps every quote)
+// An empty store means memory; path is only used by the file store
+func NewQuoteRepository(store, path string, maxQuotes int) (QuoteRepository, error) {
+	switch store {
+	case "", StoreMemory:
+		repo := NewInMemoryQuoteRepository()
+		repo.maxQuotes = maxQuotes
+		return repo, nil
+	case StoreFile:
+		return NewFileQuoteRepository(path, maxQuotes)
+	default:
+		return nil, fmt.Errorf("unknown quote store: %s", store)
+	}
+}
+
+// NewFileQuoteRepository opens the quotes at path, keeping the latest maxQuotes quotes
+// (0 keeps every quote)
+// A missing file starts without quotes; a last line cut short by a crash is dropped
+func NewFileQuoteRepository(path string, maxQuotes int) (*FileQuoteRepository, error) {
+	if path == "" {
+		path = DefaultQuoteStorePath
+	}
+
+	r := &FileQuoteRepository{
+		InMemoryQuoteRepository: NewInMemoryQuoteRepository(),
+		lines:                   jsonLines{path: path},
+	}
+	r.maxQuotes = maxQuotes
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the file backing the repository
+func (r *FileQuoteRepository) Path() string {
+	return r.lines.path
+}
+
+// Close closes the file quotes are appended to
+func (r *FileQuoteRepository) Close() error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	return r.lines.close()
+}
+
+// SaveQuote appends quote to the file, then stores it in memory; its ID must be unique
+// The file is compacted first when it mostly holds dropped quotes
+func (r *FileQuoteRepository) SaveQuote(quote *model.Quote) error {
+	data, err := json.Marshal(quote)
+	if err != nil {
+		return fmt.Errorf("failed to encode quote: %w", err)
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	// Quotes are only added while writeMu is held, so the ID stays free until then
+	r.mu.RLock()
+	_, exists := r.index[quote.ID]
+	kept := len(r.quotes)
+	r.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("quote %s already exists", quote.ID)
+	}
+
+	if r.count > 2*kept+compactSlack {
+		if err := r.compact(); err != nil {
+			return err
+		}
+	}
+	if err := r.lines.append(data); err != nil {
+		return fmt.Errorf("failed to write quote store: %w", err)
+	}
+	r.count++
+
+	r.mu.Lock()
+	r.add(storedQuote{header: quoteHeader(quote), data: data})
+	r.mu.Unlock()
+	return nil
+}
+
+// load reads the quotes of the file, keeping the latest ones, and compacts it
+func (r *FileQuoteRepository) load() error {
+	err := r.lines.read(func(line int, data []byte) error {
+		r.count++
+		quote, err := decodeQuote(data)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, ok := r.index[quote.ID]; ok {
+			return fmt.Errorf("line %d: duplicate quote %s", line, quote.ID)
+		}
+		r.add(storedQuote{header: quoteHeader(quote), data: data})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read quote store %s: %w", r.lines.path, err)
+	}
+
+	if r.count > len(r.quotes) {
+		return r.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with the kept quotes only
+// The caller must hold writeMu
+func (r *FileQuoteRepository) compact() error {
+	r.mu.RLock()
+	lines := make([][]byte, len(r.quotes))
+	for i, quote := range r.quotes {
+		lines[i] = quote.data
+	}
+	r.mu.RUnlock()
+
+	if err := r.lines.replace(lines); err != nil {
+		return fmt.Errorf("failed to compact quote store: %w", err)
+	}
+	r.count = len(lines)
+	return nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+func newTestQuoteRepository(t *testing.T) (*FileQuoteRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store", "quotes.jsonl")
+	repo, err := NewFileQuoteRepository(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() error = %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo, path
+}
+
+func TestFileQuoteRepository_SurvivesRestart(t *testing.T) {
+	repo, path := newTestQuoteRepository(t)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no file before the first quote, got %v", err)
+	}
+
+	version := 1
+	quotes := []*model.Quote{
+		testQuote("q1", 251, 0, model.DefaultProfile, &version),
+		testQuote("q2", 501, 1, "eu", nil),
+	}
+	for _, quote := range quotes {
+		if err := repo.SaveQuote(quote); err != nil {
+			t.Fatalf("SaveQuote() error = %v", err)
+		}
+	}
+	repo.Close()
+
+	reopened, err := NewFileQuoteRepository(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() after restart error = %v", err)
+	}
+	defer reopened.Close()
+
+	for _, quote := range quotes {
+		got, err := reopened.GetQuote(quote.ID)
+		if err != nil || !reflect.DeepEqual(got, quote) {
+			t.Errorf("GetQuote(%s) after restart = %+v, %v, want %+v", quote.ID, got, err, quote)
+		}
+	}
+
+	// Appending after a restart keeps the earlier quotes
+	if err := reopened.SaveQuote(testQuote("q3", 1, 2, model.DefaultProfile, nil)); err != nil {
+		t.Fatalf("SaveQuote() after restart error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("Expected 3 quote lines, got %d: %s", lines, data)
+	}
+}
+
+func TestFileQuoteRepository_CutShortLine(t *testing.T) {
+	repo, path := newTestQuoteRepository(t)
+	if err := repo.SaveQuote(testQuote("q1", 251, 0, model.DefaultProfile, nil)); err != nil {
+		t.Fatalf("SaveQuote() error = %v", err)
+	}
+	repo.Close()
+
+	// A crash while appending leaves a line without its newline
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"q2","created_`)
+	file.Close()
+
+	reopened, err := NewFileQuoteRepository(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() error = %v", err)
+	}
+	defer reopened.Close()
+
+	if err := reopened.SaveQuote(testQuote("q3", 1, 2, model.DefaultProfile, nil)); err != nil {
+		t.Fatalf("SaveQuote() error = %v", err)
+	}
+	reopened.Close()
+
+	again, err := NewFileQuoteRepository(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() after the cut short line error = %v", err)
+	}
+	defer again.Close()
+	got, total, _ := again.ListQuotes(model.QuoteFilter{})
+	if ids := quoteIDs(got); !reflect.DeepEqual(ids, []string{"q3", "q1"}) || total != 2 {
+		t.Errorf("ListQuotes() = %v of %d, want [q3 q1] of 2", ids, total)
+	}
+}
+
+func TestFileQuoteRepository_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.jsonl")
+	repo, err := NewFileQuoteRepository(path, 2)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	// The file is compacted once it holds more than compactSlack lines beyond twice the kept quotes
+	saved := 2*2 + compactSlack + 2
+	for i := 0; i < saved; i++ {
+		if err := repo.SaveQuote(testQuote(fmt.Sprintf("q%d", i), i+1, 0, model.DefaultProfile, nil)); err != nil {
+			t.Fatalf("SaveQuote() error = %v", err)
+		}
+	}
+	if lines := countLines(t, path); lines != 3 {
+		t.Errorf("Expected 3 lines after compacting, got %d", lines)
+	}
+	if _, err := repo.GetQuote("q0"); !errors.Is(err, model.ErrQuoteNotFound) {
+		t.Errorf("GetQuote() of a dropped quote error = %v, want %v", err, model.ErrQuoteNotFound)
+	}
+	repo.Close()
+
+	// Opening keeps the latest quotes and drops the others from the file
+	reopened, err := NewFileQuoteRepository(path, 2)
+	if err != nil {
+		t.Fatalf("NewFileQuoteRepository() after restart error = %v", err)
+	}
+	defer reopened.Close()
+	if lines := countLines(t, path); lines != 2 {
+		t.Errorf("Expected 2 lines after opening, got %d", lines)
+	}
+	quotes, total, err := reopened.ListQuotes(model.QuoteFilter{})
+	if err != nil || total != 2 || quotes[0].ID != fmt.Sprintf("q%d", saved-1) || quotes[1].ID != fmt.Sprintf("q%d", saved-2) {
+		t.Errorf("ListQuotes() after restart = %d quotes, %v, want the latest 2", total, err)
+	}
+}
+
+func TestFileQuoteRepository_ConcurrentSaves(t *testing.T) {
+	repo, path := newTestQuoteRepository(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := repo.SaveQuote(testQuote(fmt.Sprintf("q%d", i), i+1, 0, model.DefaultProfile, nil)); err != nil {
+				t.Errorf("SaveQuote() error = %v", err)
+			}
+			if _, _, err := repo.ListQuotes(model.QuoteFilter{}); err != nil {
+				t.Errorf("ListQuotes() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if lines := countLines(t, path); lines != 50 {
+		t.Errorf("Expected 50 lines, got %d", lines)
+	}
+	if err := repo.SaveQuote(testQuote("q7", 1, 0, model.DefaultProfile, nil)); err == nil {
+		t.Error("SaveQuote() of a saved ID expected an error")
+	}
+}
+
+func TestFileQuoteRepository_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"Broken line", "not json\n", "line 1"},
+		{"Duplicate quote", `{"id":"q1"}` + "\n" + `{"id":"q1"}` + "\n", "duplicate quote q1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "quotes.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewFileQuoteRepository(path, 0); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewFileQuoteRepository() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewQuoteRepository(t *testing.T) {
+	if repo, err := NewQuoteRepository("", "", DefaultMaxQuotes); err != nil {
+		t.Errorf("NewQuoteRepository() error = %v", err)
+	} else if _, ok := repo.(*InMemoryQuoteRepository); !ok {
+		t.Errorf("NewQuoteRepository() = %T, want *InMemoryQuoteRepository", repo)
+	}
+
+	path := filepath.Join(t.TempDir(), "quotes.jsonl")
+	if repo, err := NewQuoteRepository(StoreFile, path, DefaultMaxQuotes); err != nil {
+		t.Errorf("NewQuoteRepository() error = %v", err)
+	} else if file, ok := repo.(*FileQuoteRepository); !ok || file.Path() != path {
+		t.Errorf("NewQuoteRepository() = %T, want *FileQuoteRepository at %s", repo, path)
+	}
+
+	if _, err := NewQuoteRepository("redis", "", DefaultMaxQuotes); err == nil {
+		t.Error("NewQuoteRepository() with an unknown store expected error, got nil")
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// QuoteRepository defines the interface for quote storage operations
+// Quotes never change once saved; implementations must be safe for concurrent use
+type QuoteRepository interface {
+	SaveQuote(quote *model.Quote) error
+	GetQuote(id string) (*model.Quote, error)
+	// ListQuotes returns the page of quotes matching filter, newest first, and how many match
+	ListQuotes(filter model.QuoteFilter) ([]model.Quote, int, error)
+}
+
+// DefaultMaxQuotes is how many quotes a repository keeps by default; the oldest go first
+const DefaultMaxQuotes = 100_000
+
+// storedQuote is a quote encoded as JSON, next to the fields filters read
+// Keeping the encoding means callers never share the maps of a stored quote
+type storedQuote struct {
+	header model.Quote
+	data   []byte
+}
+
+// InMemoryQuoteRepository implements QuoteRepository using in-memory storage
+type InMemoryQuoteRepository struct {
+	mu     sync.RWMutex
+	quotes []storedQuote // oldest first
+	// index maps a quote ID to its position counted from the first quote ever saved
+	index   map[string]int
+	dropped int
+	// maxQuotes bounds len(quotes); 0 keeps every quote
+	maxQuotes int
+}
+
+// NewInMemoryQuoteRepository creates an empty quote repository
+// It keeps the latest DefaultMaxQuotes quotes
+func NewInMemoryQuoteRepository() *InMemoryQuoteRepository {
+	return &InMemoryQuoteRepository{
+		index:     make(map[string]int),
+		maxQuotes: DefaultMaxQuotes,
+	}
+}
+
+// SaveQuote stores quote; its ID must be unique
+func (r *InMemoryQuoteRepository) SaveQuote(quote *model.Quote) error {
+	data, err := json.Marshal(quote)
+	if err != nil {
+		return fmt.Errorf("failed to encode quote: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.index[quote.ID]; ok {
+		return fmt.Errorf("quote %s already exists", quote.ID)
+	}
+	r.add(storedQuote{header: quoteHeader(quote), data: data})
+	return nil
+}
+
+// add appends a quote, dropping the oldest one beyond the limit
+// The caller must hold the write lock
+func (r *InMemoryQuoteRepository) add(quote storedQuote) {
+	if r.maxQuotes > 0 && len(r.quotes) >= r.maxQuotes {
+		delete(r.index, r.quotes[0].header.ID)
+		r.quotes[0] = storedQuote{}
+		r.quotes = r.quotes[1:]
+		r.dropped++
+	}
+	r.index[quote.header.ID] = r.dropped + len(r.quotes)
+	r.quotes = append(r.quotes, quote)
+}
+
+// GetQuote returns the quote with id
+func (r *InMemoryQuoteRepository) GetQuote(id string) (*model.Quote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	position, ok := r.index[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrQuoteNotFound, id)
+	}
+	return decodeQuote(r.quotes[position-r.dropped].data)
+}
+
+// ListQuotes returns the page of quotes matching filter, newest first, and how many match
+func (r *InMemoryQuoteRepository) ListQuotes(filter model.QuoteFilter) ([]model.Quote, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	quotes := []model.Quote{}
+	total := 0
+	for i := len(r.quotes) - 1; i >= 0; i-- {
+		if !filter.Matches(&r.quotes[i].header) {
+			continue
+		}
+		total++
+		if total <= filter.Offset || (filter.Limit > 0 && len(quotes) >= filter.Limit) {
+			continue
+		}
+		quote, err := decodeQuote(r.quotes[i].data)
+		if err != nil {
+			return nil, 0, err
+		}
+		quotes = append(quotes, *quote)
+	}
+	return quotes, total, nil
+}
+
+// quoteHeader returns the fields of quote that filters read
+func quoteHeader(quote *model.Quote) model.Quote {
+	return model.Quote{
+		ID:               quote.ID,
+		CreatedAt:        quote.CreatedAt,
+		Profile:          quote.Profile,
+		PackSizesVersion: quote.PackSizesVersion,
+		Request:          model.PackRequest{Quantity: quote.Request.Quantity},
+	}
+}
+
+func decodeQuote(data []byte) (*model.Quote, error) {
+	var quote model.Quote
+	if err := json.Unmarshal(data, &quote); err != nil {
+		return nil, fmt.Errorf("failed to decode quote: %w", err)
+	}
+	return &quote, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// testQuote returns a quote for quantity created minutes after a fixed time
+func testQuote(id string, quantity, minutes int, profile string, version *int) *model.Quote {
+	return &model.Quote{
+		ID:               id,
+		CreatedAt:        time.Date(2026, 10, 1, 12, minutes, 0, 0, time.UTC),
+		Profile:          profile,
+		PackSizesVersion: version,
+		Request:          model.PackRequest{Quantity: quantity},
+		Response: model.PackResponse{
+			Quantity:      quantity,
+			TotalItems:    500,
+			TotalPacks:    1,
+			PackBreakdown: map[int]int{500: 1},
+			PackSizesUsed: []int{250, 500},
+		},
+	}
+}
+
+func quoteIDs(quotes []model.Quote) []string {
+	ids := make([]string, len(quotes))
+	for i, quote := range quotes {
+		ids[i] = quote.ID
+	}
+	return ids
+}
+
+func TestInMemoryQuoteRepository_SaveAndGet(t *testing.T) {
+	repo := NewInMemoryQuoteRepository()
+	version := 2
+	quote := testQuote("q1", 251, 0, model.DefaultProfile, &version)
+
+	if err := repo.SaveQuote(quote); err != nil {
+		t.Fatalf("SaveQuote() error = %v", err)
+	}
+	if err := repo.SaveQuote(quote); err == nil {
+		t.Error("SaveQuote() with a duplicate ID expected error, got nil")
+	}
+
+	got, err := repo.GetQuote("q1")
+	if err != nil {
+		t.Fatalf("GetQuote() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, quote) {
+		t.Errorf("GetQuote() = %+v, want %+v", got, quote)
+	}
+
+	// The stored quote is not shared with callers
+	got.Response.PackBreakdown[250] = 2
+	if again, _ := repo.GetQuote("q1"); !reflect.DeepEqual(again, quote) {
+		t.Errorf("GetQuote() after changing a returned quote = %+v, want %+v", again, quote)
+	}
+
+	if _, err := repo.GetQuote("missing"); !errors.Is(err, model.ErrQuoteNotFound) {
+		t.Errorf("GetQuote() error = %v, want %v", err, model.ErrQuoteNotFound)
+	}
+}
+
+func TestInMemoryQuoteRepository_ListQuotes(t *testing.T) {
+	repo := NewInMemoryQuoteRepository()
+	v1, v2 := 1, 2
+	quotes := []*model.Quote{
+		testQuote("q1", 100, 0, model.DefaultProfile, &v1),
+		testQuote("q2", 250, 10, model.DefaultProfile, &v2),
+		testQuote("q3", 500, 20, "eu", nil),
+		testQuote("q4", 1000, 30, model.DefaultProfile, &v2),
+	}
+	for _, quote := range quotes {
+		if err := repo.SaveQuote(quote); err != nil {
+			t.Fatalf("SaveQuote() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    model.QuoteFilter
+		wantIDs   []string
+		wantTotal int
+	}{
+		{"Every quote, newest first", model.QuoteFilter{}, []string{"q4", "q3", "q2", "q1"}, 4},
+		{"Profile", model.QuoteFilter{Profile: "eu"}, []string{"q3"}, 1},
+		{"Quantity range", model.QuoteFilter{MinQuantity: 200, MaxQuantity: 500}, []string{"q3", "q2"}, 2},
+		{"Creation time", model.QuoteFilter{Since: quotes[1].CreatedAt, Until: quotes[2].CreatedAt}, []string{"q3", "q2"}, 2},
+		{"Pack sizes version", model.QuoteFilter{PackSizesVersion: 2}, []string{"q4", "q2"}, 2},
+		{"First page", model.QuoteFilter{Limit: 3}, []string{"q4", "q3", "q2"}, 4},
+		{"Last page", model.QuoteFilter{Limit: 3, Offset: 3}, []string{"q1"}, 4},
+		{"Past the end", model.QuoteFilter{Offset: 10}, []string{}, 4},
+		{"No match", model.QuoteFilter{Profile: "us"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := repo.ListQuotes(tt.filter)
+			if err != nil {
+				t.Fatalf("ListQuotes() error = %v", err)
+			}
+			if ids := quoteIDs(got); !reflect.DeepEqual(ids, tt.wantIDs) || total != tt.wantTotal {
+				t.Errorf("ListQuotes() = %v of %d, want %v of %d", ids, total, tt.wantIDs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestInMemoryQuoteRepository_DropsOldest(t *testing.T) {
+	repo := NewInMemoryQuoteRepository()
+	repo.maxQuotes = 2
+
+	for i := 1; i <= 3; i++ {
+		if err := repo.SaveQuote(testQuote(fmt.Sprintf("q%d", i), i, i, model.DefaultProfile, nil)); err != nil {
+			t.Fatalf("SaveQuote() error = %v", err)
+		}
+	}
+
+	if _, err := repo.GetQuote("q1"); !errors.Is(err, model.ErrQuoteNotFound) {
+		t.Errorf("GetQuote() of the oldest quote error = %v, want %v", err, model.ErrQuoteNotFound)
+	}
+	if got, err := repo.GetQuote("q3"); err != nil || got.ID != "q3" {
+		t.Errorf("GetQuote() = %v, %v, want q3", got, err)
+	}
+	got, total, _ := repo.ListQuotes(model.QuoteFilter{})
+	if ids := quoteIDs(got); !reflect.DeepEqual(ids, []string{"q3", "q2"}) || total != 2 {
+		t.Errorf("ListQuotes() = %v of %d, want [q3 q2] of 2", ids, total)
+	}
+}
@@ -24,18 +24,54 @@ func SetupRouter(handler *handler.PackHandler) *gin.Engine {
 	// Web UI routes
 	router.GET("/", handler.RenderHome)
 	router.POST("/calculate", handler.CalculatePacksForm)
+	router.POST("/calculate/csv", handler.CalculateCSVForm)
 	router.POST("/pack-sizes", handler.UpdatePackSizesForm)
+	router.POST("/pack-sizes/compare", handler.ComparePackSizesForm)
 
 	// Documentation routes
 	router.GET("/docs", handler.GetDocs)
 	router.GET("/docs/json", handler.GetDocsJSON)
 
+	// Unknown API routes get the same problem details as API errors
+	router.NoRoute(handler.NotFound)
+
 	// API routes
-	api := router.Group("/api")
+	// Idempotency comes first, so it records responses once ProblemDetails is done
+	api := router.Group("/api", handler.Idempotency, handler.ProblemDetails)
 	{
 		api.POST("/calculate", handler.CalculatePacks)
+		api.POST("/calculate/batch", handler.CalculateBatch)
+		api.POST("/calculate/csv", handler.CalculateCSV)
+		api.POST("/orders/calculate", handler.CalculateOrder)
 		api.GET("/pack-sizes", handler.GetPackSizes)
 		api.PUT("/pack-sizes", handler.UpdatePackSizes)
+		api.GET("/pack-sizes/revisions", handler.ListPackSizesRevisions)
+		api.GET("/pack-sizes/revisions/diff", handler.DiffPackSizesRevisions)
+		api.POST("/pack-sizes/revisions/:version/rollback", handler.RollbackPackSizes)
+		api.POST("/pack-sizes/analysis", handler.AnalyzePackSizes)
+		api.POST("/pack-sizes/compare", handler.ComparePackSizes)
+		api.POST("/recommendations", handler.StartRecommendation)
+		api.GET("/recommendations/:id", handler.GetRecommendation)
+		api.GET("/quotes", handler.ListQuotes)
+		api.GET("/quotes/:id", handler.GetQuote)
+		api.GET("/stock", handler.GetStock)
+		api.PUT("/stock", handler.UpdateStock)
+		api.GET("/pack-costs", handler.GetPackCosts)
+		api.PUT("/pack-costs", handler.UpdatePackCosts)
+		api.GET("/policies", handler.GetPolicies)
+		api.GET("/cache/stats", handler.GetCacheStats)
+		api.GET("/profiles", handler.ListProfiles)
+		api.POST("/profiles", handler.CreateProfile)
+		api.GET("/profiles/:name", handler.GetProfile)
+		api.PUT("/profiles/:name", handler.UpdateProfile)
+		api.DELETE("/profiles/:name", handler.DeleteProfile)
+	}
+
+	// Version 2 routes answer with the stable schemas of the model V2 types;
+	// version 1 routes keep theirs
+	v2 := api.Group("/v2")
+	{
+		v2.POST("/calculate", handler.CalculatePacksV2)
 	}
 
 	// Health check
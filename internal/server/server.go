@@ -0,0 +1,255 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marcellribeiro/awesomeProject/internal/handler"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/internal/router"
+	"github.com/marcellribeiro/awesomeProject/internal/service"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+	log "github.com/sirupsen/logrus"
+)
+
+// New wires the repository, calculator, service and router of the API from the
+// environment, so every command serves the same API
+func New() (*gin.Engine, error) {
+	// Repository layer - handles data storage
+	// PACK_STORE selects the storage (memory or file), PACK_STORE_PATH the file location
+	packRepo, err := repository.NewPackRepository(os.Getenv("PACK_STORE"), os.Getenv("PACK_STORE_PATH"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pack store: %w", err)
+	}
+
+	// Quotes use the same storage as the pack sizes; PACK_QUOTE_PATH is the quote file and
+	// PACK_QUOTE_LIMIT how many of the latest quotes are kept (0 keeps every quote)
+	maxQuotes, err := quoteLimitFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	quoteRepo, err := repository.NewQuoteRepository(os.Getenv("PACK_STORE"), os.Getenv("PACK_QUOTE_PATH"), maxQuotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quote store: %w", err)
+	}
+
+	// Limits bound the work of every calculation
+	// PACK_MAX_QUANTITY, PACK_MAX_TABLE_SIZE and PACK_TIMEOUT override the defaults
+	limits, err := limitsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calculation limits: %w", err)
+	}
+
+	// Calculator - handles the core algorithm
+	// PACK_ALGORITHM selects the implementation (dynamic or residue)
+	packCalc, err := calculator.NewPackCalculator(os.Getenv("PACK_ALGORITHM"), limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calculator: %w", err)
+	}
+
+	// Dynamic tables are shared between calculations unless PACK_TABLE_CELLS is 0;
+	// PACK_TABLE_SETS bounds how many pack size sets keep a table
+	packCalc, err = tablesFromEnv(packCalc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the shared tables: %w", err)
+	}
+
+	// Results are cached unless PACK_CACHE_SIZE is 0; PACK_CACHE_TTL bounds their age
+	packCalc, err = cacheFromEnv(packCalc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the result cache: %w", err)
+	}
+
+	// Service layer - handles business logic
+	packService := service.NewPackService(packCalc, packRepo, quoteRepo, limits)
+
+	// Responses to POST requests with an Idempotency-Key are replayed for PACK_IDEMPOTENCY_TTL
+	// (0 disables them), kept like the pack sizes; PACK_IDEMPOTENCY_PATH is their file and
+	// PACK_IDEMPOTENCY_LIMIT how many of the latest are kept (0 keeps them until they expire)
+	idempotencyTTL, err := idempotencyTTLFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	maxResponses, err := idempotencyLimitFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	idempotencyRepo, err := repository.NewIdempotencyRepository(os.Getenv("PACK_STORE"), os.Getenv("PACK_IDEMPOTENCY_PATH"), maxResponses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store: %w", err)
+	}
+
+	// Handler layer - handles HTTP requests
+	packHandler := handler.NewPackHandler(packService).WithIdempotency(idempotencyRepo, idempotencyTTL)
+
+	// Setup Gin router
+	return router.SetupRouter(packHandler), nil
+}
+
+// Run serves the API on port until it fails; an empty port uses PORT, or 8080
+func Run(port string) error {
+	log.Printf("📦 Starting Pack Calculator API...")
+	ginRouter, err := New()
+	if err != nil {
+		return err
+	}
+
+	// Get port from environment or use default
+	if port == "" {
+		port = os.Getenv("PORT")
+	}
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("🚀 Server starting on port %s", port)
+	log.Printf("🌐 Open http://localhost:%s in your browser", port)
+	log.Printf("📦 Pack Calculator API is ready!")
+
+	if err := ginRouter.Run(":" + port); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	return nil
+}
+
+// limitsFromEnv returns the default calculation limits overridden by the environment
+// PACK_TIMEOUT is a duration such as 2s; 0 removes a limit
+func limitsFromEnv() (calculator.Limits, error) {
+	limits := calculator.DefaultLimits()
+
+	if value := os.Getenv("PACK_MAX_QUANTITY"); value != "" {
+		maxQuantity, err := strconv.Atoi(value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid PACK_MAX_QUANTITY: %w", err)
+		}
+		limits.MaxQuantity = maxQuantity
+	}
+
+	if value := os.Getenv("PACK_MAX_TABLE_SIZE"); value != "" {
+		maxTableSize, err := strconv.Atoi(value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid PACK_MAX_TABLE_SIZE: %w", err)
+		}
+		limits.MaxTableSize = maxTableSize
+	}
+
+	if value := os.Getenv("PACK_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid PACK_TIMEOUT: %w", err)
+		}
+		limits.Timeout = timeout
+	}
+
+	return limits, limits.Validate()
+}
+
+// quoteLimitFromEnv returns how many quotes are kept, overridden by PACK_QUOTE_LIMIT
+func quoteLimitFromEnv() (int, error) {
+	value := os.Getenv("PACK_QUOTE_LIMIT")
+	if value == "" {
+		return repository.DefaultMaxQuotes, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid PACK_QUOTE_LIMIT: %q", value)
+	}
+	return limit, nil
+}
+
+// defaultIdempotencyTTL is how long responses are replayed, overridden by PACK_IDEMPOTENCY_TTL
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyTTLFromEnv returns how long responses to idempotent requests are replayed
+func idempotencyTTLFromEnv() (time.Duration, error) {
+	value := os.Getenv("PACK_IDEMPOTENCY_TTL")
+	if value == "" {
+		return defaultIdempotencyTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid PACK_IDEMPOTENCY_TTL: %q", value)
+	}
+	return ttl, nil
+}
+
+// idempotencyLimitFromEnv returns how many idempotent responses are kept, overridden by
+// PACK_IDEMPOTENCY_LIMIT
+func idempotencyLimitFromEnv() (int, error) {
+	value := os.Getenv("PACK_IDEMPOTENCY_LIMIT")
+	if value == "" {
+		return repository.DefaultMaxIdempotentResponses, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid PACK_IDEMPOTENCY_LIMIT: %q", value)
+	}
+	return limit, nil
+}
+
+// Shared table defaults, overridden by PACK_TABLE_CELLS and PACK_TABLE_SETS
+// 4,194,304 cells take 32 MiB
+const (
+	defaultTableCells = 1 << 22
+	defaultTableSets  = 16
+)
+
+// tablesFromEnv shares the tables of a dynamic calc in a store configured by the environment
+// A PACK_TABLE_CELLS of 0, or another algorithm, returns calc unchanged
+func tablesFromEnv(calc calculator.PackCalculator) (calculator.PackCalculator, error) {
+	cells := defaultTableCells
+	if value := os.Getenv("PACK_TABLE_CELLS"); value != "" {
+		var err error
+		if cells, err = strconv.Atoi(value); err != nil || cells < 0 {
+			return nil, fmt.Errorf("invalid PACK_TABLE_CELLS: %q", value)
+		}
+	}
+
+	sets := defaultTableSets
+	if value := os.Getenv("PACK_TABLE_SETS"); value != "" {
+		var err error
+		if sets, err = strconv.Atoi(value); err != nil || sets < 0 {
+			return nil, fmt.Errorf("invalid PACK_TABLE_SETS: %q", value)
+		}
+	}
+
+	dynamic, ok := calc.(*calculator.DynamicPackCalculator)
+	if !ok || cells == 0 {
+		return calc, nil
+	}
+	return dynamic.WithTables(calculator.NewTableStore(calculator.TableStoreOptions{MaxCells: cells, MaxSets: sets})), nil
+}
+
+// Result cache defaults, overridden by PACK_CACHE_SIZE and PACK_CACHE_TTL
+const (
+	defaultCacheSize = 10_000
+	defaultCacheTTL  = 10 * time.Minute
+)
+
+// cacheFromEnv wraps calc with a result cache configured by the environment
+// A PACK_CACHE_SIZE of 0 disables the cache and returns calc unchanged
+func cacheFromEnv(calc calculator.PackCalculator) (calculator.PackCalculator, error) {
+	size := defaultCacheSize
+	if value := os.Getenv("PACK_CACHE_SIZE"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid PACK_CACHE_SIZE: %q", value)
+		}
+	}
+	if size == 0 {
+		return calc, nil
+	}
+
+	ttl := defaultCacheTTL
+	if value := os.Getenv("PACK_CACHE_TTL"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid PACK_CACHE_TTL: %q", value)
+		}
+	}
+
+	return calculator.NewCachedPackCalculator(calc, size, ttl), nil
+}
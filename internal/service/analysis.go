@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// AnalyzePackSizes analyzes the requested pack sizes, or those of the requested profile
+// The analysis stops once ctx is done
+func (s *packService) AnalyzePackSizes(ctx context.Context, request *model.AnalysisRequest) (*model.PackSizesAnalysis, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	packSizes := request.PackSizes
+	if len(packSizes) == 0 {
+		var err error
+		if packSizes, err = s.profilePackSizes(request.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	analysis, err := s.alternativeCalculator.Analyze(ctx, packSizes, request.From, request.To)
+	if err != nil {
+		return nil, fmt.Errorf("analysis failed: %w", err)
+	}
+
+	response := &model.PackSizesAnalysis{
+		PackSizes:       analysis.PackSizes,
+		GCD:             analysis.GCD,
+		AllReachable:    analysis.GCD == 1 && analysis.Gaps == 0,
+		FrobeniusNumber: analysis.FrobeniusNumber,
+		Conductor:       analysis.Conductor,
+		Gaps:            analysis.Gaps,
+		Quantities: model.QuantityAnalysis{
+			From:               analysis.From,
+			To:                 analysis.To,
+			MaxOverage:         analysis.MaxOverage,
+			MaxOverageQuantity: analysis.MaxOverageQuantity,
+			AverageOverage:     analysis.AverageOverage,
+			ExactQuantities:    analysis.ExactQuantities,
+		},
+		Sizes:          make([]model.PackSizeUsage, len(analysis.Usage)),
+		RedundantSizes: []int{},
+	}
+	for i, usage := range analysis.Usage {
+		response.Sizes[i] = model.PackSizeUsage{Size: usage.Size, Quantities: usage.Quantities, Redundant: usage.Quantities == 0}
+		if usage.Quantities == 0 {
+			response.RedundantSizes = append(response.RedundantSizes, usage.Size)
+		}
+	}
+	return response, nil
+}
+
+// profilePackSizes returns the pack sizes of the named profile, default when empty
+// It fails with ErrNoPackSizes when the profile has none
+func (s *packService) profilePackSizes(name string) ([]int, error) {
+	if name == "" {
+		name = model.DefaultProfile
+	}
+	profile, err := s.repository.GetProfile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", wrapRepositoryError(err))
+	}
+	if len(profile.PackSizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+	return profile.PackSizes, nil
+}
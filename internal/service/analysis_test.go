@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestPackService_AnalyzePackSizes(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 5000})
+
+	tests := []struct {
+		name          string
+		request       *model.AnalysisRequest
+		wantSizes     []int
+		wantGCD       int
+		wantAll       bool
+		wantConductor int
+		wantRange     [2]int
+		wantRedundant []int
+	}{
+		{
+			name:          "Default pack sizes",
+			request:       &model.AnalysisRequest{To: 1000},
+			wantSizes:     []int{250, 5000},
+			wantGCD:       250,
+			wantConductor: 250,
+			wantRange:     [2]int{1, 1000},
+			wantRedundant: []int{5000},
+		},
+		{
+			name:          "Requested pack sizes",
+			request:       &model.AnalysisRequest{PackSizes: []int{20, 9, 6}},
+			wantSizes:     []int{6, 9, 20},
+			wantGCD:       1,
+			wantConductor: 44,
+			wantRange:     [2]int{1, 64},
+			wantRedundant: []int{},
+		},
+		{
+			name:          "Every quantity reached",
+			request:       &model.AnalysisRequest{PackSizes: []int{1, 3}},
+			wantSizes:     []int{1, 3},
+			wantGCD:       1,
+			wantAll:       true,
+			wantConductor: 1,
+			wantRange:     [2]int{1, 4},
+			wantRedundant: []int{},
+		},
+		{
+			name:          "Start past the default range",
+			request:       &model.AnalysisRequest{PackSizes: []int{250, 500}, From: 100_000},
+			wantSizes:     []int{250, 500},
+			wantGCD:       250,
+			wantConductor: 250,
+			wantRange:     [2]int{100_000, 100_500},
+			wantRedundant: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := service.AnalyzePackSizes(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("AnalyzePackSizes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(analysis.PackSizes, tt.wantSizes) {
+				t.Errorf("PackSizes = %v, want %v", analysis.PackSizes, tt.wantSizes)
+			}
+			if analysis.GCD != tt.wantGCD || analysis.AllReachable != tt.wantAll || analysis.Conductor != tt.wantConductor {
+				t.Errorf("GCD, AllReachable, Conductor = %d, %v, %d, want %d, %v, %d",
+					analysis.GCD, analysis.AllReachable, analysis.Conductor, tt.wantGCD, tt.wantAll, tt.wantConductor)
+			}
+			if got := [2]int{analysis.Quantities.From, analysis.Quantities.To}; got != tt.wantRange {
+				t.Errorf("Range = %v, want %v", got, tt.wantRange)
+			}
+			if !reflect.DeepEqual(analysis.RedundantSizes, tt.wantRedundant) {
+				t.Errorf("RedundantSizes = %v, want %v", analysis.RedundantSizes, tt.wantRedundant)
+			}
+			for _, usage := range analysis.Sizes {
+				if usage.Redundant != (usage.Quantities == 0) {
+					t.Errorf("Size %d redundant = %v with %d quantities", usage.Size, usage.Redundant, usage.Quantities)
+				}
+			}
+		})
+	}
+}
+
+func TestPackService_AnalyzePackSizes_Errors(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.Limits{MaxTableSize: 10_000})
+
+	tests := []struct {
+		name     string
+		request  *model.AnalysisRequest
+		wantCode string
+	}{
+		{"No pack sizes configured", &model.AnalysisRequest{}, model.CodeNoPackSizes},
+		{"Invalid range", &model.AnalysisRequest{PackSizes: []int{3}, From: 10, To: 5}, model.CodeValidationFailed},
+		{"Invalid pack size", &model.AnalysisRequest{PackSizes: []int{3, 0}}, model.CodeValidationFailed},
+		{"Unknown profile", &model.AnalysisRequest{Profile: "missing"}, model.CodeProfileNotFound},
+		{"Range too large", &model.AnalysisRequest{PackSizes: []int{3}, To: 1_000_000}, model.CodeTableTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.AnalyzePackSizes(context.Background(), tt.request)
+			if code := ErrorCode(err); code != tt.wantCode {
+				t.Errorf("AnalyzePackSizes() error = %v (code %q), want code %q", err, code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// batchWindow is how many requests per worker may be calculated ahead of the oldest
+// pending one, bounding the results held back to keep the request order
+const batchWindow = 4
+
+// BatchRequest is one request of a batch, or the error that kept it from being read
+type BatchRequest struct {
+	Request *model.PackRequest
+	Err     error
+}
+
+// BatchOf returns a closed channel holding requests, for batches read before they are calculated
+func BatchOf(requests []BatchRequest) <-chan BatchRequest {
+	ch := make(chan BatchRequest, len(requests))
+	for _, request := range requests {
+		ch <- request
+	}
+	close(ch)
+	return ch
+}
+
+// CalculateBatch calculates every request received from requests until it is closed,
+// running up to one calculation per CPU at a time, and passes each result to emit in
+// request order. A failing request is reported in its result; requests that could not
+// be read are reported as invalid requests. The batch stops at the first emit error
+// Batch results are not recorded as quotes
+func (s *packService) CalculateBatch(ctx context.Context, requests <-chan BatchRequest, emit func(model.BatchItemResult) error) error {
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every request gets its own result channel, queued in request order
+	pending := make(chan chan model.BatchItemResult, batchWindow*s.batchWorkers)
+	go s.dispatchBatch(batchCtx, requests, pending)
+
+	count := 0
+	for result := range pending {
+		if err := emit(<-result); err != nil {
+			cancel()
+			for range pending {
+			}
+			return err
+		}
+		count++
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", calculator.ErrCanceled, err)
+	}
+	if count == 0 {
+		return model.NewFieldError("requests", "must have at least one request")
+	}
+	return nil
+}
+
+// dispatchBatch starts the calculation of every request, at most batchWorkers at a time,
+// and queues their result channels on pending, which it closes once done
+func (s *packService) dispatchBatch(ctx context.Context, requests <-chan BatchRequest, pending chan<- chan model.BatchItemResult) {
+	defer close(pending)
+	workers := make(chan struct{}, s.batchWorkers)
+
+	for index := 0; ; index++ {
+		var request BatchRequest
+		var ok bool
+		select {
+		case request, ok = <-requests:
+		case <-ctx.Done():
+			return
+		}
+		if !ok {
+			return
+		}
+
+		result := make(chan model.BatchItemResult, 1)
+		select {
+		case pending <- result:
+		case <-ctx.Done():
+			return
+		}
+
+		workers <- struct{}{}
+		go func(index int, request BatchRequest) {
+			defer func() { <-workers }()
+			result <- s.calculateBatchItem(ctx, index, request)
+		}(index, request)
+	}
+}
+
+// calculateBatchItem calculates one request of a batch
+func (s *packService) calculateBatchItem(ctx context.Context, index int, request BatchRequest) model.BatchItemResult {
+	result := model.BatchItemResult{Index: index}
+	if request.Err != nil {
+		result.Error = request.Err.Error()
+		result.Code = model.CodeInvalidRequest
+		return result
+	}
+
+	// Batches plan many quantities at once; they are not quotes to customers
+	distribution, err := s.calculate(ctx, request.Request, false)
+	if err != nil {
+		result.Error = err.Error()
+		result.Code = ErrorCode(err)
+		return result
+	}
+	result.Result = distribution
+	return result
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestPackService_CalculateBatch(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+	tables := calculator.NewTableStore(calculator.TableStoreOptions{MaxCells: 1 << 20})
+	service := NewPackService(calculator.NewDynamicPackCalculator().WithTables(tables), repo, nil, calculator.DefaultLimits())
+	service.(*packService).batchWorkers = 4
+
+	var requests []BatchRequest
+	for quantity := 1; quantity <= 20_000; quantity += 97 {
+		requests = append(requests, BatchRequest{Request: &model.PackRequest{Quantity: quantity}})
+	}
+	requests = append(requests,
+		BatchRequest{Request: &model.PackRequest{Quantity: 0}},
+		BatchRequest{Err: errors.New("quantity must be int, got: string")},
+		BatchRequest{Request: &model.PackRequest{Quantity: 120, PackSizes: []int{50, 100}}},
+	)
+
+	var results []model.BatchItemResult
+	err := service.CalculateBatch(context.Background(), BatchOf(requests), func(result model.BatchItemResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CalculateBatch() error = %v", err)
+	}
+	if len(results) != len(requests) {
+		t.Fatalf("CalculateBatch() emitted %d results, want %d", len(results), len(requests))
+	}
+
+	for i, result := range results {
+		if result.Index != i {
+			t.Fatalf("Result %d has index %d, want request order", i, result.Index)
+		}
+		if requests[i].Err != nil || requests[i].Request.Quantity == 0 {
+			continue
+		}
+		want, _ := service.CalculatePackDistribution(context.Background(), requests[i].Request)
+		if !reflect.DeepEqual(result.Result, want) {
+			t.Errorf("Result %d = %+v, want %+v", i, result.Result, want)
+		}
+	}
+
+	failed := results[len(results)-3:]
+	if failed[0].Code != model.CodeValidationFailed || failed[1].Code != model.CodeInvalidRequest || failed[2].Result == nil {
+		t.Errorf("Last results = %+v, want a validation failure, an invalid request and a result", failed)
+	}
+
+	// Every quantity shares one table for the default pack sizes
+	if stats := tables.Stats(); stats.Sets != 2 || stats.Hits == 0 {
+		t.Errorf("Table stats = %+v, want 2 shared tables answering most requests", stats)
+	}
+}
+
+func TestPackService_CalculateBatch_Errors(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500})
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	requests := func(n int) <-chan BatchRequest {
+		batch := make([]BatchRequest, n)
+		for i := range batch {
+			batch[i] = BatchRequest{Request: &model.PackRequest{Quantity: i + 1}}
+		}
+		return BatchOf(batch)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	errEmit := errors.New("client gone")
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requests  <-chan BatchRequest
+		emitErr   error
+		wantEmits int
+		wantErr   error
+	}{
+		{
+			name:     "Empty batch",
+			ctx:      context.Background(),
+			requests: requests(0),
+		},
+		{
+			name:      "Emit failure stops the batch",
+			ctx:       context.Background(),
+			requests:  requests(100),
+			emitErr:   errEmit,
+			wantEmits: 1,
+			wantErr:   errEmit,
+		},
+		{
+			name:     "Canceled batch",
+			ctx:      canceled,
+			requests: requests(100),
+			wantErr:  calculator.ErrCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emits := 0
+			err := service.CalculateBatch(tt.ctx, tt.requests, func(model.BatchItemResult) error {
+				emits++
+				return tt.emitErr
+			})
+
+			switch {
+			case tt.wantErr == nil && !model.IsValidationError(err):
+				t.Errorf("CalculateBatch() error = %v, want validation error", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("CalculateBatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantEmits > 0 && emits != tt.wantEmits {
+				t.Errorf("CalculateBatch() emitted %d results, want %d", emits, tt.wantEmits)
+			}
+		})
+	}
+}
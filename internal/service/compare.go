@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// comparisonTableCells bounds the pack tables a comparison keeps for its two sets
+const comparisonTableCells = 1 << 22
+
+// ComparePackSizes compares the proposed pack sizes of the request with those of its
+// profile over the requested quantities
+// The base of the comparison is the current set and the candidate the proposed one;
+// it stops once ctx is done
+// A comparison gets its own table store instead of the configured calculator, so its
+// thousands of quantities neither evict the cached results nor the shared tables
+func (s *packService) ComparePackSizes(ctx context.Context, request *model.ComparisonRequest) (*model.PackSizesComparison, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.profilePackSizes(request.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := request.Quantities
+	if len(quantities) == 0 {
+		if quantities, err = calculator.QuantityRange(max(request.From, 1), request.To, max(request.Step, 1)); err != nil {
+			return nil, model.NewValidationError(err.Error())
+		}
+	}
+
+	calc := s.alternativeCalculator.WithTables(calculator.NewTableStore(calculator.TableStoreOptions{
+		MaxCells: comparisonTableCells,
+		MaxSets:  2,
+	}))
+	comparison, err := calculator.Compare(ctx, calc, quantities, current, request.PackSizes)
+	if err != nil {
+		return nil, fmt.Errorf("comparison failed: %w", err)
+	}
+
+	response := toPackSizesComparison(comparison)
+	if request.Summary {
+		response.Quantities = nil
+	}
+	return response, nil
+}
+
+// toPackSizesComparison converts a calculator comparison into its response
+func toPackSizesComparison(comparison *calculator.Comparison) *model.PackSizesComparison {
+	summary := comparison.Summary
+	response := &model.PackSizesComparison{
+		Base:       comparison.Base,
+		Candidate:  comparison.Candidate,
+		Quantities: make([]model.QuantityComparison, len(comparison.Quantities)),
+		Summary: model.ComparisonSummary{
+			Quantities:  summary.Quantities,
+			Base:        model.ComparisonTotals(summary.Base),
+			Candidate:   model.ComparisonTotals(summary.Candidate),
+			ItemsDiff:   summary.ItemsDiff,
+			OverageDiff: summary.OverageDiff,
+			PacksDiff:   summary.PacksDiff,
+			Better:      summary.Better,
+			Worse:       summary.Worse,
+			Same:        summary.Same,
+		},
+	}
+	for i, entry := range comparison.Quantities {
+		response.Quantities[i] = model.QuantityComparison{
+			Quantity:    entry.Quantity,
+			Base:        model.ComparisonOutcome(entry.Base),
+			Candidate:   model.ComparisonOutcome(entry.Candidate),
+			ItemsDiff:   entry.ItemsDiff,
+			OverageDiff: entry.OverageDiff,
+			PacksDiff:   entry.PacksDiff,
+		}
+	}
+	return response
+}
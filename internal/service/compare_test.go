@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestPackService_ComparePackSizes(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000})
+	if err := repo.CreateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{300}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		request        *model.ComparisonRequest
+		wantBase       []int
+		wantQuantities []int
+		wantSummary    model.ComparisonSummary
+	}{
+		{
+			name:           "Listed quantities against the default pack sizes",
+			request:        &model.ComparisonRequest{PackSizes: []int{600, 300}, Quantities: []int{1, 251, 501, 750}},
+			wantBase:       []int{250, 500, 1000},
+			wantQuantities: []int{1, 251, 501, 750},
+			// 1: 250 vs 300, 251: 500 vs 300, 501: 750 vs 600, 750: 750 vs 900
+			wantSummary: model.ComparisonSummary{
+				Quantities:  4,
+				Base:        model.ComparisonTotals{TotalItems: 2250, TotalOverage: 747, MaxOverage: 249, AverageOverage: 186.75, TotalPacks: 6},
+				Candidate:   model.ComparisonTotals{TotalItems: 2100, TotalOverage: 597, MaxOverage: 299, AverageOverage: 149.25, TotalPacks: 5},
+				ItemsDiff:   -150,
+				OverageDiff: -150,
+				PacksDiff:   -1,
+				Better:      2,
+				Worse:       2,
+			},
+		},
+		{
+			name:     "Range against a profile, summary only",
+			request:  &model.ComparisonRequest{Profile: "bolts", PackSizes: []int{300}, From: 100, To: 900, Step: 400, Summary: true},
+			wantBase: []int{300},
+			wantSummary: model.ComparisonSummary{
+				Quantities: 3,
+				Base:       model.ComparisonTotals{TotalItems: 1800, TotalOverage: 300, MaxOverage: 200, AverageOverage: 100, TotalPacks: 6},
+				Candidate:  model.ComparisonTotals{TotalItems: 1800, TotalOverage: 300, MaxOverage: 200, AverageOverage: 100, TotalPacks: 6},
+				Same:       3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison, err := service.ComparePackSizes(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("ComparePackSizes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(comparison.Base, tt.wantBase) {
+				t.Errorf("Base = %v, want %v", comparison.Base, tt.wantBase)
+			}
+			var quantities []int
+			for _, entry := range comparison.Quantities {
+				quantities = append(quantities, entry.Quantity)
+			}
+			if !reflect.DeepEqual(quantities, tt.wantQuantities) {
+				t.Errorf("Quantities = %v, want %v", quantities, tt.wantQuantities)
+			}
+			if comparison.Summary != tt.wantSummary {
+				t.Errorf("Summary = %+v, want %+v", comparison.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestPackService_ComparePackSizes_KeepsCache(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	calc := calculator.NewCachedPackCalculator(calculator.NewDynamicPackCalculator(), 10, 0)
+	service := NewPackService(calc, repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000})
+	if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 251}); err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+
+	// The comparison calculates 200 quantities twice without going through the cache
+	if _, err := service.ComparePackSizes(context.Background(), &model.ComparisonRequest{PackSizes: []int{300}, To: 200}); err != nil {
+		t.Fatalf("ComparePackSizes() error = %v", err)
+	}
+	if stats := service.GetCacheStats(); stats.Entries != 1 || stats.Misses != 1 || stats.Evictions != 0 {
+		t.Errorf("GetCacheStats() = %+v, want the one calculation cached", stats)
+	}
+}
+
+func TestPackService_ComparePackSizes_Errors(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	tests := []struct {
+		name    string
+		request *model.ComparisonRequest
+		wantErr error
+	}{
+		{"No current pack sizes", &model.ComparisonRequest{PackSizes: []int{300}, To: 10}, calculator.ErrNoPackSizes},
+		{"Missing profile", &model.ComparisonRequest{Profile: "nuts", PackSizes: []int{300}, To: 10}, model.ErrProfileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ComparePackSizes(context.Background(), tt.request); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ComparePackSizes() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := service.ComparePackSizes(context.Background(), &model.ComparisonRequest{PackSizes: []int{300}}); !model.IsValidationError(err) {
+		t.Errorf("ComparePackSizes() error = %v, want a validation error", err)
+	}
+}
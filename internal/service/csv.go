@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// CSVRow is one order of a CSV, as written back in the results
+type CSVRow struct {
+	OrderID  string
+	Quantity string
+}
+
+// ErrTooManyCSVRows is returned when a CSV holds more orders than the caller accepts
+var ErrTooManyCSVRows = errors.New("CSV has too many orders")
+
+// ReadCSVRequests reads orders from a CSV of order_id,quantity[,pack_sizes|profile]
+// The optional third column is read as pack sizes when it lists numbers, e.g. "250|500",
+// and as a profile name otherwise. A first row starting with order_id is a header.
+// Rows that cannot be read become requests carrying their error, so they are reported
+// with the other results; only a failure to read the input itself is returned, or
+// ErrTooManyCSVRows as soon as the order past maxRows is read (0 reads every order)
+// The CSV is read by the API and by the packcalc command, so both accept the same files
+func ReadCSVRequests(r io.Reader, maxRows int) ([]CSVRow, []BatchRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []CSVRow
+	var requests []BatchRequest
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, requests, nil
+		}
+
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(rows) == 0 && err == nil && isCSVHeader(record) {
+			continue
+		}
+		if maxRows > 0 && len(rows) == maxRows {
+			return nil, nil, ErrTooManyCSVRows
+		}
+
+		row := CSVRow{}
+		if len(record) > 0 {
+			row.OrderID = strings.TrimSpace(record[0])
+		}
+		if len(record) > 1 {
+			row.Quantity = strings.TrimSpace(record[1])
+		}
+		rows = append(rows, row)
+
+		if err != nil {
+			requests = append(requests, BatchRequest{Err: model.NewValidationError(fmt.Sprintf("row is not valid CSV: %v", parseErr.Err))})
+			continue
+		}
+		requests = append(requests, parseCSVRecord(record))
+	}
+}
+
+// isCSVHeader reports whether record is the header row of an orders CSV
+func isCSVHeader(record []string) bool {
+	return strings.EqualFold(strings.TrimSpace(record[0]), "order_id")
+}
+
+// parseCSVRecord converts one CSV row into a pack request
+func parseCSVRecord(record []string) BatchRequest {
+	if len(record) < 2 || len(record) > 3 {
+		return BatchRequest{Err: model.NewValidationError(fmt.Sprintf("row must have 2 or 3 columns: order_id,quantity[,pack_sizes|profile], got: %d", len(record)))}
+	}
+
+	value := strings.TrimSpace(record[1])
+	quantity, err := strconv.Atoi(value)
+	if err != nil {
+		return BatchRequest{Err: model.NewFieldError("quantity", fmt.Sprintf("must be a whole number, got: %q", value))}
+	}
+
+	request := &model.PackRequest{Quantity: quantity}
+	if len(record) == 3 {
+		option := strings.TrimSpace(record[2])
+		if sizes, ok := parseCSVPackSizes(option); ok {
+			request.PackSizes = sizes
+		} else {
+			request.Profile = option
+		}
+	}
+	return BatchRequest{Request: request}
+}
+
+// parseCSVPackSizes parses pack sizes separated by |, ;, commas or spaces
+// ok is false when value is empty or holds anything but whole numbers
+func parseCSVPackSizes(value string) (sizes []int, ok bool) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '|' || r == ';' || r == ',' || r == ' '
+	})
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	sizes = make([]int, len(fields))
+	for i, field := range fields {
+		size, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		sizes[i] = size
+	}
+	return sizes, true
+}
+
+// WriteCSVResults writes one row per order with the packs of every pack size used in
+// any row, the total items, the overage and the total packs, or the error of the row
+// Pack size columns are 0 when a calculated row sends none and empty for failed rows
+// Text read from the orders is escaped so spreadsheets do not run it as a formula
+func WriteCSVResults(w io.Writer, rows []CSVRow, results []model.BatchItemResult) error {
+	sizeSet := make(map[int]bool)
+	for _, result := range results {
+		if result.Result == nil {
+			continue
+		}
+		for _, size := range result.Result.PackSizesUsed {
+			sizeSet[size] = true
+		}
+	}
+	sizes := make([]int, 0, len(sizeSet))
+	for size := range sizeSet {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	header := []string{"order_id", "quantity"}
+	for _, size := range sizes {
+		header = append(header, "pack_"+strconv.Itoa(size))
+	}
+	header = append(header, "total_items", "overage", "total_packs", "error")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		record := make([]string, 0, len(header))
+		record = append(record, csvText(rows[i].OrderID), csvText(rows[i].Quantity))
+
+		response := result.Result
+		if response == nil {
+			record = append(record, make([]string, len(sizes)+3)...)
+			record = append(record, csvText(result.Error))
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, size := range sizes {
+			record = append(record, strconv.Itoa(response.PackBreakdown[size]))
+		}
+		record = append(record,
+			strconv.Itoa(response.TotalItems),
+			strconv.Itoa(response.TotalItems-response.Quantity),
+			strconv.Itoa(response.TotalPacks),
+			"",
+		)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvText prefixes text starting like a spreadsheet formula with a quote,
+// so it is shown as text when the CSV is opened
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
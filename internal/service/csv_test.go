@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+func TestReadCSVRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		csv          string
+		wantRows     []CSVRow
+		wantRequests []*model.PackRequest // nil for rows that cannot be read
+	}{
+		{
+			name:         "Quantity only",
+			csv:          "SO-1,501\n",
+			wantRows:     []CSVRow{{"SO-1", "501"}},
+			wantRequests: []*model.PackRequest{{Quantity: 501}},
+		},
+		{
+			name:         "Header is skipped",
+			csv:          "order_id,quantity,pack_sizes\nSO-1,501\n",
+			wantRows:     []CSVRow{{"SO-1", "501"}},
+			wantRequests: []*model.PackRequest{{Quantity: 501}},
+		},
+		{
+			name:     "Pack sizes or profile",
+			csv:      "SO-1,120,50|100\nSO-2,120,\"50,100\"\nSO-3, 120 ,50 100\nSO-4,120,bolts\n",
+			wantRows: []CSVRow{{"SO-1", "120"}, {"SO-2", "120"}, {"SO-3", "120"}, {"SO-4", "120"}},
+			wantRequests: []*model.PackRequest{
+				{Quantity: 120, PackSizes: []int{50, 100}},
+				{Quantity: 120, PackSizes: []int{50, 100}},
+				{Quantity: 120, PackSizes: []int{50, 100}},
+				{Quantity: 120, Profile: "bolts"},
+			},
+		},
+		{
+			name:         "Unreadable rows are kept",
+			csv:          "SO-1,many\nSO-2\nSO-3,1,2,3\nSO-4,1\"2\n\nSO-5,251\n",
+			wantRows:     []CSVRow{{"SO-1", "many"}, {"SO-2", ""}, {"SO-3", "1"}, {"SO-4", ""}, {"SO-5", "251"}},
+			wantRequests: []*model.PackRequest{nil, nil, nil, nil, {Quantity: 251}},
+		},
+		{
+			name: "Empty",
+			csv:  "order_id,quantity\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, requests, err := ReadCSVRequests(strings.NewReader(tt.csv), 0)
+			if err != nil {
+				t.Fatalf("ReadCSVRequests() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("ReadCSVRequests() rows = %v, want %v", rows, tt.wantRows)
+			}
+			if len(requests) != len(tt.wantRequests) {
+				t.Fatalf("ReadCSVRequests() returned %d requests, want %d", len(requests), len(tt.wantRequests))
+			}
+			for i, want := range tt.wantRequests {
+				if want == nil {
+					if requests[i].Err == nil {
+						t.Errorf("Request %d = %+v, want an error", i, requests[i].Request)
+					}
+					continue
+				}
+				if requests[i].Err != nil || !reflect.DeepEqual(requests[i].Request, want) {
+					t.Errorf("Request %d = %+v, %v, want %+v", i, requests[i].Request, requests[i].Err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadCSVRequests_MaxRows(t *testing.T) {
+	orders := "order_id,quantity\nSO-1,1\nSO-2,2\nSO-3,3\n"
+	tests := []struct {
+		name     string
+		maxRows  int
+		wantRows int
+		wantErr  error
+	}{
+		{"No limit", 0, 3, nil},
+		{"At the limit", 3, 3, nil},
+		{"Past the limit", 2, 0, ErrTooManyCSVRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, _, err := ReadCSVRequests(strings.NewReader(orders), tt.maxRows)
+			if !errors.Is(err, tt.wantErr) || len(rows) != tt.wantRows {
+				t.Errorf("ReadCSVRequests() = %d rows, %v, want %d rows, %v", len(rows), err, tt.wantRows, tt.wantErr)
+			}
+		})
+	}
+
+	// The order past the limit ends the reading, so the rest of the input is never read
+	input := io.MultiReader(strings.NewReader(orders), iotest.ErrReader(errors.New("read past the limit")))
+	if _, _, err := ReadCSVRequests(input, 2); !errors.Is(err, ErrTooManyCSVRows) {
+		t.Errorf("ReadCSVRequests() error = %v, want %v", err, ErrTooManyCSVRows)
+	}
+}
+
+func TestWriteCSVResults(t *testing.T) {
+	rows := []CSVRow{{"SO-1", "501"}, {"SO-2", "0"}, {"SO-3", "120"}, {"=HYPERLINK(\"x\")", "-1"}, {"@SUM(A1)", "+1"}}
+	results := []model.BatchItemResult{
+		{Index: 0, Result: model.NewPackResponse(501, map[int]int{500: 1, 250: 1}, []int{250, 500, 1000})},
+		{Index: 1, Error: "quantity must be greater than 0", Code: model.CodeValidationFailed},
+		{Index: 2, Result: model.NewPackResponse(120, map[int]int{50: 1, 100: 1}, []int{50, 100})},
+		{Index: 3, Error: "-1 is not a quantity", Code: model.CodeValidationFailed},
+		{Index: 4, Result: model.NewPackResponse(1, map[int]int{50: 1}, []int{50, 100})},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSVResults(&buf, rows, results); err != nil {
+		t.Fatalf("WriteCSVResults() error = %v", err)
+	}
+
+	want := "order_id,quantity,pack_50,pack_100,pack_250,pack_500,pack_1000,total_items,overage,total_packs,error\n" +
+		"SO-1,501,0,0,1,1,0,750,249,2,\n" +
+		"SO-2,0,,,,,,,,,quantity must be greater than 0\n" +
+		"SO-3,120,1,1,0,0,0,150,30,2,\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",'-1,,,,,,,,,'-1 is not a quantity\n" +
+		"'@SUM(A1),'+1,1,0,0,0,0,50,49,1,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSVResults() =\n%s\nwant\n%s", got, want)
+	}
+}
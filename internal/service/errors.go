@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// ErrNoPackSizes is returned when neither the request nor the profile has usable pack sizes
+var ErrNoPackSizes = fmt.Errorf("%w available", calculator.ErrNoPackSizes)
+
+// ErrRepository matches every failure reported by the pack or quote repository
+var ErrRepository = errors.New("pack repository failure")
+
+// repositoryError wraps an error of a repository so it matches ErrRepository
+// while keeping its own message and wrapped errors
+type repositoryError struct {
+	err error
+}
+
+func (e *repositoryError) Error() string { return e.err.Error() }
+
+func (e *repositoryError) Unwrap() error { return e.err }
+
+func (e *repositoryError) Is(target error) bool { return target == ErrRepository }
+
+// wrapRepositoryError marks err as coming from a repository; nil stays nil
+func wrapRepositoryError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &repositoryError{err: err}
+}
+
+// ErrorCode returns the machine-readable code of an error returned by PackService
+// Domain errors are checked before repository failures, so a missing profile keeps
+// its own code even though the repository reported it
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, calculator.ErrNoPackSizes):
+		return model.CodeNoPackSizes
+	case errors.Is(err, calculator.ErrQuantityTooLarge):
+		return model.CodeQuantityTooLarge
+	case errors.Is(err, calculator.ErrTableTooLarge):
+		return model.CodeTableTooLarge
+	case errors.Is(err, calculator.ErrTimeout):
+		return model.CodeTimeout
+	case errors.Is(err, calculator.ErrCanceled):
+		return model.CodeCanceled
+	case errors.Is(err, calculator.ErrUnreachable):
+		return model.CodeUnreachable
+	case errors.Is(err, calculator.ErrInsufficientStock):
+		return model.CodeInsufficientStock
+	case errors.Is(err, calculator.ErrOverageLimit):
+		return model.CodeOverageLimit
+	case errors.Is(err, model.ErrProfileNotFound):
+		return model.CodeProfileNotFound
+	case errors.Is(err, model.ErrProfileExists):
+		return model.CodeProfileExists
+	case errors.Is(err, model.ErrRevisionNotFound):
+		return model.CodeRevisionNotFound
+	case errors.Is(err, model.ErrVersionConflict):
+		return model.CodeVersionConflict
+	case errors.Is(err, model.ErrJobNotFound):
+		return model.CodeJobNotFound
+	case errors.Is(err, model.ErrTooManyJobs):
+		return model.CodeTooManyJobs
+	case errors.Is(err, model.ErrQuoteNotFound):
+		return model.CodeQuoteNotFound
+	case model.IsValidationError(err):
+		return model.CodeValidationFailed
+	case errors.Is(err, ErrRepository):
+		return model.CodeRepositoryFailure
+	default:
+		return model.CodeInternal
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Nil", nil, ""},
+		{"Validation", model.NewValidationError("quantity must be greater than 0"), model.CodeValidationFailed},
+		{"Wrapped validation", fmt.Errorf("line 1: %w", model.NewValidationError("sku is required")), model.CodeValidationFailed},
+		{"No pack sizes", ErrNoPackSizes, model.CodeNoPackSizes},
+		{"Quantity too large", fmt.Errorf("calculation failed: %w", calculator.ErrQuantityTooLarge), model.CodeQuantityTooLarge},
+		{"Table too large", calculator.ErrTableTooLarge, model.CodeTableTooLarge},
+		{"Alternatives too large", calculator.ErrAlternativesTooLarge, model.CodeTableTooLarge},
+		{"Timeout", fmt.Errorf("calculation failed: %w", calculator.ErrTimeout), model.CodeTimeout},
+		{"Canceled", calculator.ErrCanceled, model.CodeCanceled},
+		{"Unreachable", calculator.ErrUnreachable, model.CodeUnreachable},
+		{"Insufficient stock", calculator.ErrInsufficientStock, model.CodeInsufficientStock},
+		{"Overage limit", calculator.ErrOverageLimit, model.CodeOverageLimit},
+		{"Missing profile from the repository", wrapRepositoryError(model.ErrProfileNotFound), model.CodeProfileNotFound},
+		{"Existing profile", model.ErrProfileExists, model.CodeProfileExists},
+		{"Missing revision", model.ErrRevisionNotFound, model.CodeRevisionNotFound},
+		{"Version conflict", model.ErrVersionConflict, model.CodeVersionConflict},
+		{"Missing job", fmt.Errorf("%w: 42", model.ErrJobNotFound), model.CodeJobNotFound},
+		{"Too many jobs", model.ErrTooManyJobs, model.CodeTooManyJobs},
+		{"Repository failure", wrapRepositoryError(errors.New("disk full")), model.CodeRepositoryFailure},
+		{"Unknown", errors.New("boom"), model.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackService_TypedErrors(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 10}); !errors.Is(err, ErrNoPackSizes) {
+		t.Errorf("CalculatePackDistribution() without pack sizes error = %v, want %v", err, ErrNoPackSizes)
+	}
+
+	repo.SetPackSizes([]int{250, 500})
+	_, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: calculator.DefaultMaxTableSize})
+	if !errors.Is(err, calculator.ErrTableTooLarge) {
+		t.Errorf("CalculatePackDistribution() huge quantity error = %v, want %v", err, calculator.ErrTableTooLarge)
+	}
+
+	_, err = service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 10, Profile: "nuts"})
+	if !errors.Is(err, model.ErrProfileNotFound) || !errors.Is(err, ErrRepository) {
+		t.Errorf("CalculatePackDistribution() unknown profile error = %v, want %v from the repository", err, model.ErrProfileNotFound)
+	}
+
+	if err := service.UpdatePackSizes([]int{250, -1}, model.PackSizesChange{}); !model.IsValidationError(err) {
+		t.Errorf("UpdatePackSizes() negative size error = %v, want validation error", err)
+	}
+
+	order, _ := service.CalculateOrder(context.Background(), &model.OrderRequest{Lines: []model.OrderLine{
+		{SKU: "widget", PackRequest: model.PackRequest{Quantity: 10, Profile: "nuts"}},
+	}})
+	if order.Lines[0].Code != model.CodeProfileNotFound {
+		t.Errorf("Order line code = %q, want %q", order.Lines[0].Code, model.CodeProfileNotFound)
+	}
+}
+
+func TestPackService_Limits(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500, 1000})
+	limits := calculator.Limits{MaxQuantity: 10_000, MaxTableSize: calculator.DefaultMaxTableSize}
+	service := NewPackService(calculator.NewDynamicPackCalculator().WithLimits(limits), repo, nil, limits)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		request model.PackRequest
+		want    error
+	}{
+		{"Within the limits", context.Background(), model.PackRequest{Quantity: 10_000}, nil},
+		{"Quantity above the limit", context.Background(), model.PackRequest{Quantity: 10_001}, calculator.ErrQuantityTooLarge},
+		{"Bounded calculator", context.Background(), model.PackRequest{Quantity: 10_001, Stock: map[int]int{250: 100}}, calculator.ErrQuantityTooLarge},
+		{"Canceled request", canceled, model.PackRequest{Quantity: 501}, calculator.ErrCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CalculatePackDistribution(tt.ctx, &tt.request)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CalculatePackDistribution() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
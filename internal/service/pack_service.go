@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"runtime"
+	"slices"
 	"sort"
 
 	"github.com/marcellribeiro/awesomeProject/internal/model"
@@ -11,81 +15,506 @@ import (
 
 // PackService defines the interface for pack calculation business logic
 type PackService interface {
-	CalculatePackDistribution(request *model.PackRequest) (*model.PackResponse, error)
+	CalculatePackDistribution(ctx context.Context, request *model.PackRequest) (*model.PackResponse, error)
+	CalculateOrder(ctx context.Context, request *model.OrderRequest) (*model.OrderResponse, error)
+	CalculateBatch(ctx context.Context, requests <-chan BatchRequest, emit func(model.BatchItemResult) error) error
+	AnalyzePackSizes(ctx context.Context, request *model.AnalysisRequest) (*model.PackSizesAnalysis, error)
+	ComparePackSizes(ctx context.Context, request *model.ComparisonRequest) (*model.PackSizesComparison, error)
+	StartRecommendation(request *model.RecommendationRequest) (*model.RecommendationJob, error)
+	GetRecommendation(id string) (*model.RecommendationJob, error)
+	GetQuote(id string) (*model.Quote, error)
+	ListQuotes(filter model.QuoteFilter) (*model.QuoteList, error)
 	GetAvailablePackSizes() ([]int, error)
-	UpdatePackSizes(sizes []int) error
+	UpdatePackSizes(sizes []int, change model.PackSizesChange) error
+	GetPackSizesSnapshot() (model.PackSizesSnapshot, error)
+	UpdatePackSizesIfMatch(sizes []int, version int, change model.PackSizesChange) (model.PackSizesSnapshot, error)
+	ListPackSizesRevisions() ([]model.PackSizesRevision, error)
+	DiffPackSizesRevisions(from, to int) (*model.PackSizesDiff, error)
+	RollbackPackSizes(version int, change model.PackSizesChange) (model.PackSizesSnapshot, error)
+	GetStock() (map[int]int, error)
+	UpdateStock(stock map[int]int) error
+	GetPackCosts() (map[int]int, error)
+	UpdatePackCosts(costs map[int]int) error
+	GetPolicies() []calculator.Policy
+	GetCacheStats() calculator.CacheStats
+	ListProfiles() ([]model.PackProfile, error)
+	GetProfile(name string) (*model.PackProfile, error)
+	CreateProfile(profile *model.PackProfile) error
+	UpdateProfile(profile *model.PackProfile) error
+	DeleteProfile(name string) error
 }
 
 // packService implements PackService
 type packService struct {
-	calculator calculator.PackCalculator
+	calculator        calculator.PackCalculator
+	boundedCalculator *calculator.BoundedPackCalculator
+	// alternativeCalculator enumerates ranked alternatives, explains the default rules,
+	// analyzes and recommends pack sizes whatever algorithm calculator uses
+	alternativeCalculator *calculator.DynamicPackCalculator
+	// cache is the calculator itself when it keeps results, nil otherwise
+	cache      resultCache
 	repository repository.PackRepository
+	// quotes records every successful calculation; nil records nothing
+	quotes repository.QuoteRepository
+	// batchWorkers bounds the calculations of a batch running at once
+	batchWorkers int
+	// jobs holds the pack size recommendations searching in the background
+	jobs *recommendationJobs
+}
+
+// resultCache is implemented by calculators keeping results between calls,
+// such as calculator.CachedPackCalculator
+type resultCache interface {
+	Invalidate()
+	Stats() calculator.CacheStats
 }
 
 // NewPackService creates a new pack service instance
-func NewPackService(calc calculator.PackCalculator, repo repository.PackRepository) PackService {
+// limits apply to the calculators the service creates itself; calc enforces its own.
+// Successful calculations are recorded as quotes in quotes, unless it is nil
+func NewPackService(calc calculator.PackCalculator, repo repository.PackRepository, quotes repository.QuoteRepository, limits calculator.Limits) PackService {
+	cache, _ := calc.(resultCache)
 	return &packService{
-		calculator: calc,
-		repository: repo,
+		calculator:            calc,
+		boundedCalculator:     calculator.NewBoundedPackCalculator().WithLimits(limits),
+		alternativeCalculator: calculator.NewDynamicPackCalculator().WithLimits(limits),
+		cache:                 cache,
+		repository:            repo,
+		quotes:                quotes,
+		batchWorkers:          runtime.GOMAXPROCS(0),
+		jobs:                  newRecommendationJobs(),
 	}
 }
 
 // CalculatePackDistribution calculates the optimal pack distribution for a given quantity
-func (s *packService) CalculatePackDistribution(request *model.PackRequest) (*model.PackResponse, error) {
+// The calculation stops once ctx is done; a successful one is recorded as a quote
+func (s *packService) CalculatePackDistribution(ctx context.Context, request *model.PackRequest) (*model.PackResponse, error) {
+	return s.calculate(ctx, request, true)
+}
+
+// calculate calculates the distribution of request, recording a successful one as a
+// quote when quote is true
+func (s *packService) calculate(ctx context.Context, request *model.PackRequest, quote bool) (*model.PackResponse, error) {
 	// Validate request
 	if err := request.Validate(); err != nil {
 		return nil, err
 	}
 
-	// Get pack sizes (use provided ones or fetch from repository)
-	var packSizes []int
-	var err error
+	// Get the configuration of the requested profile (default when not given)
+	profileName := request.Profile
+	if profileName == "" {
+		profileName = model.DefaultProfile
+	}
+	profile, err := s.repository.GetProfile(profileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", wrapRepositoryError(err))
+	}
 
+	// Use provided pack sizes or the profile ones
+	// Stock from the request wins; profile stock only applies to profile sizes
+	packSizes := profile.PackSizes
+	stock := profile.Stock
 	if request.HasPackSizes() {
 		packSizes = request.GetValidPackSizes()
-	} else {
-		packSizes, err = s.repository.GetAllPackSizes()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get pack sizes: %w", err)
-		}
+		stock = nil
+	}
+
+	if request.HasStock() {
+		stock = request.Stock
 	}
 
 	if len(packSizes) == 0 {
-		return nil, model.NewValidationError("no valid pack sizes available")
+		return nil, ErrNoPackSizes
+	}
+
+	// Costs are a property of the box, so profile costs apply to any pack sizes
+	costs := profile.PackCosts
+	if request.HasPackCosts() {
+		costs = request.PackCosts
+	}
+
+	policy, err := resolvePolicy(request)
+	if err != nil {
+		return nil, err
 	}
 
 	// Calculate optimal distribution
-	breakdown, err := s.calculator.Calculate(request.Quantity, packSizes)
+	// The bounded calculator is only needed when the default rules do not apply
+	// An explanation traces the dynamic calculator, so its breakdown is returned as is
+	var breakdown map[int]int
+	var algorithm string
+	var explanation *model.PackExplanation
+	if request.Explain {
+		explanation, breakdown, err = s.explain(ctx, request, packSizes, stock, policy)
+		if err != nil {
+			return nil, err
+		}
+		algorithm = calculator.AlgorithmDynamic
+	} else if !usesDefaultRules(stock, policy) {
+		breakdown, err = s.boundedCalculator.CalculateWithOptions(ctx, request.Quantity, packSizes, calculator.Options{
+			Stock:  stock,
+			Costs:  costs,
+			Policy: policy,
+		})
+		algorithm = calculator.AlgorithmBounded
+	} else {
+		breakdown, err = s.calculator.Calculate(ctx, request.Quantity, packSizes)
+		algorithm = calculator.Algorithm(s.calculator)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("calculation failed: %w", err)
 	}
 
 	// Build response with calculated totals
-	return model.NewPackResponse(request.Quantity, breakdown, packSizes), nil
+	response := model.NewPackResponse(request.Quantity, breakdown, packSizes)
+	response.CalculateCost(costs)
+	response.Policy = policy.Name
+	response.Algorithm = algorithm
+	if response.PackSetVersion, err = s.packSetVersion(request, profileName, packSizes); err != nil {
+		return nil, err
+	}
+
+	if request.Alternatives > 0 {
+		if response.Alternatives, err = s.calculateAlternatives(ctx, request, packSizes, stock, costs, policy, breakdown); err != nil {
+			return nil, err
+		}
+	}
+	response.Explanation = explanation
+	if !quote {
+		return response, nil
+	}
+	if err := s.recordQuote(request, profileName, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// packSetVersion returns the revision of the default pack sizes when the calculation
+// used them, nil otherwise
+func (s *packService) packSetVersion(request *model.PackRequest, profile string, packSizes []int) (*int, error) {
+	if profile != model.DefaultProfile || request.HasPackSizes() {
+		return nil, nil
+	}
+	snapshot, err := s.repository.GetPackSizesSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack sizes: %w", wrapRepositoryError(err))
+	}
+	// The sizes may have changed since the calculation read them
+	if !slices.Equal(snapshot.PackSizes, packSizes) {
+		return nil, nil
+	}
+	return &snapshot.Version, nil
+}
+
+// usesDefaultRules reports whether the default rules alone decide the breakdown
+// The default policy does not rank costs, so they are only reported
+func usesDefaultRules(stock map[int]int, policy calculator.Policy) bool {
+	return len(stock) == 0 && policy.Name == calculator.PolicyDefault.Name && policy.MaxOveragePercent <= 0
+}
+
+// explain traces how the default rules reach a breakdown and returns that breakdown,
+// which may differ from the configured calculator's on ties
+func (s *packService) explain(ctx context.Context, request *model.PackRequest, packSizes []int, stock map[int]int, policy calculator.Policy) (*model.PackExplanation, map[int]int, error) {
+	if !usesDefaultRules(stock, policy) {
+		return nil, nil, model.NewFieldError("explain", "is only available with the default policy and unlimited stock")
+	}
+
+	trace, err := s.alternativeCalculator.Explain(ctx, request.Quantity, packSizes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to explain calculation: %w", err)
+	}
+
+	explanation := &model.PackExplanation{
+		MinimumAmount: trace.MinimumAmount,
+		Candidates:    make([]model.AmountCandidate, len(trace.Candidates)),
+		PackCounts:    make([]model.PackCountOption, len(trace.PackCounts)),
+	}
+	for i, candidate := range trace.Candidates {
+		explanation.Candidates[i] = model.AmountCandidate(candidate)
+	}
+	for i, option := range trace.PackCounts {
+		explanation.PackCounts[i] = model.PackCountOption{
+			LastPack:      option.LastPack,
+			TotalPacks:    option.Packs,
+			PackBreakdown: option.Breakdown,
+			Chosen:        option.Chosen,
+			Reason:        option.Reason,
+		}
+	}
+	return explanation, trace.Breakdown, nil
+}
+
+// calculateAlternatives lists the best distributions under the rules that chose breakdown,
+// within the same stock; breakdown is always ranked first
+func (s *packService) calculateAlternatives(ctx context.Context, request *model.PackRequest, packSizes []int, stock, costs map[int]int, policy calculator.Policy, breakdown map[int]int) ([]model.PackAlternative, error) {
+	if request.Alternatives > calculator.MaxAlternatives {
+		return nil, model.NewFieldError("alternatives", fmt.Sprintf("cannot be more than %d, got: %d", calculator.MaxAlternatives, request.Alternatives))
+	}
+
+	var breakdowns []map[int]int
+	var err error
+	if usesDefaultRules(stock, policy) {
+		breakdowns, err = s.alternativeCalculator.CalculateAlternatives(ctx, request.Quantity, packSizes, request.Alternatives)
+	} else {
+		breakdowns, err = s.boundedCalculator.CalculateAlternatives(ctx, request.Quantity, packSizes, request.Alternatives, calculator.Options{
+			Stock:  stock,
+			Costs:  costs,
+			Policy: policy,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate alternatives: %w", err)
+	}
+	breakdowns = rankFirst(breakdowns, breakdown)
+
+	alternatives := make([]model.PackAlternative, len(breakdowns))
+	for i, breakdown := range breakdowns {
+		alternatives[i] = model.NewPackAlternative(i+1, request.Quantity, breakdown)
+	}
+	return alternatives, nil
+}
+
+// rankFirst moves breakdown to the front of the ranked breakdowns
+// The calculator may break a tie differently from the enumeration, so breakdown can
+// also be missing, in which case it takes the place of the last one
+func rankFirst(breakdowns []map[int]int, breakdown map[int]int) []map[int]int {
+	if len(breakdowns) == 0 {
+		return breakdowns
+	}
+	i := slices.IndexFunc(breakdowns, func(b map[int]int) bool { return maps.Equal(b, breakdown) })
+	if i < 0 {
+		i = len(breakdowns) - 1
+	}
+	ranked := append([]map[int]int{breakdown}, breakdowns[:i]...)
+	return append(ranked, breakdowns[i+1:]...)
+}
+
+// CalculateOrder calculates the pack distribution of every order line
+// Lines are validated and calculated independently, so a failing line is reported
+// in its result instead of failing the whole order
+func (s *packService) CalculateOrder(ctx context.Context, request *model.OrderRequest) (*model.OrderResponse, error) {
+	if len(request.Lines) == 0 {
+		return nil, model.NewFieldError("lines", "must have at least one line")
+	}
+
+	response := &model.OrderResponse{
+		OrderID: request.OrderID,
+		Lines:   make([]model.OrderLineResult, 0, len(request.Lines)),
+	}
+
+	for i := range request.Lines {
+		line := &request.Lines[i]
+		result := model.OrderLineResult{SKU: line.SKU}
+
+		if err := line.Validate(); err != nil {
+			result.Error = err.Error()
+			result.Code = ErrorCode(err)
+		} else if distribution, err := s.CalculatePackDistribution(ctx, &line.PackRequest); err != nil {
+			result.Error = err.Error()
+			result.Code = ErrorCode(err)
+		} else {
+			result.Result = distribution
+		}
+
+		response.AddLine(result)
+	}
+
+	return response, nil
+}
+
+// resolvePolicy returns the named policy of the request, with the request overage limit
+// The cost objective is a shorthand for the cost policy
+func resolvePolicy(request *model.PackRequest) (calculator.Policy, error) {
+	name := request.Policy
+	if name == "" && request.Objective == model.ObjectiveCost {
+		name = calculator.PolicyCost.Name
+	}
+	if name == "" {
+		name = calculator.PolicyDefault.Name
+	}
+
+	policy, ok := calculator.LookupPolicy(name)
+	if !ok {
+		return calculator.Policy{}, model.NewFieldError("policy", fmt.Sprintf("is unknown: %s", name))
+	}
+	policy.MaxOveragePercent = request.MaxOveragePercent
+	return policy, nil
 }
 
 // GetAvailablePackSizes returns all configured pack sizes
 func (s *packService) GetAvailablePackSizes() ([]int, error) {
 	sizes, err := s.repository.GetAllPackSizes()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
+		return nil, fmt.Errorf("failed to get pack sizes: %w", wrapRepositoryError(err))
 	}
 	sort.Ints(sizes)
 	return sizes, nil
 }
 
-// UpdatePackSizes updates the configured pack sizes
-func (s *packService) UpdatePackSizes(sizes []int) error {
-	if len(sizes) == 0 {
-		return fmt.Errorf("pack sizes cannot be empty")
+// UpdatePackSizes updates the configured pack sizes, recording who changed them and why
+func (s *packService) UpdatePackSizes(sizes []int, change model.PackSizesChange) error {
+	if err := model.ValidatePackSizes(sizes); err != nil {
+		return err
 	}
+	_, err := s.repository.UpdatePackSizes(sizes, change)
+	s.invalidateCache(err)
+	return wrapRepositoryError(err)
+}
 
-	// Validate all sizes are positive
-	for _, size := range sizes {
-		if size <= 0 {
-			return fmt.Errorf("all pack sizes must be positive, got: %d", size)
-		}
+// invalidateCache drops the cached results once the pack sizes were changed,
+// that is when err is nil
+func (s *packService) invalidateCache(err error) {
+	if err == nil && s.cache != nil {
+		s.cache.Invalidate()
+	}
+}
+
+// GetPackSizesSnapshot returns the configured pack sizes together with their version
+func (s *packService) GetPackSizesSnapshot() (model.PackSizesSnapshot, error) {
+	snapshot, err := s.repository.GetPackSizesSnapshot()
+	if err != nil {
+		return model.PackSizesSnapshot{}, fmt.Errorf("failed to get pack sizes: %w", wrapRepositoryError(err))
+	}
+	return snapshot, nil
+}
+
+// UpdatePackSizesIfMatch updates the pack sizes only if they are still at version,
+// so concurrent editors cannot silently overwrite each other
+func (s *packService) UpdatePackSizesIfMatch(sizes []int, version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	if err := model.ValidatePackSizes(sizes); err != nil {
+		return model.PackSizesSnapshot{}, err
+	}
+	snapshot, err := s.repository.CompareAndSetPackSizes(sizes, version, change)
+	s.invalidateCache(err)
+	return snapshot, wrapRepositoryError(err)
+}
+
+// ListPackSizesRevisions returns the history of the configured pack sizes, oldest first
+func (s *packService) ListPackSizesRevisions() ([]model.PackSizesRevision, error) {
+	revisions, err := s.repository.ListPackSizesRevisions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pack sizes revisions: %w", wrapRepositoryError(err))
+	}
+	return revisions, nil
+}
+
+// DiffPackSizesRevisions compares the pack sizes of two revisions
+func (s *packService) DiffPackSizesRevisions(from, to int) (*model.PackSizesDiff, error) {
+	fromRevision, err := s.repository.GetPackSizesRevision(from)
+	if err != nil {
+		return nil, wrapRepositoryError(err)
+	}
+	toRevision, err := s.repository.GetPackSizesRevision(to)
+	if err != nil {
+		return nil, wrapRepositoryError(err)
 	}
+	return model.NewPackSizesDiff(fromRevision, toRevision), nil
+}
+
+// RollbackPackSizes restores the pack sizes of an earlier revision
+// The rollback is recorded as a new revision, so it can be rolled back too
+func (s *packService) RollbackPackSizes(version int, change model.PackSizesChange) (model.PackSizesSnapshot, error) {
+	revision, err := s.repository.GetPackSizesRevision(version)
+	if err != nil {
+		return model.PackSizesSnapshot{}, wrapRepositoryError(err)
+	}
+	if len(revision.NewPackSizes) == 0 {
+		return model.PackSizesSnapshot{}, model.NewValidationError(fmt.Sprintf("revision %d has no pack sizes to restore", version))
+	}
+
+	change.RollbackOf = version
+	if change.Reason == "" {
+		change.Reason = fmt.Sprintf("rollback to revision %d", version)
+	}
+	snapshot, err := s.repository.UpdatePackSizes(revision.NewPackSizes, change)
+	s.invalidateCache(err)
+	return snapshot, wrapRepositoryError(err)
+}
+
+// GetStock returns the configured number of packs available per pack size
+func (s *packService) GetStock() (map[int]int, error) {
+	stock, err := s.repository.GetStock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", wrapRepositoryError(err))
+	}
+	return stock, nil
+}
+
+// UpdateStock updates the configured number of packs available per pack size
+// An empty stock removes all limits
+func (s *packService) UpdateStock(stock map[int]int) error {
+	if err := model.ValidateStock(stock); err != nil {
+		return err
+	}
+
+	return wrapRepositoryError(s.repository.SetStock(stock))
+}
+
+// GetPackCosts returns the configured cost of one pack per pack size
+func (s *packService) GetPackCosts() (map[int]int, error) {
+	costs, err := s.repository.GetPackCosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack costs: %w", wrapRepositoryError(err))
+	}
+	return costs, nil
+}
+
+// UpdatePackCosts updates the configured cost of one pack per pack size
+// An empty map removes all costs
+func (s *packService) UpdatePackCosts(costs map[int]int) error {
+	if err := model.ValidatePackCosts(costs); err != nil {
+		return err
+	}
+
+	return wrapRepositoryError(s.repository.SetPackCosts(costs))
+}
+
+// GetPolicies returns the policies that can be selected per request
+func (s *packService) GetPolicies() []calculator.Policy {
+	return calculator.Policies()
+}
+
+// GetCacheStats returns the statistics of the result cache
+// Enabled is false when the calculator does not cache results
+func (s *packService) GetCacheStats() calculator.CacheStats {
+	if s.cache == nil {
+		return calculator.CacheStats{}
+	}
+	return s.cache.Stats()
+}
+
+// ListProfiles returns all pack profiles, including the default one
+func (s *packService) ListProfiles() ([]model.PackProfile, error) {
+	profiles, err := s.repository.ListProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", wrapRepositoryError(err))
+	}
+	return profiles, nil
+}
+
+// GetProfile returns the pack profile with the given name
+func (s *packService) GetProfile(name string) (*model.PackProfile, error) {
+	profile, err := s.repository.GetProfile(name)
+	return profile, wrapRepositoryError(err)
+}
+
+// CreateProfile validates and stores a new pack profile
+func (s *packService) CreateProfile(profile *model.PackProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	return wrapRepositoryError(s.repository.CreateProfile(profile))
+}
+
+// UpdateProfile validates and replaces an existing pack profile
+func (s *packService) UpdateProfile(profile *model.PackProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	return wrapRepositoryError(s.repository.UpdateProfile(profile))
+}
 
-	return s.repository.SetPackSizes(sizes)
+// DeleteProfile removes a pack profile
+func (s *packService) DeleteProfile(name string) error {
+	return wrapRepositoryError(s.repository.DeleteProfile(name))
 }
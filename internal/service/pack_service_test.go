@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/marcellribeiro/awesomeProject/internal/model"
@@ -11,7 +14,7 @@ import (
 func TestPackService_CalculatePackDistribution(t *testing.T) {
 	repo := repository.NewInMemoryPackRepository()
 	calc := calculator.NewDynamicPackCalculator()
-	service := NewPackService(calc, repo)
+	service := NewPackService(calc, repo, nil, calculator.DefaultLimits())
 
 	// Setup default pack sizes for tests that don't provide custom sizes
 	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
@@ -47,7 +50,7 @@ func TestPackService_CalculatePackDistribution(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := service.CalculatePackDistribution(tt.request)
+			result, err := service.CalculatePackDistribution(context.Background(), tt.request)
 
 			if tt.wantError {
 				if err == nil {
@@ -72,3 +75,720 @@ func TestPackService_CalculatePackDistribution(t *testing.T) {
 		})
 	}
 }
+
+func TestPackService_CalculatePackDistribution_Provenance(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	calc := calculator.NewCachedPackCalculator(calculator.NewResiduePackCalculator(), 10, 0)
+	service := NewPackService(calc, repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500})
+	repo.SetPackSizes([]int{250, 500, 1000})
+	version := 2
+	if err := repo.CreateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{300}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		request       *model.PackRequest
+		wantVersion   *int
+		wantAlgorithm string
+	}{
+		{"Default pack sizes", &model.PackRequest{Quantity: 251}, &version, calculator.AlgorithmResidue},
+		{"Request pack sizes", &model.PackRequest{Quantity: 251, PackSizes: []int{100}}, nil, calculator.AlgorithmResidue},
+		{"Other profile", &model.PackRequest{Quantity: 251, Profile: "bolts"}, nil, calculator.AlgorithmResidue},
+		{"Stock", &model.PackRequest{Quantity: 251, Stock: map[int]int{500: 0}}, &version, calculator.AlgorithmBounded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.CalculatePackDistribution(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution() error = %v", err)
+			}
+			if !reflect.DeepEqual(response.PackSetVersion, tt.wantVersion) {
+				t.Errorf("PackSetVersion = %v, want %v", response.PackSetVersion, tt.wantVersion)
+			}
+			if response.Algorithm != tt.wantAlgorithm {
+				t.Errorf("Algorithm = %q, want %q", response.Algorithm, tt.wantAlgorithm)
+			}
+		})
+	}
+}
+
+func TestPackService_CalculatePackDistribution_WithStock(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	calc := calculator.NewDynamicPackCalculator()
+	service := NewPackService(calc, repo, nil, calculator.DefaultLimits())
+
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+	if err := service.UpdateStock(map[int]int{5000: 1}); err != nil {
+		t.Fatalf("UpdateStock() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		request   *model.PackRequest
+		wantItems int
+		wantPacks int
+		wantErr   error
+	}{
+		{
+			name:      "Configured stock",
+			request:   &model.PackRequest{Quantity: 12001},
+			wantItems: 12250,
+			wantPacks: 6,
+		},
+		{
+			name:      "Request stock overrides configured stock",
+			request:   &model.PackRequest{Quantity: 12001, Stock: map[int]int{5000: 2}},
+			wantItems: 12250,
+			wantPacks: 4,
+		},
+		{
+			name:      "Configured stock ignored for custom pack sizes",
+			request:   &model.PackRequest{Quantity: 12001, PackSizes: []int{1000, 5000}},
+			wantItems: 13000,
+			wantPacks: 5,
+		},
+		{
+			name:    "Insufficient stock",
+			request: &model.PackRequest{Quantity: 12001, PackSizes: []int{5000}, Stock: map[int]int{5000: 2}},
+			wantErr: calculator.ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculatePackDistribution(context.Background(), tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculatePackDistribution() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if result.TotalItems != tt.wantItems || result.TotalPacks != tt.wantPacks {
+				t.Errorf("CalculatePackDistribution() = items:%d packs:%d, want items:%d packs:%d",
+					result.TotalItems, result.TotalPacks, tt.wantItems, tt.wantPacks)
+			}
+		})
+	}
+}
+
+func TestPackService_UpdateStock(t *testing.T) {
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repository.NewInMemoryPackRepository(), nil, calculator.DefaultLimits())
+
+	if err := service.UpdateStock(map[int]int{250: -1}); err == nil {
+		t.Error("Expected error for negative stock")
+	}
+
+	if err := service.UpdateStock(map[int]int{250: 4}); err != nil {
+		t.Fatalf("UpdateStock() error = %v", err)
+	}
+
+	stock, err := service.GetStock()
+	if err != nil {
+		t.Fatalf("GetStock() error = %v", err)
+	}
+	if stock[250] != 4 {
+		t.Errorf("GetStock() = %v, want map[250:4]", stock)
+	}
+}
+
+func TestPackService_CalculatePackDistribution_WithCosts(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	repo.SetPackSizes([]int{250, 500, 1000})
+	if err := service.UpdatePackCosts(map[int]int{250: 10, 500: 30, 1000: 60}); err != nil {
+		t.Fatalf("UpdatePackCosts() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		request   *model.PackRequest
+		wantPacks int
+		wantCost  int
+	}{
+		{
+			name:      "Default objective keeps fewest packs",
+			request:   &model.PackRequest{Quantity: 1000},
+			wantPacks: 1,
+			wantCost:  60,
+		},
+		{
+			name:      "Cost objective uses configured costs",
+			request:   &model.PackRequest{Quantity: 1000, Objective: model.ObjectiveCost},
+			wantPacks: 4,
+			wantCost:  40,
+		},
+		{
+			name:      "Request costs override configured costs",
+			request:   &model.PackRequest{Quantity: 1000, Objective: model.ObjectiveCost, PackCosts: map[int]int{250: 100, 500: 1, 1000: 5}},
+			wantPacks: 2,
+			wantCost:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculatePackDistribution(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution() error = %v", err)
+			}
+			if result.TotalItems != 1000 || result.TotalPacks != tt.wantPacks || result.TotalCost != tt.wantCost {
+				t.Errorf("CalculatePackDistribution() = items:%d packs:%d cost:%d, want items:1000 packs:%d cost:%d",
+					result.TotalItems, result.TotalPacks, result.TotalCost, tt.wantPacks, tt.wantCost)
+			}
+		})
+	}
+
+	if err := service.UpdatePackCosts(map[int]int{250: -5}); err == nil {
+		t.Error("Expected error for negative cost")
+	}
+}
+
+func TestPackService_CalculatePackDistribution_CostBreaksPackTies(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{2, 3, 4})
+
+	// 2+4 and 3+3 both send 6 items in 2 packs; the fewest-packs policy takes the cheaper one
+	tests := []struct {
+		name          string
+		costs         map[int]int
+		wantBreakdown map[int]int
+		wantCost      int
+	}{
+		{"Cheaper pair", map[int]int{2: 10, 3: 1, 4: 10}, map[int]int{3: 2}, 2},
+		{"Other pair cheaper", map[int]int{2: 1, 3: 10, 4: 1}, map[int]int{2: 1, 4: 1}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{
+				Quantity:  6,
+				Policy:    calculator.PolicyFewestPacks.Name,
+				PackCosts: tt.costs,
+			})
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.PackBreakdown, tt.wantBreakdown) || result.TotalCost != tt.wantCost {
+				t.Errorf("CalculatePackDistribution() = %v cost %d, want %v cost %d",
+					result.PackBreakdown, result.TotalCost, tt.wantBreakdown, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestPackService_CalculatePackDistribution_WithPolicy(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000})
+
+	tests := []struct {
+		name       string
+		request    *model.PackRequest
+		wantItems  int
+		wantPacks  int
+		wantPolicy string
+		wantErr    bool
+	}{
+		{
+			name:       "Default policy",
+			request:    &model.PackRequest{Quantity: 1750},
+			wantItems:  1750,
+			wantPacks:  3,
+			wantPolicy: "default",
+		},
+		{
+			name:       "Fewest packs policy",
+			request:    &model.PackRequest{Quantity: 1750, Policy: "fewest-packs"},
+			wantItems:  2000,
+			wantPacks:  2,
+			wantPolicy: "fewest-packs",
+		},
+		{
+			name:       "Overage limit on named policy",
+			request:    &model.PackRequest{Quantity: 1750, Policy: "fewest-packs", MaxOveragePercent: 10},
+			wantItems:  1750,
+			wantPacks:  3,
+			wantPolicy: "fewest-packs",
+		},
+		{
+			name:       "Cost objective selects cost policy",
+			request:    &model.PackRequest{Quantity: 1750, Objective: model.ObjectiveCost},
+			wantItems:  1750,
+			wantPacks:  3,
+			wantPolicy: "cost",
+		},
+		{
+			name:    "Unknown policy",
+			request: &model.PackRequest{Quantity: 1750, Policy: "cheapest-shipping"},
+			wantErr: true,
+		},
+		{
+			name:    "Overage limit cannot be met",
+			request: &model.PackRequest{Quantity: 1001, MaxOveragePercent: 10},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculatePackDistribution(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CalculatePackDistribution() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.TotalItems != tt.wantItems || result.TotalPacks != tt.wantPacks || result.Policy != tt.wantPolicy {
+				t.Errorf("CalculatePackDistribution() = items:%d packs:%d policy:%s, want items:%d packs:%d policy:%s",
+					result.TotalItems, result.TotalPacks, result.Policy, tt.wantItems, tt.wantPacks, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestPackService_CalculatePackDistribution_PolicyCosts(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{3, 5, 7})
+	if err := service.UpdatePackCosts(map[int]int{3: 100, 5: 1, 7: 100}); err != nil {
+		t.Fatalf("UpdatePackCosts() error = %v", err)
+	}
+
+	// 3+7 and 5+5 both send 10 items in 2 packs, so every policy ranking cost takes 5+5
+	for _, policy := range []string{"cost", "fewest-packs"} {
+		t.Run(policy, func(t *testing.T) {
+			result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 10, Policy: policy})
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution() error = %v", err)
+			}
+			if want := map[int]int{5: 2}; !reflect.DeepEqual(result.PackBreakdown, want) || result.TotalCost != 2 {
+				t.Errorf("CalculatePackDistribution() = %v cost %d, want %v cost 2", result.PackBreakdown, result.TotalCost, want)
+			}
+		})
+	}
+
+	// The default policy does not rank costs, even when stock sends it to the bounded
+	// calculator, so swapping the cheap and dear sizes keeps its breakdown
+	var breakdowns []map[int]int
+	for _, costs := range []map[int]int{{3: 100, 5: 1, 7: 100}, {3: 1, 5: 100, 7: 1}} {
+		result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{
+			Quantity:  10,
+			Stock:     map[int]int{7: 5},
+			PackCosts: costs,
+		})
+		if err != nil {
+			t.Fatalf("CalculatePackDistribution() error = %v", err)
+		}
+		breakdowns = append(breakdowns, result.PackBreakdown)
+	}
+	if !reflect.DeepEqual(breakdowns[0], breakdowns[1]) {
+		t.Errorf("Default policy breakdowns = %v and %v, want the same for any costs", breakdowns[0], breakdowns[1])
+	}
+}
+
+func TestPackService_CalculatePackDistribution_CostsKeepConfiguredCalculator(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewResiduePackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+	if err := service.UpdatePackCosts(map[int]int{250: 1, 500: 2, 1000: 3, 2000: 4, 5000: 5}); err != nil {
+		t.Fatalf("UpdatePackCosts() error = %v", err)
+	}
+
+	// Profile costs are only reported by the default policy, so a quantity far beyond
+	// the bounded calculator's tables is still served by the residue calculator
+	result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 500_000_001})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if result.Algorithm != calculator.AlgorithmResidue || result.TotalItems != 500_000_250 || result.TotalCost == 0 {
+		t.Errorf("CalculatePackDistribution() = %s items:%d cost:%d, want residue items:500000250 with a cost",
+			result.Algorithm, result.TotalItems, result.TotalCost)
+	}
+
+	if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 251, Explain: true}); err != nil {
+		t.Errorf("CalculatePackDistribution() with explain and profile costs error = %v", err)
+	}
+}
+
+func TestPackService_CalculateOrder(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+
+	order := &model.OrderRequest{
+		OrderID: "SO-1",
+		Lines: []model.OrderLine{
+			{SKU: "widget", PackRequest: model.PackRequest{Quantity: 501}},
+			{SKU: "bolt", PackRequest: model.PackRequest{Quantity: 120, PackSizes: []int{50, 100}}},
+			{SKU: "", PackRequest: model.PackRequest{Quantity: 10}},
+			{SKU: "nut", PackRequest: model.PackRequest{Quantity: 0}},
+			{SKU: "gear", PackRequest: model.PackRequest{Quantity: 2001, PackSizes: []int{1000}, Stock: map[int]int{1000: 1}}},
+		},
+	}
+
+	response, err := service.CalculateOrder(context.Background(), order)
+	if err != nil {
+		t.Fatalf("CalculateOrder() error = %v", err)
+	}
+
+	if response.OrderID != "SO-1" || len(response.Lines) != 5 {
+		t.Fatalf("CalculateOrder() = %+v, want 5 lines for SO-1", response)
+	}
+
+	wantFailed := []bool{false, false, true, true, true}
+	for i, line := range response.Lines {
+		if failed := line.Error != ""; failed != wantFailed[i] {
+			t.Errorf("Line %d error = %q, want failed %v", i, line.Error, wantFailed[i])
+		}
+		if (line.Result == nil) != wantFailed[i] {
+			t.Errorf("Line %d result = %v, want failed %v", i, line.Result, wantFailed[i])
+		}
+	}
+
+	want := model.OrderTotals{Lines: 5, FailedLines: 3, TotalQuantity: 621, TotalItems: 900, TotalPacks: 4}
+	if response.Totals != want {
+		t.Errorf("Totals = %+v, want %+v", response.Totals, want)
+	}
+
+	if _, err := service.CalculateOrder(context.Background(), &model.OrderRequest{}); err == nil {
+		t.Error("Expected error for order without lines")
+	}
+}
+
+func TestPackService_Profiles(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+
+	if err := service.CreateProfile(&model.PackProfile{Name: "bolts"}); !model.IsValidationError(err) {
+		t.Errorf("CreateProfile() without sizes error = %v, want validation error", err)
+	}
+	if err := service.CreateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{50, 100}, Stock: map[int]int{100: 1}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 180, Profile: "bolts"})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if result.TotalItems != 200 || result.PackBreakdown[100] != 1 || result.PackBreakdown[50] != 2 {
+		t.Errorf("CalculatePackDistribution() with profile = %+v, want 100x1 + 50x2", result)
+	}
+
+	// The default profile is untouched by the bolts profile
+	result, err = service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 180})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if result.TotalItems != 250 {
+		t.Errorf("CalculatePackDistribution() with default profile = %+v, want 250 items", result)
+	}
+
+	if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 180, Profile: "nuts"}); !errors.Is(err, model.ErrProfileNotFound) {
+		t.Errorf("CalculatePackDistribution() unknown profile error = %v, want %v", err, model.ErrProfileNotFound)
+	}
+
+	if err := service.UpdateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{-5}}); !model.IsValidationError(err) {
+		t.Errorf("UpdateProfile() invalid sizes error = %v, want validation error", err)
+	}
+	if err := service.DeleteProfile("bolts"); err != nil {
+		t.Errorf("DeleteProfile() error = %v", err)
+	}
+}
+
+func TestPackService_UpdatePackSizesIfMatch(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	snapshot, err := service.GetPackSizesSnapshot()
+	if err != nil {
+		t.Fatalf("GetPackSizesSnapshot() error = %v", err)
+	}
+
+	if _, err := service.UpdatePackSizesIfMatch([]int{250, -1}, snapshot.Version, model.PackSizesChange{}); err == nil {
+		t.Error("Expected error for invalid pack sizes")
+	}
+
+	updated, err := service.UpdatePackSizesIfMatch([]int{500, 250}, snapshot.Version, model.PackSizesChange{})
+	if err != nil {
+		t.Fatalf("UpdatePackSizesIfMatch() error = %v", err)
+	}
+	if updated.Version == snapshot.Version {
+		t.Errorf("Version did not change after update: %+v", updated)
+	}
+
+	if _, err := service.UpdatePackSizesIfMatch([]int{1000}, snapshot.Version, model.PackSizesChange{}); !errors.Is(err, model.ErrVersionConflict) {
+		t.Errorf("UpdatePackSizesIfMatch() stale error = %v, want %v", err, model.ErrVersionConflict)
+	}
+}
+
+func TestPackService_PackSizesHistory(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+
+	service.UpdatePackSizes([]int{250, 500, 1000}, model.PackSizesChange{Author: "alice"})
+	service.UpdatePackSizes([]int{250, 750}, model.PackSizesChange{Author: "bob", Reason: "new supplier"})
+
+	diff, err := service.DiffPackSizesRevisions(1, 2)
+	if err != nil {
+		t.Fatalf("DiffPackSizesRevisions() error = %v", err)
+	}
+	if !reflect.DeepEqual(diff.Added, []int{750}) || !reflect.DeepEqual(diff.Removed, []int{500, 1000}) {
+		t.Errorf("DiffPackSizesRevisions() = %+v, want added [750] removed [500 1000]", diff)
+	}
+	if _, err := service.DiffPackSizesRevisions(1, 7); !errors.Is(err, model.ErrRevisionNotFound) {
+		t.Errorf("DiffPackSizesRevisions() missing error = %v, want %v", err, model.ErrRevisionNotFound)
+	}
+
+	snapshot, err := service.RollbackPackSizes(1, model.PackSizesChange{Author: "carol"})
+	if err != nil {
+		t.Fatalf("RollbackPackSizes() error = %v", err)
+	}
+	if snapshot.Version != 3 || !reflect.DeepEqual(snapshot.PackSizes, []int{250, 500, 1000}) {
+		t.Errorf("RollbackPackSizes() = %+v, want version 3 with [250 500 1000]", snapshot)
+	}
+
+	revisions, err := service.ListPackSizesRevisions()
+	if err != nil {
+		t.Fatalf("ListPackSizesRevisions() error = %v", err)
+	}
+	last := revisions[len(revisions)-1]
+	if last.RollbackOf != 1 || last.Author != "carol" || last.Reason != "rollback to revision 1" {
+		t.Errorf("Rollback revision = %+v", last)
+	}
+	if !reflect.DeepEqual(last.OldPackSizes, []int{250, 750}) {
+		t.Errorf("Rollback revision old sizes = %v, want [250 750]", last.OldPackSizes)
+	}
+
+	if _, err := service.RollbackPackSizes(9, model.PackSizesChange{}); !errors.Is(err, model.ErrRevisionNotFound) {
+		t.Errorf("RollbackPackSizes() missing error = %v, want %v", err, model.ErrRevisionNotFound)
+	}
+}
+
+func TestPackService_CalculatePackDistribution_WithAlternatives(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewResiduePackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+
+	result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 1000, Alternatives: 3})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+
+	want := []model.PackAlternative{
+		{Rank: 1, PackBreakdown: map[int]int{1000: 1}, TotalItems: 1000, TotalPacks: 1},
+		{Rank: 2, PackBreakdown: map[int]int{500: 2}, TotalItems: 1000, TotalPacks: 2},
+		{Rank: 3, PackBreakdown: map[int]int{500: 1, 250: 2}, TotalItems: 1000, TotalPacks: 3},
+	}
+	if !reflect.DeepEqual(result.Alternatives, want) {
+		t.Errorf("Alternatives = %+v, want %+v", result.Alternatives, want)
+	}
+
+	// Stock and policies rank the alternatives like the breakdown
+	ranked := []struct {
+		request *model.PackRequest
+		want    []map[int]int
+	}{
+		{&model.PackRequest{Quantity: 1000, Alternatives: 2, Stock: map[int]int{1000: 0}}, []map[int]int{{500: 2}, {500: 1, 250: 2}}},
+		{&model.PackRequest{Quantity: 1000, Alternatives: 3, Policy: "fewest-packs"}, []map[int]int{{1000: 1}, {2000: 1}, {5000: 1}}},
+	}
+	for _, tt := range ranked {
+		result, err := service.CalculatePackDistribution(context.Background(), tt.request)
+		if err != nil {
+			t.Fatalf("CalculatePackDistribution(%+v) error = %v", tt.request, err)
+		}
+		var got []map[int]int
+		for _, alternative := range result.Alternatives {
+			got = append(got, alternative.PackBreakdown)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CalculatePackDistribution(%+v) alternatives = %v, want %v", tt.request, got, tt.want)
+		}
+	}
+
+	invalid := []*model.PackRequest{
+		{Quantity: 1000, Alternatives: calculator.MaxAlternatives + 1},
+		{Quantity: 1000, Alternatives: -1},
+	}
+	for _, request := range invalid {
+		if _, err := service.CalculatePackDistribution(context.Background(), request); !model.IsValidationError(err) {
+			t.Errorf("CalculatePackDistribution(%+v) error = %v, want validation error", request, err)
+		}
+	}
+
+	result, _ = service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 1000})
+	if result.Alternatives != nil {
+		t.Errorf("Alternatives without asking = %+v, want none", result.Alternatives)
+	}
+}
+
+func TestPackService_CalculatePackDistribution_AlternativesStartWithBreakdown(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewResiduePackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{3, 5, 7, 9})
+
+	// The residue calculator may break ties unlike the enumeration
+	for quantity := 1; quantity <= 60; quantity++ {
+		for _, policy := range []string{"default", "cost"} {
+			result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{
+				Quantity:     quantity,
+				Policy:       policy,
+				Alternatives: 3,
+			})
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution(%d, %s) error = %v", quantity, policy, err)
+			}
+			if first := result.Alternatives[0]; first.Rank != 1 || !reflect.DeepEqual(first.PackBreakdown, result.PackBreakdown) {
+				t.Fatalf("CalculatePackDistribution(%d, %s) first alternative = %+v, want breakdown %v",
+					quantity, policy, first, result.PackBreakdown)
+			}
+		}
+	}
+}
+
+func TestPackService_CalculatePackDistribution_WithExplanation(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000, 2000, 5000})
+
+	result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 251, Explain: true})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if result.Explanation == nil {
+		t.Fatal("Expected an explanation")
+	}
+	if result.Explanation.MinimumAmount != result.TotalItems {
+		t.Errorf("MinimumAmount = %d, want %d", result.Explanation.MinimumAmount, result.TotalItems)
+	}
+	wantCandidate := model.AmountCandidate{From: 251, To: 499, Reason: "no combination of pack sizes adds up to these amounts"}
+	if len(result.Explanation.Candidates) == 0 || result.Explanation.Candidates[0] != wantCandidate {
+		t.Errorf("Candidates = %+v, want %+v first", result.Explanation.Candidates, wantCandidate)
+	}
+	for _, option := range result.Explanation.PackCounts {
+		if option.Chosen && !reflect.DeepEqual(option.PackBreakdown, result.PackBreakdown) {
+			t.Errorf("Chosen pack count breakdown = %v, want %v", option.PackBreakdown, result.PackBreakdown)
+		}
+	}
+
+	invalid := []*model.PackRequest{
+		{Quantity: 1000, Explain: true, Stock: map[int]int{1000: 1}},
+		{Quantity: 1000, Explain: true, Policy: "cost"},
+	}
+	for _, request := range invalid {
+		if _, err := service.CalculatePackDistribution(context.Background(), request); !model.IsValidationError(err) {
+			t.Errorf("CalculatePackDistribution(%+v) error = %v, want validation error", request, err)
+		}
+	}
+
+	result, _ = service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 1000})
+	if result.Explanation != nil {
+		t.Errorf("Explanation without asking = %+v, want none", result.Explanation)
+	}
+}
+
+// calculatorFunc adapts a function to calculator.PackCalculator
+type calculatorFunc func(ctx context.Context, quantity int, packSizes []int) (map[int]int, error)
+
+func (f calculatorFunc) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	return f(ctx, quantity, packSizes)
+}
+
+func TestPackService_CalculatePackDistribution_ExplanationMatchesBreakdown(t *testing.T) {
+	// 6 items are 2x3 or 1x2 + 1x4; the configured calculator breaks the tie
+	// differently from the traced one
+	tieBreaker := calculatorFunc(func(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+		return map[int]int{3: 2}, nil
+	})
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(tieBreaker, repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{2, 3, 4})
+
+	result, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 6, Explain: true})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if result.Algorithm != calculator.AlgorithmDynamic {
+		t.Errorf("Algorithm = %q, want %q", result.Algorithm, calculator.AlgorithmDynamic)
+	}
+	var chosen []map[int]int
+	for _, option := range result.Explanation.PackCounts {
+		if option.Chosen {
+			chosen = append(chosen, option.PackBreakdown)
+		}
+	}
+	if len(chosen) != 1 || !reflect.DeepEqual(chosen[0], result.PackBreakdown) {
+		t.Errorf("Chosen pack count breakdowns = %v, want only %v", chosen, result.PackBreakdown)
+	}
+	if want := map[int]int{2: 1, 4: 1}; !reflect.DeepEqual(result.PackBreakdown, want) {
+		t.Errorf("PackBreakdown = %v, want the traced %v", result.PackBreakdown, want)
+	}
+
+	// Without explain the configured calculator decides
+	result, err = service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 6})
+	if err != nil {
+		t.Fatalf("CalculatePackDistribution() error = %v", err)
+	}
+	if want := map[int]int{3: 2}; !reflect.DeepEqual(result.PackBreakdown, want) {
+		t.Errorf("PackBreakdown without explain = %v, want %v", result.PackBreakdown, want)
+	}
+}
+
+func TestPackService_Cache(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250, 500, 1000})
+	cache := calculator.NewCachedPackCalculator(calculator.NewDynamicPackCalculator(), 10, 0)
+	service := NewPackService(cache, repo, nil, calculator.DefaultLimits())
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 501}); err != nil {
+			t.Fatalf("CalculatePackDistribution() error = %v", err)
+		}
+	}
+	if stats := service.GetCacheStats(); !stats.Enabled || stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("GetCacheStats() = %+v, want 2 hits and 1 miss", stats)
+	}
+
+	// A rejected update keeps the cache
+	if err := service.UpdatePackSizes([]int{-1}, model.PackSizesChange{}); err == nil {
+		t.Fatal("UpdatePackSizes() expected a validation error")
+	}
+	if stats := service.GetCacheStats(); stats.Invalidations != 0 || stats.Entries != 1 {
+		t.Errorf("GetCacheStats() after a rejected update = %+v, want the entry kept", stats)
+	}
+
+	updates := []func() error{
+		func() error { return service.UpdatePackSizes([]int{250, 500}, model.PackSizesChange{}) },
+		func() error {
+			snapshot, _ := service.GetPackSizesSnapshot()
+			_, err := service.UpdatePackSizesIfMatch([]int{300}, snapshot.Version, model.PackSizesChange{})
+			return err
+		},
+		func() error {
+			_, err := service.RollbackPackSizes(1, model.PackSizesChange{})
+			return err
+		},
+	}
+	for i, update := range updates {
+		service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 501})
+		if err := update(); err != nil {
+			t.Fatalf("Update %d error = %v", i, err)
+		}
+		if stats := service.GetCacheStats(); stats.Invalidations != uint64(i+1) || stats.Entries != 0 {
+			t.Errorf("GetCacheStats() after update %d = %+v, want the cache invalidated", i, stats)
+		}
+	}
+}
+
+func TestPackService_GetCacheStats_Disabled(t *testing.T) {
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repository.NewInMemoryPackRepository(), nil, calculator.DefaultLimits())
+
+	if stats := service.GetCacheStats(); stats.Enabled {
+		t.Errorf("GetCacheStats() = %+v, want the cache disabled", stats)
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+)
+
+// recordQuote stores a successful calculation as a quote and sets its ID on response
+// The revision of the default pack sizes is recorded when the calculation used them
+func (s *packService) recordQuote(request *model.PackRequest, profile string, response *model.PackResponse) error {
+	if s.quotes == nil {
+		return nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to create quote: %w", err)
+	}
+	quote := &model.Quote{
+		ID:               id,
+		CreatedAt:        time.Now().UTC(),
+		Profile:          profile,
+		PackSizesVersion: response.PackSetVersion,
+		Request:          *request,
+		Response:         *response,
+	}
+
+	if err := s.quotes.SaveQuote(quote); err != nil {
+		return fmt.Errorf("failed to record quote: %w", wrapRepositoryError(err))
+	}
+	response.QuoteID = id
+	return nil
+}
+
+// GetQuote returns the quote with id
+func (s *packService) GetQuote(id string) (*model.Quote, error) {
+	if s.quotes == nil {
+		return nil, fmt.Errorf("%w: %s", model.ErrQuoteNotFound, id)
+	}
+	quote, err := s.quotes.GetQuote(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote: %w", wrapRepositoryError(err))
+	}
+	return quote, nil
+}
+
+// ListQuotes returns the page of quotes matching filter, newest first
+// A zero limit lists model.DefaultQuoteLimit quotes
+func (s *packService) ListQuotes(filter model.QuoteFilter) (*model.QuoteList, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = model.DefaultQuoteLimit
+	}
+
+	list := &model.QuoteList{Quotes: []model.Quote{}, Limit: filter.Limit, Offset: filter.Offset}
+	if s.quotes == nil {
+		return list, nil
+	}
+	quotes, total, err := s.quotes.ListQuotes(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quotes: %w", wrapRepositoryError(err))
+	}
+	list.Quotes, list.Total = quotes, total
+	return list, nil
+}
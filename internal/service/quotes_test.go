@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+// failingQuoteRepository fails every quote operation
+type failingQuoteRepository struct{}
+
+func (failingQuoteRepository) SaveQuote(*model.Quote) error { return errors.New("disk full") }
+
+func (failingQuoteRepository) GetQuote(string) (*model.Quote, error) {
+	return nil, errors.New("disk full")
+}
+
+func (failingQuoteRepository) ListQuotes(model.QuoteFilter) ([]model.Quote, int, error) {
+	return nil, 0, errors.New("disk full")
+}
+
+func TestPackService_RecordsQuotes(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	quotes := repository.NewInMemoryQuoteRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, quotes, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500, 1000})
+	if err := repo.CreateProfile(&model.PackProfile{Name: "bolts", PackSizes: []int{300}}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		request     *model.PackRequest
+		wantProfile string
+		wantVersion bool
+	}{
+		{"Default pack sizes", &model.PackRequest{Quantity: 251}, model.DefaultProfile, true},
+		{"Request pack sizes", &model.PackRequest{Quantity: 251, PackSizes: []int{100}}, model.DefaultProfile, false},
+		{"Other profile", &model.PackRequest{Quantity: 251, Profile: "bolts"}, "bolts", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.CalculatePackDistribution(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("CalculatePackDistribution() error = %v", err)
+			}
+			if response.QuoteID == "" {
+				t.Fatal("CalculatePackDistribution() returned no quote ID")
+			}
+
+			quote, err := service.GetQuote(response.QuoteID)
+			if err != nil {
+				t.Fatalf("GetQuote() error = %v", err)
+			}
+			if quote.Profile != tt.wantProfile || quote.Request.Quantity != tt.request.Quantity {
+				t.Errorf("GetQuote() = %s for %d, want %s for %d", quote.Profile, quote.Request.Quantity, tt.wantProfile, tt.request.Quantity)
+			}
+			if quote.Response.TotalItems != response.TotalItems || quote.Response.QuoteID != "" {
+				t.Errorf("GetQuote() response = %+v, want %+v without its quote ID", quote.Response, response)
+			}
+			if got := quote.PackSizesVersion != nil && *quote.PackSizesVersion == 1; got != tt.wantVersion {
+				t.Errorf("GetQuote() pack sizes version = %v, want version 1: %v", quote.PackSizesVersion, tt.wantVersion)
+			}
+		})
+	}
+
+	// Failed calculations are not quotes
+	if _, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 1, Profile: "nuts"}); err == nil {
+		t.Fatal("CalculatePackDistribution() with a missing profile expected error, got nil")
+	}
+	list, err := service.ListQuotes(model.QuoteFilter{})
+	if err != nil {
+		t.Fatalf("ListQuotes() error = %v", err)
+	}
+	if list.Total != len(tests) || list.Limit != model.DefaultQuoteLimit {
+		t.Errorf("ListQuotes() = %d quotes with limit %d, want %d with limit %d", list.Total, list.Limit, len(tests), model.DefaultQuoteLimit)
+	}
+}
+
+func TestPackService_QuotedCalculations(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	quotes := repository.NewInMemoryQuoteRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, quotes, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250, 500})
+
+	// Order lines are quotes to a customer
+	order, err := service.CalculateOrder(context.Background(), &model.OrderRequest{
+		OrderID: "SO-1",
+		Lines: []model.OrderLine{
+			{SKU: "bolt", PackRequest: model.PackRequest{Quantity: 251}},
+			{SKU: "nut", PackRequest: model.PackRequest{Quantity: 501}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CalculateOrder() error = %v", err)
+	}
+	for _, line := range order.Lines {
+		if line.Result == nil || line.Result.QuoteID == "" {
+			t.Errorf("Order line %s = %+v, want a quote ID", line.SKU, line.Result)
+		}
+	}
+
+	// Batches plan quantities in bulk and record nothing
+	requests := []BatchRequest{{Request: &model.PackRequest{Quantity: 1}}, {Request: &model.PackRequest{Quantity: 751}}}
+	err = service.CalculateBatch(context.Background(), BatchOf(requests), func(result model.BatchItemResult) error {
+		if result.Result == nil || result.Result.QuoteID != "" {
+			t.Errorf("Batch result %d = %+v, want one without a quote ID", result.Index, result.Result)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CalculateBatch() error = %v", err)
+	}
+
+	if list, _ := service.ListQuotes(model.QuoteFilter{}); list.Total != len(order.Lines) {
+		t.Errorf("ListQuotes() = %d quotes, want %d", list.Total, len(order.Lines))
+	}
+}
+
+func TestPackService_QuoteErrors(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	repo.SetPackSizes([]int{250})
+
+	tests := []struct {
+		name     string
+		quotes   repository.QuoteRepository
+		call     func(service PackService) error
+		wantCode string
+	}{
+		{
+			name:     "Missing quote",
+			quotes:   repository.NewInMemoryQuoteRepository(),
+			call:     func(service PackService) error { _, err := service.GetQuote("missing"); return err },
+			wantCode: model.CodeQuoteNotFound,
+		},
+		{
+			name:     "No quote repository",
+			call:     func(service PackService) error { _, err := service.GetQuote("missing"); return err },
+			wantCode: model.CodeQuoteNotFound,
+		},
+		{
+			name:   "Invalid filter",
+			quotes: repository.NewInMemoryQuoteRepository(),
+			call: func(service PackService) error {
+				_, err := service.ListQuotes(model.QuoteFilter{Offset: -1})
+				return err
+			},
+			wantCode: model.CodeValidationFailed,
+		},
+		{
+			name:   "Quote not recorded",
+			quotes: failingQuoteRepository{},
+			call: func(service PackService) error {
+				_, err := service.CalculatePackDistribution(context.Background(), &model.PackRequest{Quantity: 1})
+				return err
+			},
+			wantCode: model.CodeRepositoryFailure,
+		},
+		{
+			name:     "Listing fails",
+			quotes:   failingQuoteRepository{},
+			call:     func(service PackService) error { _, err := service.ListQuotes(model.QuoteFilter{}); return err },
+			wantCode: model.CodeRepositoryFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewPackService(calculator.NewDynamicPackCalculator(), repo, tt.quotes, calculator.DefaultLimits())
+			if code := ErrorCode(tt.call(service)); code != tt.wantCode {
+				t.Errorf("ErrorCode() = %s, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
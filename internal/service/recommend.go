@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+	log "github.com/sirupsen/logrus"
+)
+
+// Bounds of the recommendation jobs kept by a service
+const (
+	// maxRecommendationJobs is how many jobs are kept; the oldest finished ones go first
+	maxRecommendationJobs = 100
+	// maxRunningRecommendations is how many jobs search at once
+	maxRunningRecommendations = 2
+	// recommendationTimeout stops a search that runs too long
+	recommendationTimeout = 10 * time.Minute
+	// recommendationTableCells bounds the pack table kept while a job evaluates one set
+	recommendationTableCells = 1 << 22
+)
+
+// recommendationJobs holds the recommendation jobs of a service
+type recommendationJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*model.RecommendationJob
+	order   []string // job IDs, oldest first
+	running int
+}
+
+func newRecommendationJobs() *recommendationJobs {
+	return &recommendationJobs{jobs: make(map[string]*model.RecommendationJob)}
+}
+
+// add registers a queued job, dropping the oldest finished jobs beyond the limit
+// It fails with model.ErrTooManyJobs when every slot is taken
+func (j *recommendationJobs) add(job *model.RecommendationJob) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running >= maxRunningRecommendations {
+		return model.ErrTooManyJobs
+	}
+	for i := 0; len(j.order) >= maxRecommendationJobs && i < len(j.order); {
+		if old := j.jobs[j.order[i]]; jobFinished(old) {
+			delete(j.jobs, old.ID)
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+
+	j.running++
+	j.jobs[job.ID] = job
+	j.order = append(j.order, job.ID)
+	return nil
+}
+
+// update changes the job with id while holding the lock
+// A job changed to a finished status frees its running slot
+func (j *recommendationJobs) update(id string, change func(job *model.RecommendationJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return
+	}
+	wasFinished := jobFinished(job)
+	change(job)
+	if !wasFinished && jobFinished(job) {
+		j.running--
+	}
+}
+
+// get returns a copy of the job with id
+func (j *recommendationJobs) get(id string) (*model.RecommendationJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrJobNotFound, id)
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// jobFinished reports whether job succeeded or failed
+func jobFinished(job *model.RecommendationJob) bool {
+	return job.Status == model.JobSucceeded || job.Status == model.JobFailed
+}
+
+// StartRecommendation validates the request and starts searching for the pack size set
+// packing its orders best in the background
+// The returned job is queued; GetRecommendation reports its progress and result
+func (s *packService) StartRecommendation(request *model.RecommendationRequest) (*model.RecommendationJob, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if request.History != nil {
+		orders, err := s.historyOrders(*request.History)
+		if err != nil {
+			return nil, err
+		}
+		resolved := *request
+		resolved.Orders = orders
+		request = &resolved
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	maxEvaluations := request.MaxEvaluations
+	if maxEvaluations == 0 {
+		maxEvaluations = calculator.DefaultMaxEvaluations
+	}
+	job := &model.RecommendationJob{
+		ID:             id,
+		Status:         model.JobQueued,
+		CreatedAt:      time.Now().UTC(),
+		MaxEvaluations: maxEvaluations,
+	}
+	if err := s.jobs.add(job); err != nil {
+		return nil, err
+	}
+	snapshot := *job
+
+	go s.runRecommendation(id, request)
+	return &snapshot, nil
+}
+
+// historyOrders counts the quantities of every quote matching filter, in increasing order
+// Limit and Offset are ignored, so the whole matching history is counted
+func (s *packService) historyOrders(filter model.QuoteFilter) ([]model.OrderQuantity, error) {
+	var quotes []model.Quote
+	if s.quotes != nil {
+		filter.Limit, filter.Offset = 0, 0
+		var err error
+		if quotes, _, err = s.quotes.ListQuotes(filter); err != nil {
+			return nil, fmt.Errorf("failed to list quotes: %w", wrapRepositoryError(err))
+		}
+	}
+
+	counts := make(map[int]int)
+	for _, quote := range quotes {
+		counts[quote.Request.Quantity]++
+	}
+	switch {
+	case len(counts) == 0:
+		return nil, model.NewFieldError("history", "matches no quotes")
+	case len(counts) > model.MaxRecommendationOrders:
+		return nil, model.NewFieldError("history", fmt.Sprintf("matches more than %d quantities, got: %d", model.MaxRecommendationOrders, len(counts)))
+	}
+
+	orders := make([]model.OrderQuantity, 0, len(counts))
+	for quantity, count := range counts {
+		orders = append(orders, model.OrderQuantity{Quantity: quantity, Count: count})
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Quantity < orders[j].Quantity })
+	return orders, nil
+}
+
+// GetRecommendation returns the recommendation job with id
+func (s *packService) GetRecommendation(id string) (*model.RecommendationJob, error) {
+	return s.jobs.get(id)
+}
+
+// runRecommendation runs the search of a job and records its outcome
+func (s *packService) runRecommendation(id string, request *model.RecommendationRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), recommendationTimeout)
+	defer cancel()
+
+	s.jobs.update(id, func(job *model.RecommendationJob) {
+		started := time.Now().UTC()
+		job.Status, job.StartedAt = model.JobRunning, &started
+	})
+
+	result, err := s.recommend(ctx, request, func(evaluated int) {
+		s.jobs.update(id, func(job *model.RecommendationJob) { job.Evaluated = evaluated })
+	})
+
+	s.jobs.update(id, func(job *model.RecommendationJob) {
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status, job.Error, job.Code = model.JobFailed, err.Error(), ErrorCode(err)
+			return
+		}
+		job.Status, job.Result = model.JobSucceeded, result
+	})
+	if err != nil {
+		log.Errorf("Recommendation %s failed: %v", id, err)
+	}
+}
+
+// recommend searches the candidates of the request for the best set and evaluates the
+// current default pack sizes alongside
+// A job gets its own table store, so each set evaluated builds one table for the
+// largest order and answers the smaller ones from it
+func (s *packService) recommend(ctx context.Context, request *model.RecommendationRequest, progress func(int)) (*model.RecommendationResult, error) {
+	calc := s.alternativeCalculator.WithTables(calculator.NewTableStore(calculator.TableStoreOptions{
+		MaxCells: recommendationTableCells,
+		MaxSets:  1,
+	}))
+
+	orders := make([]calculator.QuantityCount, len(request.Orders))
+	for i, order := range request.Orders {
+		orders[i] = calculator.QuantityCount{Quantity: order.Quantity, Count: max(order.Count, 1)}
+	}
+	opts := calculator.RecommendOptions{
+		Candidates:     request.Candidates(),
+		MaxSizes:       request.MaxSizes,
+		OverageWeight:  request.OverageWeight,
+		PackWeight:     request.PackWeight,
+		MaxEvaluations: request.MaxEvaluations,
+		Progress:       progress,
+	}
+
+	current, err := s.repository.GetAllPackSizes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack sizes: %w", wrapRepositoryError(err))
+	}
+	opts.Baseline = current
+
+	recommendation, err := calculator.Recommend(ctx, calc, orders, opts)
+	if err != nil {
+		return nil, fmt.Errorf("recommendation failed: %w", err)
+	}
+
+	result := &model.RecommendationResult{
+		Recommended: toPackSetEvaluation(recommendation.Best),
+		Candidates:  opts.Candidates,
+		Exhaustive:  recommendation.Exhaustive,
+	}
+	if recommendation.Baseline != nil {
+		current := toPackSetEvaluation(*recommendation.Baseline)
+		result.Current = &current
+		result.Change = &model.EvaluationChange{
+			TotalItems:   result.Recommended.TotalItems - current.TotalItems,
+			TotalOverage: result.Recommended.TotalOverage - current.TotalOverage,
+			TotalPacks:   result.Recommended.TotalPacks - current.TotalPacks,
+		}
+	}
+	return result, nil
+}
+
+// toPackSetEvaluation converts a calculator evaluation to its API model
+func toPackSetEvaluation(evaluation calculator.PackSetEvaluation) model.PackSetEvaluation {
+	response := model.PackSetEvaluation{
+		PackSizes:    evaluation.PackSizes,
+		Orders:       evaluation.Orders,
+		TotalItems:   evaluation.TotalItems,
+		TotalOverage: evaluation.TotalOverage,
+		TotalPacks:   evaluation.TotalPacks,
+		Score:        evaluation.Score,
+	}
+	if evaluation.Orders > 0 {
+		response.AverageOverage = float64(evaluation.TotalOverage) / float64(evaluation.Orders)
+	}
+	return response
+}
+
+// newID returns a random ID for a job or quote
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
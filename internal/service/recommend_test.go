@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcellribeiro/awesomeProject/internal/model"
+	"github.com/marcellribeiro/awesomeProject/internal/repository"
+	"github.com/marcellribeiro/awesomeProject/pkg/calculator"
+)
+
+func TestPackService_Recommendation(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, nil, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250})
+
+	tests := []struct {
+		name        string
+		request     *model.RecommendationRequest
+		wantStatus  string
+		wantSizes   []int
+		wantChange  *model.EvaluationChange
+		wantCode    string
+		wantCurrent []int
+	}{
+		{
+			name: "Recommended against the current pack sizes",
+			request: &model.RecommendationRequest{
+				Orders:  []model.OrderQuantity{{Quantity: 300, Count: 10}, {Quantity: 600, Count: 5}, {Quantity: 250, Count: 2}},
+				MinSize: 100, MaxSize: 600, Step: 100, MaxSizes: 2,
+			},
+			wantStatus: model.JobSucceeded,
+			wantSizes:  []int{300, 600},
+			// 250s send 2750 items too many in 37 packs, 300 and 600 send 100 in 17
+			wantChange:  &model.EvaluationChange{TotalItems: -2650, TotalOverage: -2650, TotalPacks: -20},
+			wantCurrent: []int{250},
+		},
+		{
+			name: "Search fails",
+			request: &model.RecommendationRequest{
+				Orders:  []model.OrderQuantity{{Quantity: 1_000_000_000}},
+				MinSize: 1, MaxSize: 2, MaxSizes: 1,
+			},
+			wantStatus: model.JobFailed,
+			wantCode:   model.CodeTableTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := service.StartRecommendation(tt.request)
+			if err != nil {
+				t.Fatalf("StartRecommendation() error = %v", err)
+			}
+			if job.Status != model.JobQueued || job.MaxEvaluations != calculator.DefaultMaxEvaluations {
+				t.Errorf("StartRecommendation() = %+v, want a queued job", job)
+			}
+
+			job = waitForJob(t, service, job.ID)
+			if job.Status != tt.wantStatus || job.Code != tt.wantCode {
+				t.Fatalf("job finished %s with code %q (%s), want %s with %q", job.Status, job.Code, job.Error, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantStatus != model.JobSucceeded {
+				return
+			}
+
+			result := job.Result
+			if !reflect.DeepEqual(result.Recommended.PackSizes, tt.wantSizes) || !result.Exhaustive {
+				t.Errorf("Recommended = %+v, want %v found exhaustively", result.Recommended, tt.wantSizes)
+			}
+			if result.Current == nil || !reflect.DeepEqual(result.Current.PackSizes, tt.wantCurrent) {
+				t.Errorf("Current = %+v, want %v", result.Current, tt.wantCurrent)
+			}
+			if !reflect.DeepEqual(result.Change, tt.wantChange) {
+				t.Errorf("Change = %+v, want %+v", result.Change, tt.wantChange)
+			}
+			if job.Evaluated != 21 || job.StartedAt == nil || job.FinishedAt == nil {
+				t.Errorf("job = %+v, want 21 sets evaluated between its start and finish", job)
+			}
+		})
+	}
+}
+
+func TestPackService_Recommendation_FromHistory(t *testing.T) {
+	repo := repository.NewInMemoryPackRepository()
+	quotes := repository.NewInMemoryQuoteRepository()
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repo, quotes, calculator.DefaultLimits())
+	repo.SetPackSizes([]int{250})
+
+	saveQuotes := func(profile string, quantity, count int) {
+		for i := 0; i < count; i++ {
+			quote := &model.Quote{
+				ID:        fmt.Sprintf("%s-%d-%d", profile, quantity, i),
+				CreatedAt: time.Now().UTC(),
+				Profile:   profile,
+				Request:   model.PackRequest{Quantity: quantity},
+			}
+			if err := quotes.SaveQuote(quote); err != nil {
+				t.Fatalf("SaveQuote() error = %v", err)
+			}
+		}
+	}
+	saveQuotes(model.DefaultProfile, 600, 5)
+	saveQuotes(model.DefaultProfile, 300, 10)
+	saveQuotes(model.DefaultProfile, 250, 2)
+	saveQuotes("bolts", 7, 3)
+
+	// Limit and Offset page a listing, not a history
+	filter := model.QuoteFilter{Profile: model.DefaultProfile, Limit: 1, Offset: 1}
+	orders, err := service.(*packService).historyOrders(filter)
+	if err != nil {
+		t.Fatalf("historyOrders() error = %v", err)
+	}
+	wantOrders := []model.OrderQuantity{{Quantity: 250, Count: 2}, {Quantity: 300, Count: 10}, {Quantity: 600, Count: 5}}
+	if !reflect.DeepEqual(orders, wantOrders) {
+		t.Errorf("historyOrders() = %+v, want %+v", orders, wantOrders)
+	}
+
+	job, err := service.StartRecommendation(&model.RecommendationRequest{
+		History: &filter,
+		MinSize: 100, MaxSize: 600, Step: 100, MaxSizes: 2,
+	})
+	if err != nil {
+		t.Fatalf("StartRecommendation() error = %v", err)
+	}
+	job = waitForJob(t, service, job.ID)
+	if job.Status != model.JobSucceeded {
+		t.Fatalf("job finished %s (%s), want %s", job.Status, job.Error, model.JobSucceeded)
+	}
+	if got := job.Result.Recommended; !reflect.DeepEqual(got.PackSizes, []int{300, 600}) || got.Orders != 17 {
+		t.Errorf("Recommended = %+v, want [300 600] over 17 orders", got)
+	}
+
+	invalid := []*model.RecommendationRequest{
+		{History: &model.QuoteFilter{Profile: "nuts"}, MinSize: 1, MaxSize: 2, MaxSizes: 1},
+		{History: &filter, Orders: wantOrders, MinSize: 1, MaxSize: 2, MaxSizes: 1},
+		{History: &model.QuoteFilter{MinQuantity: -1}, MinSize: 1, MaxSize: 2, MaxSizes: 1},
+	}
+	for _, request := range invalid {
+		if _, err := service.StartRecommendation(request); !model.IsValidationError(err) {
+			t.Errorf("StartRecommendation(%+v) error = %v, want a validation error", request, err)
+		}
+	}
+}
+
+func TestPackService_StartRecommendation_Errors(t *testing.T) {
+	service := NewPackService(calculator.NewDynamicPackCalculator(), repository.NewInMemoryPackRepository(), nil, calculator.DefaultLimits())
+
+	if _, err := service.StartRecommendation(&model.RecommendationRequest{MinSize: 1, MaxSize: 2, MaxSizes: 1}); !model.IsValidationError(err) {
+		t.Errorf("StartRecommendation() error = %v, want a validation error", err)
+	}
+	if _, err := service.GetRecommendation("missing"); !errors.Is(err, model.ErrJobNotFound) {
+		t.Errorf("GetRecommendation() error = %v, want %v", err, model.ErrJobNotFound)
+	}
+}
+
+func TestRecommendationJobs(t *testing.T) {
+	jobs := newRecommendationJobs()
+	for i := 0; i < maxRunningRecommendations; i++ {
+		if err := jobs.add(&model.RecommendationJob{ID: string(rune('a' + i)), Status: model.JobQueued}); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+	if err := jobs.add(&model.RecommendationJob{ID: "busy", Status: model.JobQueued}); !errors.Is(err, model.ErrTooManyJobs) {
+		t.Fatalf("add() error = %v, want %v", err, model.ErrTooManyJobs)
+	}
+
+	// Finishing a job frees its slot; further updates of it do not
+	jobs.update("a", func(job *model.RecommendationJob) { job.Status = model.JobSucceeded })
+	jobs.update("a", func(job *model.RecommendationJob) { job.Evaluated = 1 })
+	if jobs.running != maxRunningRecommendations-1 {
+		t.Errorf("running = %d, want %d", jobs.running, maxRunningRecommendations-1)
+	}
+
+	// Past the limit the oldest finished jobs are dropped
+	for i := len(jobs.order); i < maxRecommendationJobs; i++ {
+		jobs.order = append(jobs.order, "filler")
+	}
+	if err := jobs.add(&model.RecommendationJob{ID: "new", Status: model.JobQueued}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if _, err := jobs.get("a"); !errors.Is(err, model.ErrJobNotFound) {
+		t.Errorf("get() error = %v, want the finished job dropped", err)
+	}
+	if _, err := jobs.get("b"); err != nil {
+		t.Errorf("get() error = %v, want the running job kept", err)
+	}
+}
+
+// waitForJob polls the recommendation job with id until it finished
+func waitForJob(t *testing.T, service PackService, id string) *model.RecommendationJob {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := service.GetRecommendation(id)
+		if err != nil {
+			t.Fatalf("GetRecommendation() error = %v", err)
+		}
+		if job.Status == model.JobSucceeded || job.Status == model.JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
@@ -0,0 +1,345 @@
+package calculator
+
+import (
+	"container/heap"
+	"context"
+	"math"
+)
+
+// MaxAlternatives is the largest number of alternatives CalculateAlternatives returns
+const MaxAlternatives = 10
+
+// CalculateAlternatives returns up to k distinct pack distributions ranked by the
+// default rules: least items first, then fewest packs. The first one is optimal
+//
+// Solutions are enumerated best-first over the choices "one more pack of this size"
+// and "no more packs of this size", using the exact fewest packs per amount as the
+// estimate, so each pop from the queue leads straight to the next best distribution
+func (c *DynamicPackCalculator) CalculateAlternatives(ctx context.Context, quantity int, packSizes []int, k int) ([]map[int]int, error) {
+	if quantity <= 0 || k <= 0 {
+		return []map[int]int{}, nil
+	}
+
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+	k = min(k, MaxAlternatives)
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// Every reachable amount plus a multiple of the largest pack is reachable too,
+	// so this range holds at least k reachable amounts whenever one exists
+	span, err := tableSpan(quantity, sizes, k)
+	if err != nil || c.limits.checkTableSize(tableCells(len(sizes)+1, span-1)) != nil {
+		return nil, ErrAlternativesTooLarge
+	}
+	limit := span - 1
+
+	packs, err := fewestPacksTable(ctx, sizes, limit)
+	if err != nil {
+		return nil, err
+	}
+	n := len(sizes)
+
+	// nodes form a tree of partial distributions; each one adds a pack to its parent
+	nodes := []alternativeNode{{parent: -1, size: -1}}
+	queue := &alternativeQueue{}
+	for amount := quantity; amount < limit; amount++ {
+		if fewest := packs[n][amount]; fewest != unreachablePacks {
+			heap.Push(queue, alternativeState{over: amount - quantity, packs: int(fewest), sizes: n, remaining: amount})
+		}
+	}
+
+	var result []map[int]int
+	for pops := 0; queue.Len() > 0 && len(result) < k; pops++ {
+		if err := checkContextEvery(ctx, pops); err != nil {
+			return nil, err
+		}
+		state := heap.Pop(queue).(alternativeState)
+
+		if state.remaining == 0 {
+			result = append(result, nodes[state.node].breakdown(nodes, sizes))
+			continue
+		}
+
+		// One more pack of the largest size still allowed
+		i := state.sizes - 1
+		if rest := state.remaining - sizes[i]; rest >= 0 && packs[state.sizes][rest] != unreachablePacks {
+			nodes = append(nodes, alternativeNode{parent: state.node, size: i})
+			heap.Push(queue, alternativeState{
+				over:      state.over,
+				packs:     state.used + 1 + int(packs[state.sizes][rest]),
+				used:      state.used + 1,
+				sizes:     state.sizes,
+				remaining: rest,
+				node:      len(nodes) - 1,
+				sequence:  len(nodes),
+			})
+		}
+
+		// No more packs of that size
+		if i > 0 && packs[i][state.remaining] != unreachablePacks {
+			heap.Push(queue, alternativeState{
+				over:      state.over,
+				packs:     state.used + int(packs[i][state.remaining]),
+				used:      state.used,
+				sizes:     i,
+				remaining: state.remaining,
+				node:      state.node,
+				sequence:  len(nodes),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// CalculateAlternatives returns up to k distinct pack distributions within opts.Stock,
+// ranked by opts.Policy. The first one is as good as the one CalculateWithOptions
+// returns, though equally ranked distributions may come in another order
+//
+// The enumeration is the one of DynamicPackCalculator.CalculateAlternatives, estimating
+// each partial distribution with the best policy score per amount for every prefix of
+// the sizes. That score allows the full stock of a size already taken from, so it is a
+// lower bound: distributions still come out in rank order, after some dead ends
+func (c *BoundedPackCalculator) CalculateAlternatives(ctx context.Context, quantity int, packSizes []int, k int, opts Options) ([]map[int]int, error) {
+	if quantity <= 0 || k <= 0 {
+		return []map[int]int{}, nil
+	}
+
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+	k = min(k, MaxAlternatives)
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	if err := opts.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	criteria := opts.Policy.criteria()
+	steps, extras := policySteps(sizes, criteria, opts.Costs)
+
+	// Taking packs out of a distribution lowers its items over and pack count and raises
+	// nothing, so one of quantity plus k largest packs or more ranks behind k others
+	span, err := tableSpan(quantity, sizes, k)
+	if err != nil {
+		return nil, ErrAlternativesTooLarge
+	}
+	counts, available := stockCounts(sizes, span, opts.Stock)
+	if available < quantity {
+		return nil, ErrInsufficientStock
+	}
+	limit := min(span, available+1)
+	// A score takes the room of six table cells
+	if c.limits.checkTableSize(tableCells(6*(len(sizes)+1), limit)) != nil {
+		return nil, ErrAlternativesTooLarge
+	}
+
+	// rows[i][amount] = best score making exactly amount with the first i sizes
+	rows := make([][]score, len(sizes)+1)
+	rows[0] = make([]score, limit)
+	for amount := 1; amount < limit; amount++ {
+		rows[0][amount] = unreachable
+	}
+	choice := make([]int32, limit)
+	for i, size := range sizes {
+		if rows[i+1], err = c.addPackSize(ctx, rows[i], size, counts[i], steps[i], extras[i], choice); err != nil {
+			return nil, err
+		}
+	}
+	n := len(sizes)
+
+	nodes := []alternativeNode{{parent: -1, size: -1}}
+	queue := &rankedQueue{}
+	push := func(state rankedState, estimate score) {
+		state.rank = rankAmount(criteria, state.partial.add(estimate, 1), state.over)
+		state.sequence = queue.sequence
+		queue.sequence++
+		heap.Push(queue, state)
+	}
+	reachable := false
+	for amount := quantity; amount < limit; amount++ {
+		if rows[n][amount] == unreachable {
+			continue
+		}
+		reachable = true
+		if opts.Policy.WithinOverage(quantity, amount) {
+			push(rankedState{over: amount - quantity, sizes: n, remaining: amount}, rows[n][amount])
+		}
+	}
+	if queue.Len() == 0 {
+		if reachable {
+			return nil, ErrOverageLimit
+		}
+		return nil, ErrInsufficientStock
+	}
+
+	var result []map[int]int
+	for pops := 0; queue.Len() > 0 && len(result) < k; pops++ {
+		if err := checkContextEvery(ctx, pops); err != nil {
+			return nil, err
+		}
+		state := heap.Pop(queue).(rankedState)
+
+		if state.remaining == 0 {
+			result = append(result, nodes[state.node].breakdown(nodes, sizes))
+			continue
+		}
+
+		// One more pack of the largest size still allowed, within its stock
+		// Its extra is counted with its first pack, so the estimate takes it back out
+		i := state.sizes - 1
+		if rest := state.remaining - sizes[i]; rest >= 0 && state.taken < counts[i] {
+			next := state
+			next.partial = state.partial.add(steps[i], 1)
+			if state.taken == 0 {
+				next.partial = next.partial.add(extras[i], 1)
+			}
+			next.taken++
+			next.remaining = rest
+
+			estimate := rows[i][rest]
+			if more := rows[i+1][rest]; more != unreachable {
+				if more = more.add(extras[i], -1); estimate == unreachable || more.less(estimate) {
+					estimate = more
+				}
+			}
+			if estimate != unreachable {
+				nodes = append(nodes, alternativeNode{parent: state.node, size: i})
+				next.node = len(nodes) - 1
+				push(next, estimate)
+			}
+		}
+
+		// No more packs of that size
+		if i > 0 && rows[i][state.remaining] != unreachable {
+			next := state
+			next.sizes = i
+			next.taken = 0
+			push(next, rows[i][state.remaining])
+		}
+	}
+
+	return result, nil
+}
+
+// unreachablePacks marks amounts that cannot be made with the allowed sizes
+const unreachablePacks = math.MaxInt32
+
+// fewestPacksTable returns table[i][amount] = fewest packs of the first i sizes
+// that add up to exactly amount
+func fewestPacksTable(ctx context.Context, sizes []int, limit int) ([][]int32, error) {
+	table := make([][]int32, len(sizes)+1)
+	table[0] = make([]int32, limit)
+	for amount := 1; amount < limit; amount++ {
+		table[0][amount] = unreachablePacks
+	}
+
+	for i, size := range sizes {
+		prev, next := table[i], make([]int32, limit)
+		for amount := 0; amount < limit; amount++ {
+			if err := checkContextEvery(ctx, amount); err != nil {
+				return nil, err
+			}
+			next[amount] = prev[amount]
+			if amount >= size && next[amount-size] != unreachablePacks && next[amount-size]+1 < next[amount] {
+				next[amount] = next[amount-size] + 1
+			}
+		}
+		table[i+1] = next
+	}
+	return table, nil
+}
+
+type alternativeNode struct {
+	parent int
+	size   int // index of the pack size added, -1 for the root
+}
+
+// breakdown walks up to the root counting the packs added along the way
+func (n alternativeNode) breakdown(nodes []alternativeNode, sizes []int) map[int]int {
+	result := make(map[int]int)
+	for node := n; node.parent >= 0; node = nodes[node.parent] {
+		result[sizes[node.size]]++
+	}
+	return result
+}
+
+// alternativeState is a partial distribution still missing remaining items,
+// which may only use the first sizes pack sizes
+type alternativeState struct {
+	over      int // items over the quantity of the final distribution
+	packs     int // packs of the best distribution completing this state
+	used      int // packs taken so far
+	sizes     int
+	remaining int
+	node      int
+	sequence  int // keeps the order stable between equally ranked states
+}
+
+type alternativeQueue []alternativeState
+
+func (q alternativeQueue) Len() int { return len(q) }
+func (q alternativeQueue) Less(i, j int) bool {
+	if q[i].over != q[j].over {
+		return q[i].over < q[j].over
+	}
+	if q[i].packs != q[j].packs {
+		return q[i].packs < q[j].packs
+	}
+	return q[i].sequence < q[j].sequence
+}
+func (q alternativeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *alternativeQueue) Push(x interface{}) { *q = append(*q, x.(alternativeState)) }
+func (q *alternativeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// rankedState is a partial distribution of CalculateAlternatives with options, still
+// missing remaining items, which may only use the first sizes pack sizes
+type rankedState struct {
+	rank      []int // policy ranking of the best distribution completing this state
+	over      int   // items over the quantity of the final distribution
+	partial   score // score of the packs taken so far
+	taken     int   // packs taken of the largest size allowed
+	sizes     int
+	remaining int
+	node      int
+	sequence  int // keeps the order stable between equally ranked states
+}
+
+type rankedQueue struct {
+	states   []rankedState
+	sequence int
+}
+
+func (q rankedQueue) Len() int { return len(q.states) }
+func (q rankedQueue) Less(i, j int) bool {
+	a, b := q.states[i], q.states[j]
+	if lessRank(a.rank, b.rank) || lessRank(b.rank, a.rank) {
+		return lessRank(a.rank, b.rank)
+	}
+	return a.sequence < b.sequence
+}
+func (q rankedQueue) Swap(i, j int)       { q.states[i], q.states[j] = q.states[j], q.states[i] }
+func (q *rankedQueue) Push(x interface{}) { q.states = append(q.states, x.(rankedState)) }
+func (q *rankedQueue) Pop() interface{} {
+	old := q.states
+	item := old[len(old)-1]
+	q.states = old[:len(old)-1]
+	return item
+}
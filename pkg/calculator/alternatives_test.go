@@ -0,0 +1,252 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDynamicPackCalculator_CalculateAlternatives(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+	packSizes := []int{250, 500, 1000, 2000, 5000}
+
+	tests := []struct {
+		name     string
+		quantity int
+		k        int
+		want     []map[int]int
+	}{
+		{
+			name:     "Ties are listed after the optimum",
+			quantity: 1000,
+			k:        3,
+			want:     []map[int]int{{1000: 1}, {500: 2}, {500: 1, 250: 2}},
+		},
+		{
+			name:     "More items come after every exact distribution",
+			quantity: 501,
+			k:        4,
+			want:     []map[int]int{{500: 1, 250: 1}, {250: 3}, {1000: 1}, {500: 2}},
+		},
+		{
+			name:     "Single alternative is the optimum",
+			quantity: 12001,
+			k:        1,
+			want:     []map[int]int{{5000: 2, 2000: 1, 250: 1}},
+		},
+		{
+			name:     "Zero alternatives",
+			quantity: 1000,
+			k:        0,
+			want:     []map[int]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.CalculateAlternatives(context.Background(), tt.quantity, packSizes, tt.k)
+			if err != nil {
+				t.Fatalf("CalculateAlternatives() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("CalculateAlternatives() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamicPackCalculator_CalculateAlternatives_FirstIsOptimal(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	for _, tt := range calculateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.CalculateAlternatives(context.Background(), tt.quantity, tt.packSizes, 3)
+			if err != nil {
+				t.Fatalf("CalculateAlternatives() error = %v", err)
+			}
+			if len(result) == 0 {
+				t.Fatal("CalculateAlternatives() returned no distributions")
+			}
+
+			items, packs := totals(result[0])
+			if items != tt.wantTotal || packs != tt.wantPacks {
+				t.Errorf("First alternative = items:%d packs:%d, want items:%d packs:%d",
+					items, packs, tt.wantTotal, tt.wantPacks)
+			}
+		})
+	}
+}
+
+func TestDynamicPackCalculator_CalculateAlternatives_MatchesBruteForce(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+	packSizes := []int{3, 7, 10}
+	const k = 6
+
+	for quantity := 1; quantity <= 40; quantity++ {
+		result, err := calc.CalculateAlternatives(context.Background(), quantity, packSizes, k)
+		if err != nil {
+			t.Fatalf("CalculateAlternatives(%d) error = %v", quantity, err)
+		}
+		if len(result) != k {
+			t.Fatalf("CalculateAlternatives(%d) returned %d distributions, want %d", quantity, len(result), k)
+		}
+
+		seen := make(map[string]bool)
+		var got [][2]int
+		for _, breakdown := range result {
+			key := fmt.Sprint(breakdown)
+			if seen[key] {
+				t.Fatalf("CalculateAlternatives(%d) repeated %v", quantity, breakdown)
+			}
+			seen[key] = true
+
+			items, packs := totals(breakdown)
+			if items < quantity {
+				t.Fatalf("CalculateAlternatives(%d) returned %v with only %d items", quantity, breakdown, items)
+			}
+			got = append(got, [2]int{items - quantity, packs})
+		}
+
+		want := bruteForceRanks(quantity, packSizes)[:k]
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("CalculateAlternatives(%d) ranks = %v, want %v", quantity, got, want)
+		}
+	}
+}
+
+// bruteForceRanks returns the (items over, packs) of every distribution of up to
+// 15 packs per size, best first
+func bruteForceRanks(quantity int, packSizes []int) [][2]int {
+	var ranks [][2]int
+	var search func(i, items, packs int)
+	search = func(i, items, packs int) {
+		if i == len(packSizes) {
+			if items >= quantity {
+				ranks = append(ranks, [2]int{items - quantity, packs})
+			}
+			return
+		}
+		for count := 0; count <= 15; count++ {
+			search(i+1, items+count*packSizes[i], packs+count)
+		}
+	}
+	search(0, 0, 0)
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i][0] != ranks[j][0] {
+			return ranks[i][0] < ranks[j][0]
+		}
+		return ranks[i][1] < ranks[j][1]
+	})
+	return ranks
+}
+
+func TestBoundedPackCalculator_CalculateAlternatives_MatchesBruteForce(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+	packSizes := []int{3, 7, 10}
+	costs := map[int]int{3: 4, 7: 5, 10: 9}
+	const k = 5
+
+	for _, stock := range []map[int]int{nil, {3: 3, 10: 2}} {
+		for _, policy := range Policies() {
+			for quantity := 1; quantity <= 40; quantity++ {
+				opts := Options{Stock: stock, Costs: costs, Policy: policy}
+				result, err := calc.CalculateAlternatives(context.Background(), quantity, packSizes, k, opts)
+				if err != nil {
+					t.Fatalf("CalculateAlternatives(%d, %s, %v) error = %v", quantity, policy.Name, stock, err)
+				}
+
+				seen := make(map[string]bool)
+				var got [][]int
+				for _, breakdown := range result {
+					if key := fmt.Sprint(breakdown); seen[key] {
+						t.Fatalf("CalculateAlternatives(%d, %s, %v) repeated %v", quantity, policy.Name, stock, breakdown)
+					} else {
+						seen[key] = true
+					}
+					for size, count := range breakdown {
+						if available, ok := stock[size]; ok && count > available {
+							t.Fatalf("CalculateAlternatives(%d, %s, %v) returned %v beyond stock", quantity, policy.Name, stock, breakdown)
+						}
+					}
+					got = append(got, rankBreakdown(quantity, breakdown, costs, policy))
+				}
+
+				want := bruteForcePolicyRanks(quantity, packSizes, stock, costs, policy)[:k]
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("CalculateAlternatives(%d, %s, %v) ranks = %v, want %v", quantity, policy.Name, stock, got, want)
+				}
+
+				best, err := calc.CalculateWithOptions(context.Background(), quantity, packSizes, opts)
+				if err != nil || !reflect.DeepEqual(rankBreakdown(quantity, best, costs, policy), got[0]) {
+					t.Fatalf("CalculateWithOptions(%d, %s, %v) = %v, %v, want rank %v", quantity, policy.Name, stock, best, err, got[0])
+				}
+			}
+		}
+	}
+}
+
+func TestBoundedPackCalculator_CalculateAlternatives_Errors(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{"Not enough stock", Options{Stock: map[int]int{250: 1, 500: 0}}, ErrInsufficientStock},
+		{"Overage limit", Options{Policy: Policy{MaxOveragePercent: 10}}, ErrOverageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := calc.CalculateAlternatives(context.Background(), 501, []int{250, 500}, 3, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CalculateAlternatives() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// bruteForcePolicyRanks returns the rank of every combination of up to 15 packs per
+// size within stock, best first
+func bruteForcePolicyRanks(quantity int, packSizes []int, stock, costs map[int]int, policy Policy) [][]int {
+	var ranks [][]int
+	breakdown := make(map[int]int)
+
+	var search func(i int)
+	search = func(i int) {
+		if i == len(packSizes) {
+			if items, _ := totals(breakdown); items >= quantity {
+				ranks = append(ranks, rankBreakdown(quantity, breakdown, costs, policy))
+			}
+			return
+		}
+
+		limit := 15
+		if available, ok := stock[packSizes[i]]; ok {
+			limit = available
+		}
+		for count := 0; count <= limit; count++ {
+			breakdown[packSizes[i]] = count
+			search(i + 1)
+		}
+		delete(breakdown, packSizes[i])
+	}
+
+	search(0)
+	sort.SliceStable(ranks, func(i, j int) bool { return lessRank(ranks[i], ranks[j]) })
+	return ranks
+}
+
+func TestDynamicPackCalculator_CalculateAlternatives_TooLarge(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	_, err := calc.CalculateAlternatives(context.Background(), 50_000_000, []int{23, 31, 53}, 3)
+	if !errors.Is(err, ErrAlternativesTooLarge) {
+		t.Errorf("CalculateAlternatives() error = %v, want %v", err, ErrAlternativesTooLarge)
+	}
+}
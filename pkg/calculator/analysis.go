@@ -0,0 +1,184 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Analysis describes the amounts a pack size set reaches exactly and the overage
+// it causes under the default rules over a range of quantities
+type Analysis struct {
+	PackSizes []int
+	// GCD divides every reachable amount; above 1 the other amounts are never reachable exactly
+	GCD int
+	// FrobeniusNumber is the largest multiple of GCD that is not reachable, 0 when there is none
+	FrobeniusNumber int
+	// Conductor is the amount from which every multiple of GCD is reachable
+	Conductor int
+	// Gaps counts the multiples of GCD that are not reachable, all of them below Conductor
+	Gaps int
+
+	// From and To bound the quantities analyzed
+	From int
+	To   int
+	// MaxOverage is the most items sent beyond a quantity, first reached at MaxOverageQuantity
+	MaxOverage         int
+	MaxOverageQuantity int
+	AverageOverage     float64
+	// ExactQuantities counts the quantities sent without overage
+	ExactQuantities int
+	// Usage lists per pack size how many quantities have an optimal breakdown using it
+	Usage []PackSizeUsage
+}
+
+// PackSizeUsage counts the quantities of an analysis with an optimal breakdown using a pack size
+// A size no quantity uses is redundant over the range
+type PackSizeUsage struct {
+	Size       int
+	Quantities int
+}
+
+// Analyze analyzes packSizes over the quantities from from to to
+// A zero from starts at 1 and a zero to stops at the conductor plus the largest pack,
+// past which the overage only repeats itself every GCD items, or at from plus the
+// largest pack when from starts later
+func (c *DynamicPackCalculator) Analyze(ctx context.Context, packSizes []int, from, to int) (*Analysis, error) {
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	analysis, err := c.analyzeReachability(ctx, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	if from == 0 {
+		from = 1
+	}
+	if to == 0 {
+		to = max(analysis.Conductor, from) + sizes[len(sizes)-1]
+	}
+	switch {
+	case from < 0:
+		return nil, fmt.Errorf("range must start above 0, got: %d", from)
+	case to < from:
+		return nil, fmt.Errorf("range end %d is before its start %d", to, from)
+	}
+	analysis.From, analysis.To = from, to
+
+	if err := c.analyzeOverage(ctx, analysis); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// analyzeReachability finds the GCD, Frobenius number, conductor and gaps of sizes
+// Dividing the sizes by their GCD leaves a set reaching every large enough amount.
+// The smallest reachable amount of each residue modulo its smallest size, found like
+// the residue calculator does, bounds the unreachable amounts of that residue
+func (c *DynamicPackCalculator) analyzeReachability(ctx context.Context, sizes []int) (*Analysis, error) {
+	divisor := 0
+	for _, size := range sizes {
+		divisor = gcd(divisor, size)
+	}
+	scaled := make([]int, len(sizes))
+	for i, size := range sizes {
+		scaled[i] = size / divisor
+	}
+
+	base := scaled[0]
+	if err := c.limits.checkTableSize(base); err != nil {
+		return nil, err
+	}
+	dist, err := shortestResiduePaths(ctx, base, scaled[1:], func(pack int) int { return pack })
+	if err != nil {
+		return nil, err
+	}
+
+	largest, gaps := 0, 0
+	for residue, node := range dist {
+		largest = max(largest, node.weight)
+		gaps += (node.weight - residue) / base
+	}
+
+	// The largest unreachable amount lies one smallest size below the last residue reached
+	frobenius := max(largest-base, 0) * divisor
+	return &Analysis{
+		PackSizes:       sizes,
+		GCD:             divisor,
+		FrobeniusNumber: frobenius,
+		Conductor:       frobenius + divisor,
+		Gaps:            gaps,
+	}, nil
+}
+
+// analyzeOverage fills the overage and pack size usage of the analysis range
+// The pack table spans the range plus the largest pack, so the least reachable amount
+// of every quantity is in it; a size is used for an amount when one pack of that size
+// plus the fewest packs reaching the rest is the fewest packs reaching the amount
+func (c *DynamicPackCalculator) analyzeOverage(ctx context.Context, analysis *Analysis) error {
+	sizes := analysis.PackSizes
+	ctx, cancel, err := c.limits.start(ctx, analysis.To)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	span, err := tableSpan(analysis.To, sizes, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.limits.checkTableSize(span); err != nil {
+		return err
+	}
+
+	packs, _, err := c.buildPacksTable(ctx, span-1, sizes)
+	if err != nil {
+		return err
+	}
+
+	analysis.Usage = make([]PackSizeUsage, len(sizes))
+	for i, size := range sizes {
+		analysis.Usage[i].Size = size
+	}
+
+	totalOverage := 0
+	next := math.MaxInt
+	for amount := span - 1; amount >= analysis.From; amount-- {
+		if err := checkContextEvery(ctx, amount); err != nil {
+			return err
+		}
+		if packs[amount] != math.MaxInt32 {
+			next = amount
+		}
+		if amount > analysis.To {
+			continue
+		}
+
+		overage := next - amount
+		totalOverage += overage
+		if overage == 0 {
+			analysis.ExactQuantities++
+		}
+		if overage >= analysis.MaxOverage {
+			analysis.MaxOverage, analysis.MaxOverageQuantity = overage, amount
+		}
+		for i, size := range sizes {
+			if next >= size && packs[next-size]+1 == packs[next] {
+				analysis.Usage[i].Quantities++
+			}
+		}
+	}
+
+	analysis.AverageOverage = float64(totalOverage) / float64(analysis.To-analysis.From+1)
+	return nil
+}
+
+// gcd returns the greatest common divisor of a and b, with gcd(0, b) = b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
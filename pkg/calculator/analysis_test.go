@@ -0,0 +1,120 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDynamicPackCalculator_Analyze(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	tests := []struct {
+		name      string
+		packSizes []int
+		from, to  int
+		want      Analysis
+	}{
+		{
+			name:      "Coprime sizes over a range",
+			packSizes: []int{5, 3},
+			from:      1,
+			to:        10,
+			// Reached: 3, 5, 6, 8, 9, 10; quantities 1 and 2 get 3, 4 gets 5 and 7 gets 8
+			want: Analysis{
+				PackSizes: []int{3, 5}, GCD: 1, FrobeniusNumber: 7, Conductor: 8, Gaps: 4,
+				From: 1, To: 10, MaxOverage: 2, MaxOverageQuantity: 1, AverageOverage: 0.5, ExactQuantities: 6,
+				Usage: []PackSizeUsage{{Size: 3, Quantities: 7}, {Size: 5, Quantities: 5}},
+			},
+		},
+		{
+			name:      "Default range ends past the conductor",
+			packSizes: []int{6, 9, 20},
+			want: Analysis{
+				PackSizes: []int{6, 9, 20}, GCD: 1, FrobeniusNumber: 43, Conductor: 44, Gaps: 22,
+				From: 1, To: 64, MaxOverage: 5, MaxOverageQuantity: 1, AverageOverage: 0.578125, ExactQuantities: 42,
+				Usage: []PackSizeUsage{{Size: 6, Quantities: 41}, {Size: 9, Quantities: 45}, {Size: 20, Quantities: 30}},
+			},
+		},
+		{
+			name:      "Common divisor",
+			packSizes: []int{250, 500, 1000, 2000, 5000},
+			// Every multiple of 250 is reached; the 11 odd ones up to 5250 need a 250 pack
+			want: Analysis{
+				PackSizes: []int{250, 500, 1000, 2000, 5000}, GCD: 250, FrobeniusNumber: 0, Conductor: 250, Gaps: 0,
+				From: 1, To: 5250, MaxOverage: 249, MaxOverageQuantity: 1, AverageOverage: 124.5, ExactQuantities: 21,
+				Usage: []PackSizeUsage{
+					{Size: 250, Quantities: 2750},
+					{Size: 500, Quantities: 2500},
+					{Size: 1000, Quantities: 2000},
+					{Size: 2000, Quantities: 3000},
+					{Size: 5000, Quantities: 500},
+				},
+			},
+		},
+		{
+			name:      "Size beyond the range is redundant",
+			packSizes: []int{250, 5000},
+			from:      1,
+			to:        1000,
+			want: Analysis{
+				PackSizes: []int{250, 5000}, GCD: 250, FrobeniusNumber: 0, Conductor: 250, Gaps: 0,
+				From: 1, To: 1000, MaxOverage: 249, MaxOverageQuantity: 1, AverageOverage: 124.5, ExactQuantities: 4,
+				Usage: []PackSizeUsage{{Size: 250, Quantities: 1000}, {Size: 5000, Quantities: 0}},
+			},
+		},
+		{
+			name:      "Unit size reaches everything",
+			packSizes: []int{1, 7},
+			from:      1,
+			to:        3,
+			want: Analysis{
+				PackSizes: []int{1, 7}, GCD: 1, FrobeniusNumber: 0, Conductor: 1, Gaps: 0,
+				From: 1, To: 3, MaxOverageQuantity: 1, ExactQuantities: 3,
+				Usage: []PackSizeUsage{{Size: 1, Quantities: 3}, {Size: 7, Quantities: 0}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Analyze(context.Background(), tt.packSizes, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Analyze() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamicPackCalculator_Analyze_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		calc      *DynamicPackCalculator
+		packSizes []int
+		from, to  int
+		wantErr   error
+	}{
+		{"No pack sizes", NewDynamicPackCalculator(), []int{0, -5}, 1, 10, ErrNoPackSizes},
+		{"Table too large", NewDynamicPackCalculator().WithLimits(Limits{MaxTableSize: 100}), []int{250}, 1, 1000, ErrTableTooLarge},
+		{"Quantity too large", NewDynamicPackCalculator().WithLimits(Limits{MaxQuantity: 100}), []int{250}, 1, 1000, ErrQuantityTooLarge},
+		{"End before start", NewDynamicPackCalculator(), []int{250}, 10, 5, nil},
+		{"Negative start", NewDynamicPackCalculator(), []int{250}, -1, 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.calc.Analyze(context.Background(), tt.packSizes, tt.from, tt.to)
+			if err == nil {
+				t.Fatal("Analyze() expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Analyze() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
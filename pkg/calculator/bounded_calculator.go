@@ -0,0 +1,286 @@
+package calculator
+
+import (
+	"context"
+	"math"
+)
+
+// Options describes limits and preferences for a bounded calculation
+type Options struct {
+	// Stock is the number of packs available per size. Sizes without an entry are unlimited
+	Stock map[int]int
+	// Costs is the cost of one pack per size. Sizes without an entry cost nothing
+	Costs map[int]int
+	// Policy ranks the candidate distributions. The zero value follows the default rules
+	Policy Policy
+}
+
+// BoundedPackCalculator calculates pack distributions when only a limited number
+// of packs of each size is available
+type BoundedPackCalculator struct {
+	limits Limits
+}
+
+// NewBoundedPackCalculator creates a new bounded calculator instance with the default limits
+func NewBoundedPackCalculator() *BoundedPackCalculator {
+	return &BoundedPackCalculator{limits: DefaultLimits()}
+}
+
+// WithLimits returns a copy of the calculator enforcing limits
+func (c *BoundedPackCalculator) WithLimits(limits Limits) *BoundedPackCalculator {
+	return &BoundedPackCalculator{limits: limits}
+}
+
+// Algorithm returns AlgorithmBounded
+func (c *BoundedPackCalculator) Algorithm() string {
+	return AlgorithmBounded
+}
+
+// Calculate determines the optimal pack distribution assuming unlimited stock
+func (c *BoundedPackCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	return c.CalculateWithOptions(ctx, quantity, packSizes, Options{})
+}
+
+// CalculateWithStock determines the optimal pack distribution using at most
+// stock[size] packs of each size. Sizes missing from stock are unlimited.
+// The default rules apply: least items first, then as few packs as possible
+func (c *BoundedPackCalculator) CalculateWithStock(ctx context.Context, quantity int, packSizes []int, stock map[int]int) (map[int]int, error) {
+	return c.CalculateWithOptions(ctx, quantity, packSizes, Options{Stock: stock})
+}
+
+// CalculateWithOptions determines the best pack distribution within the given stock
+// according to the policy
+func (c *BoundedPackCalculator) CalculateWithOptions(ctx context.Context, quantity int, packSizes []int, opts Options) (map[int]int, error) {
+	if quantity <= 0 {
+		return map[int]int{}, nil
+	}
+
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	// Amounts up to quantity + largest pack must not overflow
+	if _, err := tableSpan(quantity, sizes, 1); err != nil {
+		return nil, err
+	}
+
+	if err := opts.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	criteria := opts.Policy.criteria()
+	steps, extras := policySteps(sizes, criteria, opts.Costs)
+
+	// An optimal solution never reaches quantity + largest pack, otherwise any
+	// pack could be removed and still cover the order with a better score on
+	// every criterion
+	limit := quantity + sizes[len(sizes)-1]
+
+	counts, available := stockCounts(sizes, limit, opts.Stock)
+	if available < quantity {
+		return nil, ErrInsufficientStock
+	}
+	limit = min(limit, available+1)
+
+	// One row for the scores and one per size for the choices
+	if err := c.limits.checkTableSize(tableCells(len(sizes)+1, limit)); err != nil {
+		return nil, err
+	}
+
+	// dp[i] = best score to achieve amount i with the sizes added so far
+	// The score holds the criteria that add up pack by pack, in policy order
+	dp := make([]score, limit)
+	for i := 1; i < limit; i++ {
+		dp[i] = unreachable
+	}
+
+	// choices[i][amount] = how many packs of sizes[i] were used to reach amount
+	choices := make([][]int32, len(sizes))
+	for i, size := range sizes {
+		choices[i] = make([]int32, limit)
+		if dp, err = c.addPackSize(ctx, dp, size, counts[i], steps[i], extras[i], choices[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Items over only depends on the amount, so rank the reachable amounts
+	best := -1
+	var bestRank []int
+	reachable := false
+	for amount := quantity; amount < limit; amount++ {
+		if dp[amount] == unreachable {
+			continue
+		}
+		reachable = true
+		if !opts.Policy.WithinOverage(quantity, amount) {
+			continue
+		}
+
+		rank := rankAmount(criteria, dp[amount], amount-quantity)
+		if best == -1 || lessRank(rank, bestRank) {
+			best, bestRank = amount, rank
+		}
+	}
+
+	if best == -1 {
+		if reachable {
+			return nil, ErrOverageLimit
+		}
+		return nil, ErrInsufficientStock
+	}
+
+	// Backtrack through the sizes to find which packs were used
+	result := make(map[int]int)
+	for i, amount := len(sizes)-1, best; i >= 0; i-- {
+		if count := int(choices[i][amount]); count > 0 {
+			result[sizes[i]] = count
+			amount -= count * sizes[i]
+		}
+	}
+	return result, nil
+}
+
+// stockCounts returns how many packs of each size can be used for amounts below limit,
+// within stock, and the items they hold together
+func stockCounts(sizes []int, limit int, stock map[int]int) (counts []int, available int) {
+	counts = make([]int, len(sizes))
+	for i, size := range sizes {
+		counts[i] = (limit - 1) / size
+		if count, ok := stock[size]; ok && count < counts[i] {
+			counts[i] = max(count, 0)
+		}
+		available += counts[i] * size
+	}
+	return counts, available
+}
+
+// score holds the additive policy criteria for one amount; lower is better,
+// compared element by element
+type score [3]int
+
+// unreachable marks amounts that cannot be made with the sizes added so far
+var unreachable = score{math.MaxInt, math.MaxInt, math.MaxInt}
+
+func (s score) add(other score, times int) score {
+	for i := range s {
+		s[i] += other[i] * times
+	}
+	return s
+}
+
+func (s score) less(other score) bool {
+	for i := range s {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return false
+}
+
+// policySteps returns, per size, what each pack adds to the score and what
+// using the size at all adds to it
+func policySteps(sizes []int, criteria []Criterion, costs map[int]int) (steps, extras []score) {
+	steps = make([]score, len(sizes))
+	extras = make([]score, len(sizes))
+
+	position := 0
+	for _, criterion := range criteria {
+		if criterion == CriterionItemsOver {
+			continue
+		}
+		for i, size := range sizes {
+			switch criterion {
+			case CriterionPackCount:
+				steps[i][position] = 1
+			case CriterionCost:
+				steps[i][position] = costs[size]
+			case CriterionDistinctSizes:
+				extras[i][position] = 1
+			}
+		}
+		position++
+	}
+	return steps, extras
+}
+
+// rankAmount lays out the full ranking of a reachable amount in policy order
+func rankAmount(criteria []Criterion, s score, itemsOver int) []int {
+	rank := make([]int, 0, len(criteria))
+	position := 0
+	for _, criterion := range criteria {
+		if criterion == CriterionItemsOver {
+			rank = append(rank, itemsOver)
+			continue
+		}
+		rank = append(rank, s[position])
+		position++
+	}
+	return rank
+}
+
+func lessRank(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// addPackSize extends the DP table with up to count packs of the given size
+// Amounts that share a residue modulo size form independent chains, and along a chain
+// next[j] = min(dp[j], min(dp[t] - t*step) + j*step + extra) over the window
+// j-count <= t < j, kept in a monotonic queue
+func (c *BoundedPackCalculator) addPackSize(ctx context.Context, dp []score, size, count int, step, extra score, choice []int32) ([]score, error) {
+	next := make([]score, len(dp))
+	window := make([]int, 0, len(dp)/size+1)
+
+	// base(t) is the score at position t of the chain with t packs taken back out
+	base := func(residue, t int) score {
+		return dp[residue+t*size].add(step, -t)
+	}
+
+	steps := 0
+	for residue := 0; residue < size && residue < len(dp); residue++ {
+		window = window[:0]
+		head := 0
+
+		for j, amount := 0, residue; amount < len(dp); j, amount = j+1, amount+size {
+			if err := checkContextEvery(ctx, steps); err != nil {
+				return nil, err
+			}
+			steps++
+			// Drop candidates that would need more than count packs
+			for len(window) > head && window[head] < j-count {
+				head++
+			}
+
+			// Taking no pack of this size wins ties
+			next[amount] = dp[amount]
+			if len(window) > head {
+				best := window[head]
+				candidate := base(residue, best).add(step, j).add(extra, 1)
+				if candidate.less(dp[amount]) {
+					next[amount] = candidate
+					choice[amount] = int32(j - best)
+				}
+			}
+
+			if dp[amount] != unreachable {
+				// Drop candidates that can no longer beat the new one
+				current := base(residue, j)
+				for len(window) > head && !base(residue, window[len(window)-1]).less(current) {
+					window = window[:len(window)-1]
+				}
+				window = append(window, j)
+			}
+		}
+	}
+
+	return next, nil
+}
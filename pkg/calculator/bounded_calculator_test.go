@@ -0,0 +1,328 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBoundedPackCalculator_Calculate(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+
+	for _, tt := range calculateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			totalItems, totalPacks := totals(result)
+			if totalItems != tt.wantTotal || totalPacks != tt.wantPacks {
+				t.Errorf("Calculate() = items:%d packs:%d, want items:%d packs:%d. Breakdown: %v",
+					totalItems, totalPacks, tt.wantTotal, tt.wantPacks, result)
+			}
+		})
+	}
+}
+
+func TestBoundedPackCalculator_CalculateWithStock(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+	packSizes := []int{250, 500, 1000, 2000, 5000}
+
+	tests := []struct {
+		name     string
+		quantity int
+		stock    map[int]int
+		want     map[int]int
+		wantErr  error
+	}{
+		{
+			name:     "Unlimited stock",
+			quantity: 12001,
+			stock:    nil,
+			want:     map[int]int{5000: 2, 2000: 1, 250: 1},
+		},
+		{
+			name:     "Limited largest pack",
+			quantity: 12001,
+			stock:    map[int]int{5000: 1},
+			want:     map[int]int{5000: 1, 2000: 3, 1000: 1, 250: 1},
+		},
+		{
+			name:     "Out of smallest pack",
+			quantity: 251,
+			stock:    map[int]int{250: 0},
+			want:     map[int]int{500: 1},
+		},
+		{
+			name:     "More items when exact packs are missing",
+			quantity: 750,
+			stock:    map[int]int{250: 0, 500: 1},
+			want:     map[int]int{1000: 1},
+		},
+		{
+			name:     "Everything in stock is needed",
+			quantity: 1501,
+			stock:    map[int]int{250: 2, 500: 1, 1000: 1, 2000: 0, 5000: 0},
+			want:     map[int]int{1000: 1, 500: 1, 250: 1},
+		},
+		{
+			name:     "Insufficient stock",
+			quantity: 2001,
+			stock:    map[int]int{250: 2, 500: 1, 1000: 1, 2000: 0, 5000: 0},
+			wantErr:  ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.CalculateWithStock(context.Background(), tt.quantity, packSizes, tt.stock)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateWithStock() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("CalculateWithStock() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundedPackCalculator_MatchesBruteForce(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+	packSizes := []int{3, 7, 10}
+	stocks := []map[int]int{
+		{3: 2, 7: 1, 10: 3},
+		{3: 5, 7: 0, 10: 1},
+		{3: 0, 7: 4, 10: 2},
+		{3: 1, 10: 1},
+	}
+
+	for _, stock := range stocks {
+		for quantity := 1; quantity <= 60; quantity++ {
+			wantItems, wantPacks := bruteForceWithStock(quantity, packSizes, stock)
+
+			result, err := calc.CalculateWithStock(context.Background(), quantity, packSizes, stock)
+			if wantItems == 0 {
+				if !errors.Is(err, ErrInsufficientStock) {
+					t.Fatalf("CalculateWithStock(%d, %v) error = %v, want %v", quantity, stock, err, ErrInsufficientStock)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("CalculateWithStock(%d, %v) error = %v", quantity, stock, err)
+			}
+
+			for size, count := range result {
+				if available, ok := stock[size]; ok && count > available {
+					t.Fatalf("CalculateWithStock(%d, %v) used %d packs of %d", quantity, stock, count, size)
+				}
+			}
+
+			gotItems, gotPacks := totals(result)
+			if gotItems != wantItems || gotPacks != wantPacks {
+				t.Fatalf("CalculateWithStock(%d, %v) = %v (items:%d packs:%d), want items:%d packs:%d",
+					quantity, stock, result, gotItems, gotPacks, wantItems, wantPacks)
+			}
+		}
+	}
+}
+
+// bruteForceWithStock tries every combination of up to 10 packs per size
+// It returns zero items when no combination covers the quantity
+func bruteForceWithStock(quantity int, packSizes []int, stock map[int]int) (bestItems, bestPacks int) {
+	var search func(i, items, packs int)
+	search = func(i, items, packs int) {
+		if i == len(packSizes) {
+			if items >= quantity && (bestItems == 0 || items < bestItems || items == bestItems && packs < bestPacks) {
+				bestItems, bestPacks = items, packs
+			}
+			return
+		}
+
+		limit := 10
+		if available, ok := stock[packSizes[i]]; ok {
+			limit = available
+		}
+		for count := 0; count <= limit; count++ {
+			search(i+1, items+count*packSizes[i], packs+count)
+		}
+	}
+
+	search(0, 0, 0)
+	return bestItems, bestPacks
+}
+
+func TestBoundedPackCalculator_CalculateWithOptions(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+	packSizes := []int{250, 500, 1000}
+	costs := map[int]int{250: 1, 500: 5, 1000: 6}
+
+	tests := []struct {
+		name     string
+		quantity int
+		opts     Options
+		want     map[int]int
+		wantErr  bool
+	}{
+		{
+			name:     "Default policy ignores cheaper combinations",
+			quantity: 1000,
+			opts:     Options{Costs: costs, Policy: PolicyDefault},
+			want:     map[int]int{1000: 1},
+		},
+		{
+			name:     "Cost policy prefers cheaper packs",
+			quantity: 1000,
+			opts:     Options{Costs: costs, Policy: PolicyCost},
+			want:     map[int]int{250: 4},
+		},
+		{
+			name:     "Cost policy never sends more items",
+			quantity: 251,
+			opts:     Options{Costs: map[int]int{250: 10, 500: 5, 1000: 1}, Policy: PolicyCost},
+			want:     map[int]int{500: 1},
+		},
+		{
+			name:     "Pack count breaks cost ties",
+			quantity: 1000,
+			opts:     Options{Costs: map[int]int{250: 2, 500: 4, 1000: 8}, Policy: PolicyCost},
+			want:     map[int]int{1000: 1},
+		},
+		{
+			name:     "Cost breaks pack count ties",
+			quantity: 1500,
+			opts:     Options{Costs: map[int]int{250: 1, 500: 1, 1000: 100}, Policy: PolicyDefault},
+			want:     map[int]int{1000: 1, 500: 1},
+		},
+		{
+			name:     "Cost policy respects stock",
+			quantity: 1000,
+			opts:     Options{Costs: map[int]int{250: 1, 500: 3, 1000: 6}, Stock: map[int]int{250: 2}, Policy: PolicyCost},
+			want:     map[int]int{250: 2, 500: 1},
+		},
+		{
+			name:     "Fewest packs before least items",
+			quantity: 1750,
+			opts:     Options{Policy: PolicyFewestPacks},
+			want:     map[int]int{1000: 2},
+		},
+		{
+			name:     "Fewest sizes before fewest packs",
+			quantity: 1500,
+			opts:     Options{Policy: PolicyFewestSizes},
+			want:     map[int]int{500: 3},
+		},
+		{
+			name:     "Fewest packs within overage limit",
+			quantity: 1750,
+			opts:     Options{Policy: Policy{Criteria: PolicyFewestPacks.Criteria, MaxOveragePercent: 10}},
+			want:     map[int]int{1000: 1, 500: 1, 250: 1},
+		},
+		{
+			name:     "Overage limit cannot be met",
+			quantity: 1001,
+			opts:     Options{Policy: Policy{MaxOveragePercent: 10}},
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown criterion",
+			quantity: 1000,
+			opts:     Options{Policy: Policy{Criteria: []Criterion{"speed"}}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.CalculateWithOptions(context.Background(), tt.quantity, packSizes, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CalculateWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("CalculateWithOptions() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundedPackCalculator_PoliciesMatchBruteForce(t *testing.T) {
+	calc := NewBoundedPackCalculator()
+	packSizes := []int{3, 7, 10}
+	costs := map[int]int{3: 4, 7: 5, 10: 9}
+	stock := map[int]int{3: 3, 10: 2}
+
+	for _, policy := range append(Policies(), Policy{Criteria: []Criterion{CriterionDistinctSizes, CriterionCost}}) {
+		for quantity := 1; quantity <= 40; quantity++ {
+			want := bruteForcePolicy(quantity, packSizes, stock, costs, policy)
+
+			result, err := calc.CalculateWithOptions(context.Background(), quantity, packSizes, Options{Stock: stock, Costs: costs, Policy: policy})
+			if err != nil {
+				t.Fatalf("CalculateWithOptions(%d, %v) error = %v", quantity, policy.Criteria, err)
+			}
+
+			got := rankBreakdown(quantity, result, costs, policy)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("CalculateWithOptions(%d, %v) = %v ranked %v, want rank %v",
+					quantity, policy.Criteria, result, got, want)
+			}
+		}
+	}
+}
+
+// bruteForcePolicy returns the best rank over every combination of up to 10 packs per size
+func bruteForcePolicy(quantity int, packSizes []int, stock, costs map[int]int, policy Policy) []int {
+	var best []int
+	breakdown := make(map[int]int)
+
+	var search func(i int)
+	search = func(i int) {
+		if i == len(packSizes) {
+			if items, _ := totals(breakdown); items >= quantity {
+				rank := rankBreakdown(quantity, breakdown, costs, policy)
+				if best == nil || lessRank(rank, best) {
+					best = rank
+				}
+			}
+			return
+		}
+
+		limit := 10
+		if available, ok := stock[packSizes[i]]; ok {
+			limit = available
+		}
+		for count := 0; count <= limit; count++ {
+			breakdown[packSizes[i]] = count
+			search(i + 1)
+		}
+		delete(breakdown, packSizes[i])
+	}
+
+	search(0)
+	return best
+}
+
+// rankBreakdown lays out the policy criteria of a breakdown in policy order
+func rankBreakdown(quantity int, breakdown map[int]int, costs map[int]int, policy Policy) []int {
+	items, packs := totals(breakdown)
+	cost, distinct := 0, 0
+	for size, count := range breakdown {
+		cost += costs[size] * count
+		if count > 0 {
+			distinct++
+		}
+	}
+
+	values := map[Criterion]int{
+		CriterionItemsOver:     items - quantity,
+		CriterionPackCount:     packs,
+		CriterionCost:          cost,
+		CriterionDistinctSizes: distinct,
+	}
+
+	rank := make([]int, 0, len(policy.criteria()))
+	for _, criterion := range policy.criteria() {
+		rank = append(rank, values[criterion])
+	}
+	return rank
+}
@@ -0,0 +1,188 @@
+package calculator
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedPackCalculator decorates a PackCalculator with a bounded LRU cache of results
+// Results are keyed on the quantity and the normalized pack sizes, so the order and
+// duplicates of the pack sizes do not matter. Errors are never cached
+type CachedPackCalculator struct {
+	calculator PackCalculator
+	capacity   int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	stats   CacheStats
+}
+
+// CacheStats reports how a CachedPackCalculator has been used
+type CacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	Capacity      int     `json:"capacity"`
+	TTLSeconds    float64 `json:"ttl_seconds"`
+	Entries       int     `json:"entries"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Evictions     uint64  `json:"evictions"`
+	Expirations   uint64  `json:"expirations"`
+	Invalidations uint64  `json:"invalidations"`
+}
+
+type cacheEntry struct {
+	key       string
+	breakdown map[int]int
+	expires   time.Time // zero when entries never expire
+}
+
+// NewCachedPackCalculator caches up to capacity results of calc, each for ttl
+// A capacity below 1 keeps a single result; a zero ttl keeps results until evicted
+func NewCachedPackCalculator(calc PackCalculator, capacity int, ttl time.Duration) *CachedPackCalculator {
+	capacity = max(capacity, 1)
+	return &CachedPackCalculator{
+		calculator: calc,
+		capacity:   capacity,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element, capacity),
+		order:      list.New(),
+	}
+}
+
+// Calculate returns the cached distribution for quantity and packSizes, or calculates
+// and caches it
+func (c *CachedPackCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	if quantity <= 0 {
+		return c.calculator.Calculate(ctx, quantity, packSizes)
+	}
+
+	key := cacheKey(quantity, packSizes)
+	if breakdown, ok := c.get(key); ok {
+		return breakdown, nil
+	}
+
+	breakdown, err := c.calculator.Calculate(ctx, quantity, packSizes)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, breakdown)
+	return breakdown, nil
+}
+
+// Algorithm returns the algorithm name of the cached calculator
+func (c *CachedPackCalculator) Algorithm() string {
+	return Algorithm(c.calculator)
+}
+
+// Invalidate drops every cached result
+func (c *CachedPackCalculator) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+	c.stats.Invalidations++
+}
+
+// Stats returns the cache statistics since the calculator was created
+func (c *CachedPackCalculator) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Enabled = true
+	stats.Capacity = c.capacity
+	stats.TTLSeconds = c.ttl.Seconds()
+	stats.Entries = c.order.Len()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// get returns a copy of the cached breakdown for key, dropping it when expired
+func (c *CachedPackCalculator) get(key string) (map[int]int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return copyBreakdown(entry.breakdown), true
+}
+
+// put caches a copy of breakdown under key, evicting the least recently used entry when full
+func (c *CachedPackCalculator) put(key string, breakdown map[int]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, breakdown: copyBreakdown(breakdown)}
+	if c.ttl > 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+
+	// Another call may have calculated the same key meanwhile
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(entry)
+}
+
+func (c *CachedPackCalculator) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// cacheKey identifies a calculation by its quantity and normalized pack sizes
+func cacheKey(quantity int, packSizes []int) string {
+	return strconv.Itoa(quantity) + ":" + packSetKey(normalizePackSizes(packSizes))
+}
+
+// packSetKey identifies a set of normalized pack sizes
+func packSetKey(sizes []int) string {
+	var key strings.Builder
+	for i, size := range sizes {
+		if i > 0 {
+			key.WriteByte(',')
+		}
+		key.WriteString(strconv.Itoa(size))
+	}
+	return key.String()
+}
+
+func copyBreakdown(breakdown map[int]int) map[int]int {
+	result := make(map[int]int, len(breakdown))
+	for size, count := range breakdown {
+		result[size] = count
+	}
+	return result
+}
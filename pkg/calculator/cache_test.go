@@ -0,0 +1,166 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countingCalculator counts the calls reaching the dynamic calculator
+type countingCalculator struct {
+	calls int
+	err   error
+}
+
+func (c *countingCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return NewDynamicPackCalculator().Calculate(ctx, quantity, packSizes)
+}
+
+func TestCachedPackCalculator_Calculate(t *testing.T) {
+	packSizes := []int{250, 500, 1000, 2000, 5000}
+
+	tests := []struct {
+		name      string
+		capacity  int
+		calls     []int   // quantities calculated in order
+		sizes     [][]int // pack sizes per call, packSizes when nil
+		wantCalls int     // calls reaching the wrapped calculator
+		wantStats CacheStats
+	}{
+		{
+			name:      "Repeated quantity is served from the cache",
+			capacity:  10,
+			calls:     []int{501, 501, 501},
+			wantCalls: 1,
+			wantStats: CacheStats{Entries: 1, Hits: 2, Misses: 1},
+		},
+		{
+			name:      "Pack sizes are normalized",
+			capacity:  10,
+			calls:     []int{501, 501, 501},
+			sizes:     [][]int{{250, 500, 1000}, {1000, 250, 500}, {500, 500, 1000, 250, -1}},
+			wantCalls: 1,
+			wantStats: CacheStats{Entries: 1, Hits: 2, Misses: 1},
+		},
+		{
+			name:      "Different pack sizes are different entries",
+			capacity:  10,
+			calls:     []int{501, 501},
+			sizes:     [][]int{{250, 500}, {250, 1000}},
+			wantCalls: 2,
+			wantStats: CacheStats{Entries: 2, Misses: 2},
+		},
+		{
+			name:      "Least recently used entry is evicted",
+			capacity:  2,
+			calls:     []int{1, 2, 1, 3, 1, 2},
+			wantCalls: 4,
+			wantStats: CacheStats{Entries: 2, Hits: 2, Misses: 4, Evictions: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &countingCalculator{}
+			calc := NewCachedPackCalculator(inner, tt.capacity, 0)
+
+			for i, quantity := range tt.calls {
+				sizes := packSizes
+				if tt.sizes != nil {
+					sizes = tt.sizes[i]
+				}
+				want, _ := NewDynamicPackCalculator().Calculate(context.Background(), quantity, sizes)
+				got, err := calc.Calculate(context.Background(), quantity, sizes)
+				if err != nil {
+					t.Fatalf("Calculate() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Calculate(%d) = %v, want %v", quantity, got, want)
+				}
+			}
+
+			if inner.calls != tt.wantCalls {
+				t.Errorf("Wrapped calculator called %d times, want %d", inner.calls, tt.wantCalls)
+			}
+			stats := calc.Stats()
+			if stats.Entries != tt.wantStats.Entries || stats.Hits != tt.wantStats.Hits ||
+				stats.Misses != tt.wantStats.Misses || stats.Evictions != tt.wantStats.Evictions {
+				t.Errorf("Stats() = %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestCachedPackCalculator_TTL(t *testing.T) {
+	inner := &countingCalculator{}
+	calc := NewCachedPackCalculator(inner, 10, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	calc.now = func() time.Time { return now }
+
+	packSizes := []int{250, 500}
+	calc.Calculate(context.Background(), 501, packSizes)
+	now = now.Add(59 * time.Second)
+	calc.Calculate(context.Background(), 501, packSizes)
+	if inner.calls != 1 {
+		t.Errorf("Wrapped calculator called %d times before the TTL, want 1", inner.calls)
+	}
+
+	now = now.Add(time.Second)
+	calc.Calculate(context.Background(), 501, packSizes)
+	if inner.calls != 2 {
+		t.Errorf("Wrapped calculator called %d times after the TTL, want 2", inner.calls)
+	}
+
+	stats := calc.Stats()
+	if stats.Expirations != 1 || stats.Hits != 1 || stats.Misses != 2 || stats.TTLSeconds != 60 {
+		t.Errorf("Stats() = %+v, want 1 expiration, 1 hit, 2 misses and a TTL of 60 seconds", stats)
+	}
+}
+
+func TestCachedPackCalculator_Invalidate(t *testing.T) {
+	inner := &countingCalculator{}
+	calc := NewCachedPackCalculator(inner, 10, 0)
+
+	calc.Calculate(context.Background(), 501, []int{250, 500})
+	calc.Invalidate()
+	calc.Calculate(context.Background(), 501, []int{250, 500})
+
+	if inner.calls != 2 {
+		t.Errorf("Wrapped calculator called %d times, want 2", inner.calls)
+	}
+	if stats := calc.Stats(); stats.Invalidations != 1 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 1 invalidation and 1 entry", stats)
+	}
+}
+
+func TestCachedPackCalculator_DoesNotCacheErrors(t *testing.T) {
+	inner := &countingCalculator{err: ErrTimeout}
+	calc := NewCachedPackCalculator(inner, 10, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := calc.Calculate(context.Background(), 501, []int{250, 500}); !errors.Is(err, ErrTimeout) {
+			t.Errorf("Calculate() error = %v, want %v", err, ErrTimeout)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("Wrapped calculator called %d times, want 2", inner.calls)
+	}
+}
+
+func TestCachedPackCalculator_ReturnsCopies(t *testing.T) {
+	calc := NewCachedPackCalculator(NewDynamicPackCalculator(), 10, 0)
+
+	first, _ := calc.Calculate(context.Background(), 501, []int{250, 500})
+	first[250] = 100
+
+	second, _ := calc.Calculate(context.Background(), 501, []int{250, 500})
+	if second[250] != 1 {
+		t.Errorf("Calculate() = %v, modifying an earlier result changed the cache", second)
+	}
+}
@@ -0,0 +1,191 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Comparison compares the distributions of the same quantities under two pack size sets
+type Comparison struct {
+	Base       []int                `json:"base_pack_sizes"`
+	Candidate  []int                `json:"candidate_pack_sizes"`
+	Quantities []QuantityComparison `json:"quantities"`
+	Summary    ComparisonSummary    `json:"summary"`
+}
+
+// QuantityComparison compares the distributions of one quantity
+// The differences are candidate minus base, and zero unless both sets reach the quantity
+type QuantityComparison struct {
+	Quantity    int            `json:"quantity"`
+	Base        CompareOutcome `json:"base"`
+	Candidate   CompareOutcome `json:"candidate"`
+	ItemsDiff   int            `json:"items_diff"`
+	OverageDiff int            `json:"overage_diff"`
+	PacksDiff   int            `json:"packs_diff"`
+}
+
+// CompareOutcome is the distribution of one quantity under one pack size set
+// Overage is the number of items shipped beyond the quantity
+type CompareOutcome struct {
+	PackBreakdown map[int]int `json:"pack_breakdown,omitempty"`
+	TotalItems    int         `json:"total_items"`
+	Overage       int         `json:"overage"`
+	TotalPacks    int         `json:"total_packs"`
+	Unreachable   bool        `json:"unreachable,omitempty"`
+}
+
+// ComparisonSummary aggregates a comparison
+// Better, Worse and Same count the quantities where the candidate ships fewer, more or as
+// many items, then packs, as the base; reaching a quantity beats not reaching it
+type ComparisonSummary struct {
+	Quantities  int           `json:"quantities"`
+	Base        CompareTotals `json:"base"`
+	Candidate   CompareTotals `json:"candidate"`
+	ItemsDiff   int           `json:"items_diff"`
+	OverageDiff int           `json:"overage_diff"`
+	PacksDiff   int           `json:"packs_diff"`
+	Better      int           `json:"better"`
+	Worse       int           `json:"worse"`
+	Same        int           `json:"same"`
+}
+
+// CompareTotals sums the outcomes of one pack size set over the quantities it reaches
+type CompareTotals struct {
+	TotalItems     int     `json:"total_items"`
+	TotalOverage   int     `json:"total_overage"`
+	MaxOverage     int     `json:"max_overage"`
+	AverageOverage float64 `json:"average_overage"`
+	TotalPacks     int     `json:"total_packs"`
+	Unreachable    int     `json:"unreachable"`
+}
+
+// QuantityRange returns the quantities from from to to, every step
+func QuantityRange(from, to, step int) ([]int, error) {
+	switch {
+	case from <= 0:
+		return nil, fmt.Errorf("range must start above 0, got: %d", from)
+	case to < from:
+		return nil, fmt.Errorf("range end %d is before its start %d", to, from)
+	case step <= 0:
+		return nil, fmt.Errorf("range step must be positive, got: %d", step)
+	}
+
+	quantities := make([]int, 0, (to-from)/step+1)
+	for quantity := from; quantity <= to; quantity += step {
+		quantities = append(quantities, quantity)
+		if quantity > to-step {
+			break // the next step would overflow
+		}
+	}
+	return quantities, nil
+}
+
+// Compare calculates every quantity with the base and the candidate pack sizes using calc
+// A quantity a set cannot reach is reported as unreachable; any other error stops the
+// comparison. With a calculator sharing its tables each set only builds one table
+func Compare(ctx context.Context, calc PackCalculator, quantities []int, base, candidate []int) (*Comparison, error) {
+	comparison := &Comparison{
+		Base:       normalizePackSizes(base),
+		Candidate:  normalizePackSizes(candidate),
+		Quantities: make([]QuantityComparison, 0, len(quantities)),
+	}
+	if len(comparison.Base) == 0 || len(comparison.Candidate) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	summary := &comparison.Summary
+	for _, quantity := range quantities {
+		baseOutcome, err := compareOutcome(ctx, calc, quantity, comparison.Base)
+		if err != nil {
+			return nil, fmt.Errorf("base pack sizes: %w", err)
+		}
+		candidateOutcome, err := compareOutcome(ctx, calc, quantity, comparison.Candidate)
+		if err != nil {
+			return nil, fmt.Errorf("candidate pack sizes: %w", err)
+		}
+
+		entry := QuantityComparison{Quantity: quantity, Base: baseOutcome, Candidate: candidateOutcome}
+		if !baseOutcome.Unreachable && !candidateOutcome.Unreachable {
+			entry.ItemsDiff = candidateOutcome.TotalItems - baseOutcome.TotalItems
+			entry.OverageDiff = candidateOutcome.Overage - baseOutcome.Overage
+			entry.PacksDiff = candidateOutcome.TotalPacks - baseOutcome.TotalPacks
+		}
+		comparison.Quantities = append(comparison.Quantities, entry)
+
+		summary.Quantities++
+		summary.Base.add(baseOutcome)
+		summary.Candidate.add(candidateOutcome)
+		summary.ItemsDiff += entry.ItemsDiff
+		summary.OverageDiff += entry.OverageDiff
+		summary.PacksDiff += entry.PacksDiff
+		switch order := compareOutcomes(candidateOutcome, baseOutcome); {
+		case order < 0:
+			summary.Better++
+		case order > 0:
+			summary.Worse++
+		default:
+			summary.Same++
+		}
+	}
+
+	summary.Base.average(summary.Quantities)
+	summary.Candidate.average(summary.Quantities)
+	return comparison, nil
+}
+
+// compareOutcome calculates quantity with sizes
+func compareOutcome(ctx context.Context, calc PackCalculator, quantity int, sizes []int) (CompareOutcome, error) {
+	breakdown, err := calc.Calculate(ctx, quantity, sizes)
+	if errors.Is(err, ErrUnreachable) {
+		return CompareOutcome{Unreachable: true}, nil
+	}
+	if err != nil {
+		return CompareOutcome{}, err
+	}
+
+	outcome := CompareOutcome{PackBreakdown: breakdown}
+	for size, count := range breakdown {
+		outcome.TotalItems += size * count
+		outcome.TotalPacks += count
+	}
+	outcome.Overage = outcome.TotalItems - quantity
+	return outcome, nil
+}
+
+// compareOutcomes orders a before b when it ships fewer items, then fewer packs
+func compareOutcomes(a, b CompareOutcome) int {
+	switch {
+	case a.Unreachable || b.Unreachable:
+		return boolOrder(a.Unreachable) - boolOrder(b.Unreachable)
+	case a.TotalItems != b.TotalItems:
+		return a.TotalItems - b.TotalItems
+	default:
+		return a.TotalPacks - b.TotalPacks
+	}
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (t *CompareTotals) add(outcome CompareOutcome) {
+	if outcome.Unreachable {
+		t.Unreachable++
+		return
+	}
+	t.TotalItems += outcome.TotalItems
+	t.TotalOverage += outcome.Overage
+	t.MaxOverage = max(t.MaxOverage, outcome.Overage)
+	t.TotalPacks += outcome.TotalPacks
+}
+
+// average sets the average overage once the outcomes of quantities were added
+func (t *CompareTotals) average(quantities int) {
+	if reached := quantities - t.Unreachable; reached > 0 {
+		t.AverageOverage = float64(t.TotalOverage) / float64(reached)
+	}
+}
@@ -0,0 +1,151 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuantityRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		from, to, step int
+		want           []int
+		wantErr        bool
+	}{
+		{"Every quantity", 1, 5, 1, []int{1, 2, 3, 4, 5}, false},
+		{"Every 250", 250, 1000, 250, []int{250, 500, 750, 1000}, false},
+		{"End not on a step", 1, 10, 4, []int{1, 5, 9}, false},
+		{"Single quantity", 7, 7, 1, []int{7}, false},
+		{"Zero start", 0, 5, 1, nil, true},
+		{"End before start", 5, 1, 1, nil, true},
+		{"Zero step", 1, 5, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QuantityRange(tt.from, tt.to, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QuantityRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QuantityRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	comparison, err := Compare(context.Background(), calc, []int{1, 251, 501, 750}, []int{500, 250, 1000}, []int{300, 600, 600})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(comparison.Base, []int{250, 500, 1000}) || !reflect.DeepEqual(comparison.Candidate, []int{300, 600}) {
+		t.Errorf("Compare() pack sizes = %v and %v, want them normalized", comparison.Base, comparison.Candidate)
+	}
+
+	// 1: 250 vs 300, 251: 500 vs 300, 501: 750 vs 600, 750: 750 vs 900
+	wantDiffs := []int{50, -200, -150, 150}
+	for i, entry := range comparison.Quantities {
+		if entry.ItemsDiff != wantDiffs[i] || entry.OverageDiff != wantDiffs[i] {
+			t.Errorf("Quantity %d items and overage diffs = %d and %d, want %d", entry.Quantity, entry.ItemsDiff, entry.OverageDiff, wantDiffs[i])
+		}
+		if entry.Base.Overage != entry.Base.TotalItems-entry.Quantity {
+			t.Errorf("Quantity %d base overage = %d, want %d", entry.Quantity, entry.Base.Overage, entry.Base.TotalItems-entry.Quantity)
+		}
+	}
+
+	want := ComparisonSummary{
+		Quantities:  4,
+		Base:        CompareTotals{TotalItems: 2250, TotalOverage: 747, MaxOverage: 249, AverageOverage: 186.75, TotalPacks: 6},
+		Candidate:   CompareTotals{TotalItems: 2100, TotalOverage: 597, MaxOverage: 299, AverageOverage: 149.25, TotalPacks: 5},
+		ItemsDiff:   -150,
+		OverageDiff: -150,
+		PacksDiff:   -1,
+		Better:      2,
+		Worse:       2,
+	}
+	if comparison.Summary != want {
+		t.Errorf("Compare() summary = %+v, want %+v", comparison.Summary, want)
+	}
+}
+
+// unreachableCalculator cannot reach quantities above limit with the pack sizes in unreachable
+type unreachableCalculator struct {
+	limit       int
+	unreachable []int
+}
+
+func (c unreachableCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	if quantity > c.limit && reflect.DeepEqual(packSizes, c.unreachable) {
+		return nil, ErrUnreachable
+	}
+	return NewDynamicPackCalculator().Calculate(ctx, quantity, packSizes)
+}
+
+func TestCompare_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		calc      PackCalculator
+		base      []int
+		candidate []int
+		want      ComparisonSummary
+		wantErr   error
+	}{
+		{
+			name:      "Unreachable candidate is worse",
+			calc:      unreachableCalculator{limit: 250, unreachable: []int{300}},
+			base:      []int{250},
+			candidate: []int{300},
+			want: ComparisonSummary{
+				Quantities:  2,
+				Base:        CompareTotals{TotalItems: 1000, TotalOverage: 498, MaxOverage: 249, AverageOverage: 249, TotalPacks: 4},
+				Candidate:   CompareTotals{TotalItems: 300, TotalOverage: 299, MaxOverage: 299, AverageOverage: 299, TotalPacks: 1, Unreachable: 1},
+				ItemsDiff:   50,
+				OverageDiff: 50,
+				Worse:       2,
+			},
+		},
+		{
+			name:      "Unreachable in both is the same",
+			calc:      unreachableCalculator{limit: 0, unreachable: []int{300}},
+			base:      []int{300},
+			candidate: []int{300},
+			want: ComparisonSummary{
+				Quantities: 2,
+				Base:       CompareTotals{Unreachable: 2},
+				Candidate:  CompareTotals{Unreachable: 2},
+				Same:       2,
+			},
+		},
+		{
+			name:      "No base pack sizes",
+			calc:      NewDynamicPackCalculator(),
+			candidate: []int{300},
+			wantErr:   ErrNoPackSizes,
+		},
+		{
+			name:      "Calculation error",
+			calc:      NewDynamicPackCalculator().WithLimits(Limits{MaxQuantity: 100}),
+			base:      []int{250},
+			candidate: []int{300},
+			wantErr:   ErrQuantityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison, err := Compare(context.Background(), tt.calc, []int{1, 501}, tt.base, tt.candidate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Compare() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && comparison.Summary != tt.want {
+				t.Errorf("Compare() summary = %+v, want %+v", comparison.Summary, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the calculators
+var (
+	// ErrNoPackSizes is returned when no positive pack size is given
+	ErrNoPackSizes = errors.New("no valid pack sizes")
+	// ErrQuantityTooLarge is returned when the quantity is above the limit or overflows
+	ErrQuantityTooLarge = errors.New("quantity too large")
+	// ErrTableTooLarge is returned when the calculation needs more table cells than allowed
+	ErrTableTooLarge = errors.New("calculation table too large")
+	// ErrTimeout is returned when the calculation runs longer than allowed
+	ErrTimeout = errors.New("calculation timed out")
+	// ErrCanceled is returned when the caller gave up on the calculation
+	ErrCanceled = errors.New("calculation canceled")
+	// ErrUnreachable is returned when no combination of packs covers the quantity
+	ErrUnreachable = errors.New("no pack combination reaches the quantity")
+	// ErrInsufficientStock is returned when the available packs cannot cover the order
+	ErrInsufficientStock = errors.New("insufficient stock to fulfill the order")
+	// ErrOverageLimit is returned when no distribution stays within the policy overage limit
+	ErrOverageLimit = errors.New("no pack distribution within the overage limit")
+	// ErrNoOrders is returned when pack sizes are recommended without any order to pack
+	ErrNoOrders = errors.New("no orders to recommend pack sizes for")
+)
+
+// ErrAlternativesTooLarge is returned when the table needed to enumerate alternatives
+// would be too big for the quantity and pack sizes
+var ErrAlternativesTooLarge = fmt.Errorf("%w to enumerate alternatives", ErrTableTooLarge)
@@ -0,0 +1,164 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// explainedRunnersUp is how many reachable amounts above the minimum an explanation lists
+const explainedRunnersUp = 3
+
+// Explanation traces how Calculate chose its breakdown under the default rules
+type Explanation struct {
+	// MinimumAmount is the least reachable amount not below the quantity (rule 2)
+	MinimumAmount int
+	// Candidates are the amounts considered in increasing order
+	// Consecutive unreachable amounts are grouped into one range
+	Candidates []AmountCandidate
+	// PackCounts compares the fewest packs reaching MinimumAmount by the last pack added (rule 3)
+	PackCounts []PackCountOption
+	// Breakdown is the chosen distribution
+	Breakdown map[int]int
+}
+
+// AmountCandidate is an amount, or a range of amounts, that could fulfill the order
+type AmountCandidate struct {
+	From      int
+	To        int // equal to From for a single amount
+	Reachable bool
+	Chosen    bool
+	Reason    string
+}
+
+// PackCountOption is the best distribution of the minimum amount ending with one pack size
+type PackCountOption struct {
+	LastPack  int
+	Packs     int         // 0 when the rest cannot be made from the pack sizes
+	Breakdown map[int]int // nil when the rest cannot be made from the pack sizes
+	Chosen    bool
+	Reason    string
+}
+
+// Explain calculates the distribution for quantity like Calculate and records
+// the amounts and pack counts it compared along the way
+func (c *DynamicPackCalculator) Explain(ctx context.Context, quantity int, packSizes []int) (*Explanation, error) {
+	if quantity <= 0 {
+		return &Explanation{Breakdown: map[int]int{}}, nil
+	}
+
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	// The runners-up need the table to span one more largest pack than Calculate
+	span, err := tableSpan(quantity, sizes, 2)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.limits.checkTableSize(span); err != nil {
+		return nil, err
+	}
+
+	minAmount, err := c.findMinimumAmount(ctx, quantity, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Adding the largest pack to a reachable amount always reaches another one,
+	// so the table holds at least one runner-up
+	packs, parent, err := c.buildPacksTable(ctx, minAmount+sizes[len(sizes)-1], sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		MinimumAmount: minAmount,
+		Candidates:    explainAmounts(quantity, minAmount, packs),
+		PackCounts:    explainPackCounts(minAmount, sizes, packs, parent),
+		Breakdown:     backtrackPacks(parent, minAmount),
+	}
+	return explanation, nil
+}
+
+// explainAmounts lists the unreachable amounts below minAmount, minAmount itself
+// and the next reachable amounts that would send more items
+func explainAmounts(quantity, minAmount int, packs []int) []AmountCandidate {
+	var candidates []AmountCandidate
+
+	if minAmount > quantity {
+		candidates = append(candidates, AmountCandidate{
+			From:   quantity,
+			To:     minAmount - 1,
+			Reason: "no combination of pack sizes adds up to these amounts",
+		})
+	}
+
+	candidates = append(candidates, AmountCandidate{
+		From:      minAmount,
+		To:        minAmount,
+		Reachable: true,
+		Chosen:    true,
+		Reason:    "least amount of items that fulfills the order",
+	})
+
+	runnersUp := 0
+	for amount := minAmount + 1; amount < len(packs) && runnersUp < explainedRunnersUp; amount++ {
+		if packs[amount] == math.MaxInt32 {
+			continue
+		}
+		candidates = append(candidates, AmountCandidate{
+			From:      amount,
+			To:        amount,
+			Reachable: true,
+			Reason:    fmt.Sprintf("sends %d more items than %d", amount-minAmount, minAmount),
+		})
+		runnersUp++
+	}
+
+	return candidates
+}
+
+// explainPackCounts compares every last pack findMinimumPacks considers for target
+// Like findMinimumPacks, the smallest pack size wins a tie
+func explainPackCounts(target int, sizes []int, packs, parent []int) []PackCountOption {
+	best := parent[target]
+	var options []PackCountOption
+
+	for _, size := range sizes {
+		if size > target {
+			break
+		}
+
+		option := PackCountOption{LastPack: size}
+		rest := target - size
+		if packs[rest] == math.MaxInt32 {
+			option.Reason = fmt.Sprintf("the remaining %d items cannot be made from the pack sizes", rest)
+			options = append(options, option)
+			continue
+		}
+
+		option.Packs = packs[rest] + 1
+		option.Breakdown = backtrackPacks(parent, rest)
+		option.Breakdown[size]++
+
+		switch {
+		case size == best:
+			option.Chosen = true
+			option.Reason = fmt.Sprintf("fewest packs to send %d items", target)
+		case option.Packs > packs[target]:
+			option.Reason = fmt.Sprintf("uses %d packs instead of %d", option.Packs, packs[target])
+		default:
+			option.Reason = fmt.Sprintf("same number of packs as ending with %d, which was considered first", best)
+		}
+		options = append(options, option)
+	}
+
+	return options
+}
@@ -0,0 +1,104 @@
+package calculator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDynamicPackCalculator_Explain(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+	packSizes := []int{250, 500, 1000, 2000, 5000}
+
+	tests := []struct {
+		name           string
+		quantity       int
+		wantMinimum    int
+		wantCandidates []AmountCandidate
+		wantPackCounts []PackCountOption
+	}{
+		{
+			name:        "Unreachable amounts are grouped",
+			quantity:    251,
+			wantMinimum: 500,
+			wantCandidates: []AmountCandidate{
+				{From: 251, To: 499, Reason: "no combination of pack sizes adds up to these amounts"},
+				{From: 500, To: 500, Reachable: true, Chosen: true, Reason: "least amount of items that fulfills the order"},
+				{From: 750, To: 750, Reachable: true, Reason: "sends 250 more items than 500"},
+				{From: 1000, To: 1000, Reachable: true, Reason: "sends 500 more items than 500"},
+				{From: 1250, To: 1250, Reachable: true, Reason: "sends 750 more items than 500"},
+			},
+			wantPackCounts: []PackCountOption{
+				{LastPack: 250, Packs: 2, Breakdown: map[int]int{250: 2}, Reason: "uses 2 packs instead of 1"},
+				{LastPack: 500, Packs: 1, Breakdown: map[int]int{500: 1}, Chosen: true, Reason: "fewest packs to send 500 items"},
+			},
+		},
+		{
+			name:        "Ties go to the pack considered first",
+			quantity:    750,
+			wantMinimum: 750,
+			wantCandidates: []AmountCandidate{
+				{From: 750, To: 750, Reachable: true, Chosen: true, Reason: "least amount of items that fulfills the order"},
+				{From: 1000, To: 1000, Reachable: true, Reason: "sends 250 more items than 750"},
+				{From: 1250, To: 1250, Reachable: true, Reason: "sends 500 more items than 750"},
+				{From: 1500, To: 1500, Reachable: true, Reason: "sends 750 more items than 750"},
+			},
+			wantPackCounts: []PackCountOption{
+				{LastPack: 250, Packs: 2, Breakdown: map[int]int{250: 1, 500: 1}, Chosen: true, Reason: "fewest packs to send 750 items"},
+				{LastPack: 500, Packs: 2, Breakdown: map[int]int{250: 1, 500: 1}, Reason: "same number of packs as ending with 250, which was considered first"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation, err := calc.Explain(context.Background(), tt.quantity, packSizes)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if explanation.MinimumAmount != tt.wantMinimum {
+				t.Errorf("MinimumAmount = %d, want %d", explanation.MinimumAmount, tt.wantMinimum)
+			}
+			if !reflect.DeepEqual(explanation.Candidates, tt.wantCandidates) {
+				t.Errorf("Candidates = %+v, want %+v", explanation.Candidates, tt.wantCandidates)
+			}
+			if !reflect.DeepEqual(explanation.PackCounts, tt.wantPackCounts) {
+				t.Errorf("PackCounts = %+v, want %+v", explanation.PackCounts, tt.wantPackCounts)
+			}
+		})
+	}
+}
+
+func TestDynamicPackCalculator_Explain_MatchesCalculate(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	for _, tt := range calculateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizes := append([]int(nil), tt.packSizes...)
+			explanation, err := calc.Explain(context.Background(), tt.quantity, tt.packSizes)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			want, _ := calc.Calculate(context.Background(), tt.quantity, sizes)
+			if !reflect.DeepEqual(explanation.Breakdown, want) {
+				t.Errorf("Breakdown = %v, want %v", explanation.Breakdown, want)
+			}
+			if explanation.MinimumAmount != tt.wantTotal {
+				t.Errorf("MinimumAmount = %d, want %d", explanation.MinimumAmount, tt.wantTotal)
+			}
+
+			chosen := 0
+			for _, option := range explanation.PackCounts {
+				if option.Chosen {
+					chosen++
+					if !reflect.DeepEqual(option.Breakdown, want) {
+						t.Errorf("Chosen pack count breakdown = %v, want %v", option.Breakdown, want)
+					}
+				}
+			}
+			if chosen != 1 {
+				t.Errorf("Explain() chose %d pack counts, want 1", chosen)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultMaxTableSize is the largest number of table cells a calculation allocates by default
+// The dynamic calculator needs one cell per amount up to the quantity plus the largest pack,
+// the bounded calculator one per amount and pack size, the residue one per unit of a pack size
+const DefaultMaxTableSize = 1 << 24
+
+// checkInterval is how many loop iterations run between two context checks
+const checkInterval = 1 << 14
+
+// Limits bounds the work of one calculation; zero fields are not limited
+type Limits struct {
+	// MaxQuantity is the largest quantity accepted
+	MaxQuantity int
+	// MaxTableSize is the largest number of table cells allocated
+	MaxTableSize int
+	// Timeout is the longest wall time a calculation may run
+	Timeout time.Duration
+}
+
+// DefaultLimits returns the limits the calculators use unless told otherwise
+// Only the table size is limited, so a calculation always fits in memory
+func DefaultLimits() Limits {
+	return Limits{MaxTableSize: DefaultMaxTableSize}
+}
+
+// Validate checks that no limit is negative
+func (l Limits) Validate() error {
+	switch {
+	case l.MaxQuantity < 0:
+		return fmt.Errorf("max quantity cannot be negative, got: %d", l.MaxQuantity)
+	case l.MaxTableSize < 0:
+		return fmt.Errorf("max table size cannot be negative, got: %d", l.MaxTableSize)
+	case l.Timeout < 0:
+		return fmt.Errorf("timeout cannot be negative, got: %s", l.Timeout)
+	}
+	return nil
+}
+
+// start checks the quantity and returns the context bounded by the timeout
+// The returned cancel function must be called once the calculation is done
+func (l Limits) start(ctx context.Context, quantity int) (context.Context, context.CancelFunc, error) {
+	if l.MaxQuantity > 0 && quantity > l.MaxQuantity {
+		return nil, nil, fmt.Errorf("%w: %d is more than %d", ErrQuantityTooLarge, quantity, l.MaxQuantity)
+	}
+	if err := checkContext(ctx); err != nil {
+		return nil, nil, err
+	}
+	if l.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// checkTableSize rejects tables of more than MaxTableSize cells
+// A negative size stands for a table whose size overflows
+func (l Limits) checkTableSize(cells int) error {
+	if cells < 0 {
+		return fmt.Errorf("%w: the number of cells overflows", ErrTableTooLarge)
+	}
+	if l.MaxTableSize > 0 && cells > l.MaxTableSize {
+		return fmt.Errorf("%w: %d cells are more than %d", ErrTableTooLarge, cells, l.MaxTableSize)
+	}
+	return nil
+}
+
+// tableCells returns rows*columns, or -1 when the product overflows
+func tableCells(rows, columns int) int {
+	if columns < 0 || rows > 0 && columns > math.MaxInt/rows {
+		return -1
+	}
+	return rows * columns
+}
+
+// tableSpan returns the number of amounts from 0 to quantity plus extra largest packs,
+// the length of the tables, or ErrQuantityTooLarge when it overflows
+func tableSpan(quantity int, sizes []int, extra int) (int, error) {
+	largest := tableCells(extra, sizes[len(sizes)-1])
+	if largest < 0 || quantity > math.MaxInt-largest-1 {
+		return 0, ErrQuantityTooLarge
+	}
+	return quantity + largest + 1, nil
+}
+
+// checkContext converts the end of ctx into ErrTimeout or ErrCanceled
+func checkContext(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+}
+
+// checkContextEvery checks ctx on every checkInterval-th iteration only,
+// so tight loops do not pay for the check
+func checkContextEvery(ctx context.Context, iteration int) error {
+	if iteration%checkInterval != 0 {
+		return nil
+	}
+	return checkContext(ctx)
+}
@@ -0,0 +1,143 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// cancelAfter is a context that is canceled once Err has been checked checks times
+type cancelAfter struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestPackCalculators_Limits(t *testing.T) {
+	limits := Limits{MaxQuantity: 1_000_000, MaxTableSize: 10_000}
+	calculators := map[string]PackCalculator{
+		"dynamic": NewDynamicPackCalculator().WithLimits(limits),
+		"residue": NewResiduePackCalculator().WithLimits(limits),
+		"bounded": NewBoundedPackCalculator().WithLimits(limits),
+	}
+
+	tests := []struct {
+		name      string
+		quantity  int
+		packSizes []int
+		want      map[string]error
+	}{
+		{
+			name:      "Within the limits",
+			quantity:  501,
+			packSizes: []int{250, 500, 1000},
+			want:      map[string]error{"dynamic": nil, "residue": nil, "bounded": nil},
+		},
+		{
+			name:      "Quantity above the limit",
+			quantity:  1_000_001,
+			packSizes: []int{250, 500, 1000},
+			want:      map[string]error{"dynamic": ErrQuantityTooLarge, "residue": ErrQuantityTooLarge, "bounded": ErrQuantityTooLarge},
+		},
+		{
+			name:      "Table above the limit",
+			quantity:  20_000,
+			packSizes: []int{250, 500, 1000},
+			want:      map[string]error{"dynamic": ErrTableTooLarge, "residue": nil, "bounded": ErrTableTooLarge},
+		},
+		{
+			name:      "Pack size above the table limit",
+			quantity:  10,
+			packSizes: []int{3, 20_000},
+			want:      map[string]error{"dynamic": ErrTableTooLarge, "residue": ErrTableTooLarge, "bounded": ErrTableTooLarge},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, calc := range calculators {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				_, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
+				if !errors.Is(err, tt.want[name]) {
+					t.Errorf("Calculate() error = %v, want %v", err, tt.want[name])
+				}
+			})
+		}
+	}
+}
+
+func TestPackCalculators_Context(t *testing.T) {
+	calculators := map[string]PackCalculator{
+		"dynamic": NewDynamicPackCalculator(),
+		"residue": NewResiduePackCalculator(),
+		"bounded": NewBoundedPackCalculator(),
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  func() context.Context
+		want error
+	}{
+		{"Canceled before the calculation", func() context.Context { return canceled }, ErrCanceled},
+		{"Deadline before the calculation", func() context.Context { return expired }, ErrTimeout},
+		{"Canceled during the calculation", func() context.Context { return &cancelAfter{Context: context.Background(), checks: 2} }, ErrCanceled},
+	}
+
+	for _, tt := range tests {
+		for name, calc := range calculators {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				// Large enough for every calculator to check the context more than twice
+				_, err := calc.Calculate(tt.ctx(), 2_000_000, []int{23, 31, 53, 64_007})
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Calculate() error = %v, want %v", err, tt.want)
+				}
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Calculate() error = %v, want it to wrap the context error", err)
+				}
+			})
+		}
+	}
+}
+
+func TestBoundedPackCalculator_Timeout(t *testing.T) {
+	calc := NewBoundedPackCalculator().WithLimits(Limits{MaxTableSize: DefaultMaxTableSize, Timeout: time.Millisecond})
+
+	_, err := calc.Calculate(context.Background(), 2_000_000, []int{23, 31, 53})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Calculate() error = %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"Default limits", DefaultLimits(), false},
+		{"No limits", Limits{}, false},
+		{"Negative quantity", Limits{MaxQuantity: -1}, true},
+		{"Negative table size", Limits{MaxTableSize: -1}, true},
+		{"Negative timeout", Limits{Timeout: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limits.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,22 +1,83 @@
 package calculator
 
 import (
+	"context"
+	"fmt"
 	"math"
-	"sort"
 )
 
+// Algorithm names accepted by NewPackCalculator
+const (
+	AlgorithmDynamic = "dynamic"
+	AlgorithmResidue = "residue"
+)
+
+// AlgorithmBounded names the BoundedPackCalculator, used for stock and policies
+const AlgorithmBounded = "bounded"
+
 // PackCalculator defines the interface for calculating pack distributions
+// Calculate stops with ErrTimeout or ErrCanceled once ctx is done
 type PackCalculator interface {
-	Calculate(quantity int, packSizes []int) (map[int]int, error)
+	Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error)
+}
+
+// Algorithm returns the algorithm name of calc, or an empty string when it reports none
+// Calculators report their name with an Algorithm() string method
+func Algorithm(calc PackCalculator) string {
+	if named, ok := calc.(interface{ Algorithm() string }); ok {
+		return named.Algorithm()
+	}
+	return ""
+}
+
+// NewPackCalculator creates the calculator for the given algorithm name, enforcing limits
+// An empty name selects the dynamic programming calculator
+func NewPackCalculator(algorithm string, limits Limits) (PackCalculator, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case "", AlgorithmDynamic:
+		return NewDynamicPackCalculator().WithLimits(limits), nil
+	case AlgorithmResidue:
+		return NewResiduePackCalculator().WithLimits(limits), nil
+	default:
+		return nil, fmt.Errorf("unknown calculator algorithm: %s", algorithm)
+	}
 }
 
 // DynamicPackCalculator implements PackCalculator using dynamic programming
 // This ensures we follow Rule 2 (minimize items) then Rule 3 (minimize packs)
-type DynamicPackCalculator struct{}
+type DynamicPackCalculator struct {
+	limits Limits
+	// tables are shared between calculations when set, built per calculation otherwise
+	tables *TableStore
+}
 
-// NewDynamicPackCalculator creates a new calculator instance
+// NewDynamicPackCalculator creates a new calculator instance with the default limits
 func NewDynamicPackCalculator() *DynamicPackCalculator {
-	return &DynamicPackCalculator{}
+	return &DynamicPackCalculator{limits: DefaultLimits()}
+}
+
+// WithLimits returns a copy of the calculator enforcing limits
+func (c *DynamicPackCalculator) WithLimits(limits Limits) *DynamicPackCalculator {
+	calc := *c
+	calc.limits = limits
+	return &calc
+}
+
+// Algorithm returns AlgorithmDynamic
+func (c *DynamicPackCalculator) Algorithm() string {
+	return AlgorithmDynamic
+}
+
+// WithTables returns a copy of the calculator reusing the tables of store across
+// calculations; a nil store builds the tables per calculation
+func (c *DynamicPackCalculator) WithTables(store *TableStore) *DynamicPackCalculator {
+	calc := *c
+	calc.tables = store
+	return &calc
 }
 
 // Calculate determines the optimal pack distribution for the given quantity
@@ -24,27 +85,47 @@ func NewDynamicPackCalculator() *DynamicPackCalculator {
 // 1. Only whole packs can be sent
 // 2. Send the least amount of items to fulfill the order
 // 3. Send as few packs as possible
-func (c *DynamicPackCalculator) Calculate(quantity int, packSizes []int) (map[int]int, error) {
+func (c *DynamicPackCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
 	if quantity <= 0 {
 		return map[int]int{}, nil
 	}
 
-	if len(packSizes) == 0 {
-		return nil, nil
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	span, err := tableSpan(quantity, sizes, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.limits.checkTableSize(span); err != nil {
+		return nil, err
 	}
 
-	// Sort pack sizes for consistent processing
-	sort.Ints(packSizes)
+	if c.tables != nil {
+		if breakdown, ok, err := c.tables.calculate(ctx, quantity, sizes, span); ok {
+			return breakdown, err
+		}
+	}
 
 	// Find the minimum amount that can fulfill the order
-	minAmount := c.findMinimumAmount(quantity, packSizes)
+	minAmount, err := c.findMinimumAmount(ctx, quantity, sizes)
+	if err != nil {
+		return nil, err
+	}
 
 	// Now find the minimum number of packs to achieve that amount
-	return c.findMinimumPacks(minAmount, packSizes), nil
+	return c.findMinimumPacks(ctx, minAmount, sizes)
 }
 
 // findMinimumAmount finds the minimum number of items >= quantity that can be made
-func (c *DynamicPackCalculator) findMinimumAmount(quantity int, packSizes []int) int {
+func (c *DynamicPackCalculator) findMinimumAmount(ctx context.Context, quantity int, packSizes []int) (int, error) {
 	// We'll search for the minimum achievable amount >= quantity
 	// Using a reasonable upper bound (quantity + largest pack size)
 	// The worst case is needing one extra largest pack! That's why we add it.
@@ -57,39 +138,9 @@ func (c *DynamicPackCalculator) findMinimumAmount(quantity int, packSizes []int)
 
 	// Build up the DP table
 	for i := 1; i <= maxSearch; i++ {
-		for _, pack := range packSizes {
-			if i >= pack && dp[i-pack] {
-				dp[i] = true
-				break
-			}
-		}
-	}
-
-	// Find the minimum amount >= quantity that is achievable
-	for amount := quantity; amount <= maxSearch; amount++ {
-		if dp[amount] {
-			return amount
+		if err := checkContextEvery(ctx, i); err != nil {
+			return 0, err
 		}
-	}
-
-	// If nothing found in range, extend search
-	// This handles edge cases with specific pack sizes
-	return c.findMinimumAmountExtended(quantity, packSizes)
-}
-
-// findMinimumAmountExtended extends the search for edge cases
-// This is a fallback for rare cases where initial search fails
-func (c *DynamicPackCalculator) findMinimumAmountExtended(quantity int, packSizes []int) int {
-	// For edge cases, we might need to search further
-	maxSearch := quantity * 2
-	if maxSearch < 10000 {
-		maxSearch = 10000
-	}
-
-	dp := make([]bool, maxSearch+1)
-	dp[0] = true
-
-	for i := 1; i <= maxSearch; i++ {
 		for _, pack := range packSizes {
 			if i >= pack && dp[i-pack] {
 				dp[i] = true
@@ -98,44 +149,30 @@ func (c *DynamicPackCalculator) findMinimumAmountExtended(quantity int, packSize
 		}
 	}
 
+	// Find the minimum amount >= quantity that is achievable
 	for amount := quantity; amount <= maxSearch; amount++ {
 		if dp[amount] {
-			return amount
+			return amount, nil
 		}
 	}
 
-	// Fallback: use greedy approach
-	return c.greedyMinimumAmount(quantity, packSizes)
+	// A multiple of the largest pack always lies in the range, so this means
+	// the pack sizes were not positive
+	return 0, ErrUnreachable
 }
 
-// greedyMinimumAmount uses greedy approach as fallback
-// This may not always yield optimal results but serves as a last resort
-// Getting from largest to smallest pack
-func (c *DynamicPackCalculator) greedyMinimumAmount(quantity int, packSizes []int) int {
-	remaining := quantity
-	total := 0
-
-	// Start from largest pack
-	for i := len(packSizes) - 1; i >= 0; i-- {
-		pack := packSizes[i]
-		packs := remaining / pack
-		if packs > 0 {
-			total += packs * pack
-			remaining -= packs * pack
-		}
-	}
-
-	if remaining > 0 {
-		// Add one more smallest pack to cover remaining
-		total += packSizes[0]
+// findMinimumPacks finds the minimum number of packs to achieve exact target amount
+func (c *DynamicPackCalculator) findMinimumPacks(ctx context.Context, target int, packSizes []int) (map[int]int, error) {
+	_, parent, err := c.buildPacksTable(ctx, target, packSizes)
+	if err != nil {
+		return nil, err
 	}
-
-	return total
+	return backtrackPacks(parent, target), nil
 }
 
-// findMinimumPacks finds the minimum number of packs to achieve exact target amount
-func (c *DynamicPackCalculator) findMinimumPacks(target int, packSizes []int) map[int]int {
-	// DP array: dp[i] = minimum number of packs to achieve amount i
+// buildPacksTable returns dp[i] = minimum number of packs to achieve amount i
+// and parent[i] = the last pack added to reach it, -1 when i cannot be reached
+func (c *DynamicPackCalculator) buildPacksTable(ctx context.Context, target int, packSizes []int) ([]int, []int, error) {
 	dp := make([]int, target+1)
 	parent := make([]int, target+1)
 
@@ -146,6 +183,9 @@ func (c *DynamicPackCalculator) findMinimumPacks(target int, packSizes []int) ma
 
 	// Build DP table
 	for i := 1; i <= target; i++ {
+		if err := checkContextEvery(ctx, i); err != nil {
+			return nil, nil, err
+		}
 		for _, pack := range packSizes {
 			if i >= pack && dp[i-pack] != math.MaxInt32 {
 				if dp[i-pack]+1 < dp[i] {
@@ -156,7 +196,11 @@ func (c *DynamicPackCalculator) findMinimumPacks(target int, packSizes []int) ma
 		}
 	}
 
-	// Backtrack to find which packs were used
+	return dp, parent, nil
+}
+
+// backtrackPacks follows parent to find which packs were used to reach target
+func backtrackPacks(parent []int, target int) map[int]int {
 	result := make(map[int]int)
 	current := target
 
@@ -1,57 +1,64 @@
 package calculator
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// calculateTests is shared by every PackCalculator implementation
+var calculateTests = []struct {
+	name      string
+	quantity  int
+	packSizes []int
+	wantTotal int
+	wantPacks int
+}{
+	{
+		name:      "Order 1 item",
+		quantity:  1,
+		packSizes: []int{250, 500, 1000, 2000, 5000},
+		wantTotal: 250,
+		wantPacks: 1,
+	},
+	{
+		name:      "Order 250 items",
+		quantity:  250,
+		packSizes: []int{250, 500, 1000, 2000, 5000},
+		wantTotal: 250,
+		wantPacks: 1,
+	},
+	{
+		name:      "Order 251 items",
+		quantity:  251,
+		packSizes: []int{250, 500, 1000, 2000, 5000},
+		wantTotal: 500,
+		wantPacks: 1,
+	},
+	{
+		name:      "Order 501 items",
+		quantity:  501,
+		packSizes: []int{250, 500, 1000, 2000, 5000},
+		wantTotal: 750,
+		wantPacks: 2,
+	},
+	{
+		name:      "Order 12001 items",
+		quantity:  12001,
+		packSizes: []int{250, 500, 1000, 2000, 5000},
+		wantTotal: 12250,
+		wantPacks: 4,
+	},
+}
 
 func TestDynamicPackCalculator_Calculate(t *testing.T) {
 	calc := NewDynamicPackCalculator()
 
-	tests := []struct {
-		name      string
-		quantity  int
-		packSizes []int
-		wantTotal int
-		wantPacks int
-	}{
-		{
-			name:      "Order 1 item",
-			quantity:  1,
-			packSizes: []int{250, 500, 1000, 2000, 5000},
-			wantTotal: 250,
-			wantPacks: 1,
-		},
-		{
-			name:      "Order 250 items",
-			quantity:  250,
-			packSizes: []int{250, 500, 1000, 2000, 5000},
-			wantTotal: 250,
-			wantPacks: 1,
-		},
-		{
-			name:      "Order 251 items",
-			quantity:  251,
-			packSizes: []int{250, 500, 1000, 2000, 5000},
-			wantTotal: 500,
-			wantPacks: 1,
-		},
-		{
-			name:      "Order 501 items",
-			quantity:  501,
-			packSizes: []int{250, 500, 1000, 2000, 5000},
-			wantTotal: 750,
-			wantPacks: 2,
-		},
-		{
-			name:      "Order 12001 items",
-			quantity:  12001,
-			packSizes: []int{250, 500, 1000, 2000, 5000},
-			wantTotal: 12250,
-			wantPacks: 4,
-		},
-	}
-
-	for _, tt := range tests {
+	for _, tt := range calculateTests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := calc.Calculate(tt.quantity, tt.packSizes)
+			result, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
 			if err != nil {
 				t.Errorf("Calculate() error = %v", err)
 				return
@@ -81,7 +88,7 @@ func TestDynamicPackCalculator_EdgeCase(t *testing.T) {
 	packSizes := []int{23, 31, 53}
 	quantity := 500000
 
-	result, err := calc.Calculate(quantity, packSizes)
+	result, err := calc.Calculate(context.Background(), quantity, packSizes)
 	if err != nil {
 		t.Fatalf("Calculate() error = %v", err)
 	}
@@ -99,3 +106,66 @@ func TestDynamicPackCalculator_EdgeCase(t *testing.T) {
 		t.Errorf("Shipped %d items but needed at least %d", actualTotal, quantity)
 	}
 }
+
+func TestPackCalculators_Errors(t *testing.T) {
+	calculators := map[string]PackCalculator{
+		"dynamic": NewDynamicPackCalculator(),
+		"residue": NewResiduePackCalculator(),
+		"bounded": NewBoundedPackCalculator(),
+	}
+
+	tests := []struct {
+		name      string
+		quantity  int
+		packSizes []int
+		want      map[string]error
+	}{
+		{
+			name:      "No pack sizes",
+			quantity:  10,
+			packSizes: nil,
+			want:      map[string]error{"dynamic": ErrNoPackSizes, "residue": ErrNoPackSizes, "bounded": ErrNoPackSizes},
+		},
+		{
+			name:      "Only non-positive pack sizes",
+			quantity:  10,
+			packSizes: []int{0, -5},
+			want:      map[string]error{"dynamic": ErrNoPackSizes, "residue": ErrNoPackSizes, "bounded": ErrNoPackSizes},
+		},
+		{
+			name:      "Table above the default limit",
+			quantity:  DefaultMaxTableSize,
+			packSizes: []int{250, 500},
+			want:      map[string]error{"dynamic": ErrTableTooLarge, "residue": nil, "bounded": ErrTableTooLarge},
+		},
+		{
+			name:      "Quantity overflowing",
+			quantity:  math.MaxInt - 100,
+			packSizes: []int{250, 500},
+			want:      map[string]error{"dynamic": ErrQuantityTooLarge, "residue": ErrQuantityTooLarge, "bounded": ErrQuantityTooLarge},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, calc := range calculators {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				_, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
+				if !errors.Is(err, tt.want[name]) {
+					t.Errorf("Calculate() error = %v, want %v", err, tt.want[name])
+				}
+			})
+		}
+	}
+}
+
+func TestDynamicPackCalculator_DoesNotModifyInput(t *testing.T) {
+	calc := NewDynamicPackCalculator()
+
+	packSizes := []int{1000, 250, 500}
+	if _, err := calc.Calculate(context.Background(), 501, packSizes); err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if !reflect.DeepEqual(packSizes, []int{1000, 250, 500}) {
+		t.Errorf("Calculate() modified pack sizes: %v", packSizes)
+	}
+}
@@ -0,0 +1,118 @@
+package calculator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Criterion is one rule used to rank pack distributions; lower values are better
+type Criterion string
+
+// Criteria that can be combined into a Policy
+const (
+	// CriterionItemsOver counts the items shipped beyond the ordered quantity
+	CriterionItemsOver Criterion = "items_over"
+	// CriterionPackCount counts the packs shipped
+	CriterionPackCount Criterion = "pack_count"
+	// CriterionCost sums the cost of the packs shipped
+	CriterionCost Criterion = "cost"
+	// CriterionDistinctSizes counts the different pack sizes shipped
+	CriterionDistinctSizes Criterion = "distinct_sizes"
+)
+
+// Policy ranks pack distributions by its criteria in order, each one only
+// breaking the ties left by the previous ones
+type Policy struct {
+	Name     string      `json:"name"`
+	Criteria []Criterion `json:"criteria"`
+	// MaxOveragePercent rejects distributions shipping more than this percentage
+	// above the ordered quantity. Zero means no limit
+	MaxOveragePercent float64 `json:"max_overage_percent,omitempty"`
+}
+
+// Built-in policies
+var (
+	// PolicyDefault follows the README rules: least items, then fewest packs
+	// It leaves costs to the policies naming them, so every calculator can apply it
+	PolicyDefault = Policy{
+		Name:     "default",
+		Criteria: []Criterion{CriterionItemsOver, CriterionPackCount},
+	}
+	// PolicyCost sends the least items at the lowest cost
+	PolicyCost = Policy{
+		Name:     "cost",
+		Criteria: []Criterion{CriterionItemsOver, CriterionCost, CriterionPackCount},
+	}
+	// PolicyFewestPacks sends as few packs as possible, then the least items
+	PolicyFewestPacks = Policy{
+		Name:     "fewest-packs",
+		Criteria: []Criterion{CriterionPackCount, CriterionItemsOver, CriterionCost},
+	}
+	// PolicyFewestSizes sends the least items using as few different pack sizes as possible
+	PolicyFewestSizes = Policy{
+		Name:     "fewest-sizes",
+		Criteria: []Criterion{CriterionItemsOver, CriterionDistinctSizes, CriterionPackCount},
+	}
+)
+
+var policies = map[string]Policy{
+	PolicyDefault.Name:     PolicyDefault,
+	PolicyCost.Name:        PolicyCost,
+	PolicyFewestPacks.Name: PolicyFewestPacks,
+	PolicyFewestSizes.Name: PolicyFewestSizes,
+}
+
+// LookupPolicy returns the built-in policy with the given name
+func LookupPolicy(name string) (Policy, bool) {
+	policy, ok := policies[name]
+	return policy, ok
+}
+
+// Policies returns all built-in policies sorted by name
+func Policies() []Policy {
+	result := make([]Policy, 0, len(policies))
+	for _, policy := range policies {
+		result = append(result, policy)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Validate checks that the policy only uses known criteria, each at most once
+func (p Policy) Validate() error {
+	seen := make(map[Criterion]bool, len(p.Criteria))
+	for _, criterion := range p.Criteria {
+		switch criterion {
+		case CriterionItemsOver, CriterionPackCount, CriterionCost, CriterionDistinctSizes:
+		default:
+			return fmt.Errorf("unknown criterion: %s", criterion)
+		}
+		if seen[criterion] {
+			return fmt.Errorf("criterion used more than once: %s", criterion)
+		}
+		seen[criterion] = true
+	}
+
+	if p.MaxOveragePercent < 0 {
+		return fmt.Errorf("max overage percent cannot be negative, got: %v", p.MaxOveragePercent)
+	}
+	return nil
+}
+
+// WithinOverage reports whether shipping amount items for quantity respects MaxOveragePercent
+func (p Policy) WithinOverage(quantity, amount int) bool {
+	if p.MaxOveragePercent <= 0 {
+		return true
+	}
+	return float64(amount-quantity)*100 <= p.MaxOveragePercent*float64(quantity)
+}
+
+// criteria returns the ranking criteria, falling back to the default rules
+func (p Policy) criteria() []Criterion {
+	if len(p.Criteria) == 0 {
+		return PolicyDefault.Criteria
+	}
+	return p.Criteria
+}
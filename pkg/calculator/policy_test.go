@@ -0,0 +1,66 @@
+package calculator
+
+import "testing"
+
+func TestLookupPolicy(t *testing.T) {
+	for _, policy := range Policies() {
+		found, ok := LookupPolicy(policy.Name)
+		if !ok || found.Name != policy.Name {
+			t.Errorf("LookupPolicy(%q) = %v, %v", policy.Name, found, ok)
+		}
+		if err := policy.Validate(); err != nil {
+			t.Errorf("Built-in policy %q is invalid: %v", policy.Name, err)
+		}
+	}
+
+	if _, ok := LookupPolicy("unknown"); ok {
+		t.Error("LookupPolicy() found an unknown policy")
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"Empty uses defaults", Policy{}, false},
+		{"Custom order", Policy{Criteria: []Criterion{CriterionPackCount, CriterionItemsOver}}, false},
+		{"Unknown criterion", Policy{Criteria: []Criterion{"weight"}}, true},
+		{"Duplicate criterion", Policy{Criteria: []Criterion{CriterionCost, CriterionCost}}, true},
+		{"Negative overage", Policy{MaxOveragePercent: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_WithinOverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  float64
+		quantity int
+		amount   int
+		want     bool
+	}{
+		{"No limit", 0, 1, 5000, true},
+		{"Exact amount", 10, 1000, 1000, true},
+		{"At the limit", 10, 1000, 1100, true},
+		{"Over the limit", 10, 1000, 1101, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := Policy{MaxOveragePercent: tt.percent}
+			if got := policy.WithinOverage(tt.quantity, tt.amount); got != tt.want {
+				t.Errorf("WithinOverage(%d, %d) = %v, want %v", tt.quantity, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
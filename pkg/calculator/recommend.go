@@ -0,0 +1,317 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+)
+
+// DefaultMaxEvaluations is how many pack size sets Recommend evaluates unless told otherwise
+const DefaultMaxEvaluations = 2000
+
+// QuantityCount is a quantity ordered Count times
+type QuantityCount struct {
+	Quantity int
+	Count    int
+}
+
+// RecommendOptions configures the search of Recommend
+type RecommendOptions struct {
+	// Candidates are the pack sizes the recommended set is chosen from
+	Candidates []int
+	// MaxSizes is the most pack sizes the recommended set holds
+	MaxSizes int
+	// OverageWeight and PackWeight weigh the total overage and packs into the score the
+	// search minimizes; when both are 0 only the overage counts
+	OverageWeight int
+	PackWeight    int
+	// MaxEvaluations bounds the pack size sets evaluated, DefaultMaxEvaluations when 0
+	MaxEvaluations int
+	// Baseline, when set, is a pack size set evaluated with the same weights for comparison
+	// It does not count towards MaxEvaluations
+	Baseline []int
+	// Progress, when set, is called with the number of sets evaluated after each one
+	Progress func(evaluated int)
+}
+
+// PackSetEvaluation is how a pack size set packs a history of orders under the default rules
+type PackSetEvaluation struct {
+	PackSizes    []int
+	Orders       int
+	TotalItems   int
+	TotalOverage int
+	TotalPacks   int
+	// Score is the weighted overage and packs the search minimizes
+	Score int
+}
+
+// Recommendation is the best pack size set a search found
+// Exhaustive reports whether every set of the candidates was evaluated
+type Recommendation struct {
+	Best       PackSetEvaluation
+	Baseline   *PackSetEvaluation
+	Evaluated  int
+	Exhaustive bool
+}
+
+// errBudgetSpent stops a search once it evaluated as many sets as allowed
+var errBudgetSpent = errors.New("evaluation budget spent")
+
+// EvaluatePackSizes calculates every order with packSizes using calc and sums the results
+// Orders are calculated from the largest quantity down, so a calculator sharing its
+// tables builds the table of packSizes once
+func EvaluatePackSizes(ctx context.Context, calc PackCalculator, orders []QuantityCount, packSizes []int) (PackSetEvaluation, error) {
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return PackSetEvaluation{}, ErrNoPackSizes
+	}
+	return evaluate(ctx, calc, normalizeOrders(orders), sizes, RecommendOptions{})
+}
+
+// Recommend searches the sets of at most opts.MaxSizes candidates for the one packing
+// orders with the lowest score, using calc to evaluate them
+// Every set is evaluated when they fit in opts.MaxEvaluations. Otherwise sizes are added
+// greedily, one at a time, then swapped or dropped while that lowers the score; the best
+// set found is returned once the budget is spent
+func Recommend(ctx context.Context, calc PackCalculator, orders []QuantityCount, opts RecommendOptions) (*Recommendation, error) {
+	candidates := normalizePackSizes(opts.Candidates)
+	if len(candidates) == 0 {
+		return nil, ErrNoPackSizes
+	}
+	orders = normalizeOrders(orders)
+	if len(orders) == 0 {
+		return nil, ErrNoOrders
+	}
+	if opts.OverageWeight == 0 && opts.PackWeight == 0 {
+		opts.OverageWeight = 1
+	}
+	if opts.MaxEvaluations <= 0 {
+		opts.MaxEvaluations = DefaultMaxEvaluations
+	}
+	maxSizes := min(max(opts.MaxSizes, 1), len(candidates))
+
+	var baseline *PackSetEvaluation
+	if sizes := normalizePackSizes(opts.Baseline); len(sizes) > 0 {
+		evaluation, err := evaluate(ctx, calc, orders, sizes, opts)
+		if err != nil {
+			return nil, err
+		}
+		baseline = &evaluation
+	}
+
+	search := &recommendSearch{ctx: ctx, calc: calc, orders: orders, opts: opts, seen: make(map[string]PackSetEvaluation)}
+	exhaustive := countSets(len(candidates), maxSizes, opts.MaxEvaluations) <= opts.MaxEvaluations
+	var err error
+	if exhaustive {
+		err = search.exhaustive(candidates, maxSizes)
+	} else {
+		err = search.local(candidates, maxSizes)
+	}
+	if err != nil && !errors.Is(err, errBudgetSpent) {
+		return nil, err
+	}
+
+	return &Recommendation{Best: *search.best, Baseline: baseline, Evaluated: search.evaluated, Exhaustive: exhaustive}, nil
+}
+
+// recommendSearch evaluates pack size sets and keeps the best one
+type recommendSearch struct {
+	ctx       context.Context
+	calc      PackCalculator
+	orders    []QuantityCount
+	opts      RecommendOptions
+	evaluated int
+	best      *PackSetEvaluation
+	// seen holds the sets evaluated so far by key, so none is evaluated twice
+	seen map[string]PackSetEvaluation
+}
+
+// evaluate scores sizes, which must be sorted, and keeps it when it is the best so far
+func (s *recommendSearch) evaluate(sizes []int) (PackSetEvaluation, error) {
+	key := packSetKey(sizes)
+	if evaluation, ok := s.seen[key]; ok {
+		return evaluation, nil
+	}
+	if s.evaluated >= s.opts.MaxEvaluations {
+		return PackSetEvaluation{}, errBudgetSpent
+	}
+	evaluation, err := evaluate(s.ctx, s.calc, s.orders, sizes, s.opts)
+	if err != nil {
+		return PackSetEvaluation{}, err
+	}
+
+	s.seen[key] = evaluation
+	s.evaluated++
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.evaluated)
+	}
+	if s.best == nil || betterEvaluation(evaluation, *s.best) {
+		s.best = &evaluation
+	}
+	return evaluation, nil
+}
+
+// exhaustive evaluates every set of 1 to maxSizes candidates
+func (s *recommendSearch) exhaustive(candidates []int, maxSizes int) error {
+	var visit func(start int, sizes []int) error
+	visit = func(start int, sizes []int) error {
+		for i := start; i < len(candidates); i++ {
+			set := append(slices.Clip(sizes), candidates[i])
+			if _, err := s.evaluate(set); err != nil {
+				return err
+			}
+			if len(set) < maxSizes {
+				if err := visit(i+1, set); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit(0, nil)
+}
+
+// local grows a set greedily up to maxSizes candidates, then swaps or drops single
+// sizes while that improves it
+func (s *recommendSearch) local(candidates []int, maxSizes int) error {
+	var current *PackSetEvaluation
+	for len(current.sizes()) < maxSizes {
+		var next *PackSetEvaluation
+		for _, candidate := range candidates {
+			if slices.Contains(current.sizes(), candidate) {
+				continue
+			}
+			evaluation, err := s.evaluate(withSize(current.sizes(), candidate))
+			if err != nil {
+				return err
+			}
+			if next == nil || betterEvaluation(evaluation, *next) {
+				next = &evaluation
+			}
+		}
+		if current != nil && !betterEvaluation(*next, *current) {
+			break
+		}
+		current = next
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for _, neighbor := range neighborSets(current.sizes(), candidates) {
+			evaluation, err := s.evaluate(neighbor)
+			if err != nil {
+				return err
+			}
+			if betterEvaluation(evaluation, *current) {
+				current, improved = &evaluation, true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// sizes returns the pack sizes of e, nil for no evaluation
+func (e *PackSetEvaluation) sizes() []int {
+	if e == nil {
+		return nil
+	}
+	return e.PackSizes
+}
+
+// neighborSets returns the sets differing from sizes by one dropped or swapped size
+func neighborSets(sizes, candidates []int) [][]int {
+	var neighbors [][]int
+	for i := range sizes {
+		rest := slices.Delete(slices.Clone(sizes), i, i+1)
+		if len(rest) > 0 {
+			neighbors = append(neighbors, rest)
+		}
+		for _, candidate := range candidates {
+			if !slices.Contains(sizes, candidate) {
+				neighbors = append(neighbors, withSize(rest, candidate))
+			}
+		}
+	}
+	return neighbors
+}
+
+// withSize returns a sorted copy of sizes with size added
+func withSize(sizes []int, size int) []int {
+	set := append(slices.Clone(sizes), size)
+	sort.Ints(set)
+	return set
+}
+
+// evaluate sums the results of orders, sorted by decreasing quantity, packed with sizes
+func evaluate(ctx context.Context, calc PackCalculator, orders []QuantityCount, sizes []int, opts RecommendOptions) (PackSetEvaluation, error) {
+	evaluation := PackSetEvaluation{PackSizes: sizes}
+	for _, order := range orders {
+		breakdown, err := calc.Calculate(ctx, order.Quantity, sizes)
+		if err != nil {
+			return PackSetEvaluation{}, err
+		}
+		items, packs := 0, 0
+		for size, count := range breakdown {
+			items += size * count
+			packs += count
+		}
+
+		evaluation.Orders += order.Count
+		evaluation.TotalItems += items * order.Count
+		evaluation.TotalOverage += (items - order.Quantity) * order.Count
+		evaluation.TotalPacks += packs * order.Count
+	}
+	evaluation.Score = opts.OverageWeight*evaluation.TotalOverage + opts.PackWeight*evaluation.TotalPacks
+	return evaluation, nil
+}
+
+// betterEvaluation orders a before b by score, then overage, packs, number of sizes
+// and finally the sizes themselves, so searches are deterministic
+func betterEvaluation(a, b PackSetEvaluation) bool {
+	switch {
+	case a.Score != b.Score:
+		return a.Score < b.Score
+	case a.TotalOverage != b.TotalOverage:
+		return a.TotalOverage < b.TotalOverage
+	case a.TotalPacks != b.TotalPacks:
+		return a.TotalPacks < b.TotalPacks
+	case len(a.PackSizes) != len(b.PackSizes):
+		return len(a.PackSizes) < len(b.PackSizes)
+	default:
+		return slices.Compare(a.PackSizes, b.PackSizes) < 0
+	}
+}
+
+// normalizeOrders merges the orders of the same quantity, drops the non-positive ones
+// and sorts them by decreasing quantity
+func normalizeOrders(orders []QuantityCount) []QuantityCount {
+	counts := make(map[int]int, len(orders))
+	for _, order := range orders {
+		if order.Quantity > 0 && order.Count > 0 {
+			counts[order.Quantity] += order.Count
+		}
+	}
+
+	merged := make([]QuantityCount, 0, len(counts))
+	for quantity, count := range counts {
+		merged = append(merged, QuantityCount{Quantity: quantity, Count: count})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Quantity > merged[j].Quantity })
+	return merged
+}
+
+// countSets returns the number of sets of 1 to k of n candidates, or limit+1 once it exceeds limit
+func countSets(n, k, limit int) int {
+	total, combinations := 0, 1
+	for i := 1; i <= k; i++ {
+		// combinations of i out of n, computed from those of i-1
+		combinations = combinations * (n - i + 1) / i
+		total += combinations
+		if combinations > limit || total > limit {
+			return limit + 1
+		}
+	}
+	return total
+}
@@ -0,0 +1,149 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluatePackSizes(t *testing.T) {
+	orders := []QuantityCount{{Quantity: 251, Count: 2}, {Quantity: 1, Count: 1}, {Quantity: 251, Count: 1}, {Quantity: 7, Count: 0}}
+
+	got, err := EvaluatePackSizes(context.Background(), NewDynamicPackCalculator(), orders, []int{500, 250})
+	if err != nil {
+		t.Fatalf("EvaluatePackSizes() error = %v", err)
+	}
+
+	// 251 three times gets 500 in one pack, 1 gets 250; the order counted 0 times is dropped
+	want := PackSetEvaluation{PackSizes: []int{250, 500}, Orders: 4, TotalItems: 1750, TotalOverage: 996, TotalPacks: 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EvaluatePackSizes() = %+v, want %+v", got, want)
+	}
+
+	if _, err := EvaluatePackSizes(context.Background(), NewDynamicPackCalculator(), orders, nil); !errors.Is(err, ErrNoPackSizes) {
+		t.Errorf("EvaluatePackSizes() error = %v, want %v", err, ErrNoPackSizes)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	orders := []QuantityCount{{Quantity: 300, Count: 10}, {Quantity: 600, Count: 5}, {Quantity: 250, Count: 2}}
+	candidates := []int{100, 200, 300, 400, 500, 600}
+
+	tests := []struct {
+		name           string
+		opts           RecommendOptions
+		wantSizes      []int
+		wantOverage    int
+		wantPacks      int
+		wantExhaustive bool
+		wantEvaluated  int
+		wantBaseline   *PackSetEvaluation
+	}{
+		{
+			name: "Single size",
+			// 100 and 300 both send 50 more items for 250; 300 needs fewer packs
+			opts:           RecommendOptions{Candidates: candidates, MaxSizes: 1},
+			wantSizes:      []int{300},
+			wantOverage:    100,
+			wantPacks:      22,
+			wantExhaustive: true,
+			wantEvaluated:  6,
+		},
+		{
+			name:           "Only packs count",
+			opts:           RecommendOptions{Candidates: candidates, MaxSizes: 1, PackWeight: 1},
+			wantSizes:      []int{600},
+			wantOverage:    3700,
+			wantPacks:      17,
+			wantExhaustive: true,
+			wantEvaluated:  6,
+		},
+		{
+			name:           "Two sizes",
+			opts:           RecommendOptions{Candidates: candidates, MaxSizes: 2},
+			wantSizes:      []int{300, 600},
+			wantOverage:    100,
+			wantPacks:      17,
+			wantExhaustive: true,
+			wantEvaluated:  21,
+		},
+		{
+			name: "Local search within a budget",
+			// 41 sets of up to 3 sizes do not fit; no third size improves on 300 and 600
+			opts:          RecommendOptions{Candidates: candidates, MaxSizes: 3, MaxEvaluations: 20},
+			wantSizes:     []int{300, 600},
+			wantOverage:   100,
+			wantPacks:     17,
+			wantEvaluated: 19,
+		},
+		{
+			name: "Baseline",
+			// 250s send 500 for 300 and 750 for 600, 2750 items more than ordered
+			opts:           RecommendOptions{Candidates: candidates, MaxSizes: 1, Baseline: []int{250}},
+			wantSizes:      []int{300},
+			wantOverage:    100,
+			wantPacks:      22,
+			wantExhaustive: true,
+			wantEvaluated:  6,
+			wantBaseline:   &PackSetEvaluation{PackSizes: []int{250}, Orders: 17, TotalItems: 9250, TotalOverage: 2750, TotalPacks: 37, Score: 2750},
+		},
+		{
+			name:          "Budget spent",
+			opts:          RecommendOptions{Candidates: candidates, MaxSizes: 3, MaxEvaluations: 4},
+			wantSizes:     []int{300},
+			wantOverage:   100,
+			wantPacks:     22,
+			wantEvaluated: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progress int
+			tt.opts.Progress = func(evaluated int) { progress = evaluated }
+
+			got, err := Recommend(context.Background(), NewDynamicPackCalculator(), orders, tt.opts)
+			if err != nil {
+				t.Fatalf("Recommend() error = %v", err)
+			}
+
+			best := got.Best
+			if !reflect.DeepEqual(best.PackSizes, tt.wantSizes) || best.TotalOverage != tt.wantOverage || best.TotalPacks != tt.wantPacks {
+				t.Errorf("Recommend() = %v with %d overage in %d packs, want %v with %d in %d",
+					best.PackSizes, best.TotalOverage, best.TotalPacks, tt.wantSizes, tt.wantOverage, tt.wantPacks)
+			}
+			if got.Exhaustive != tt.wantExhaustive || got.Evaluated != tt.wantEvaluated || progress != got.Evaluated {
+				t.Errorf("Recommend() exhaustive = %v after %d sets (progress %d), want %v after %d",
+					got.Exhaustive, got.Evaluated, progress, tt.wantExhaustive, tt.wantEvaluated)
+			}
+			if !reflect.DeepEqual(got.Baseline, tt.wantBaseline) {
+				t.Errorf("Recommend() baseline = %+v, want %+v", got.Baseline, tt.wantBaseline)
+			}
+		})
+	}
+}
+
+func TestRecommend_Errors(t *testing.T) {
+	orders := []QuantityCount{{Quantity: 300, Count: 1}}
+
+	tests := []struct {
+		name    string
+		calc    PackCalculator
+		orders  []QuantityCount
+		opts    RecommendOptions
+		wantErr error
+	}{
+		{"No candidates", NewDynamicPackCalculator(), orders, RecommendOptions{MaxSizes: 1}, ErrNoPackSizes},
+		{"No orders", NewDynamicPackCalculator(), []QuantityCount{{Quantity: 300}}, RecommendOptions{Candidates: []int{100}, MaxSizes: 1}, ErrNoOrders},
+		{"Calculation error", NewDynamicPackCalculator().WithLimits(Limits{MaxQuantity: 100}), orders, RecommendOptions{Candidates: []int{100}, MaxSizes: 1}, ErrQuantityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Recommend(context.Background(), tt.calc, tt.orders, tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Recommend() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package calculator
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+)
+
+// ResiduePackCalculator implements PackCalculator using shortest paths over residue classes
+// Instead of a table indexed by quantity, it works on the residues modulo a pack size,
+// so memory depends on the pack sizes rather than on the ordered quantity
+type ResiduePackCalculator struct {
+	limits Limits
+}
+
+// NewResiduePackCalculator creates a new residue-class calculator instance with the default limits
+func NewResiduePackCalculator() *ResiduePackCalculator {
+	return &ResiduePackCalculator{limits: DefaultLimits()}
+}
+
+// WithLimits returns a copy of the calculator enforcing limits
+func (c *ResiduePackCalculator) WithLimits(limits Limits) *ResiduePackCalculator {
+	return &ResiduePackCalculator{limits: limits}
+}
+
+// Algorithm returns AlgorithmResidue
+func (c *ResiduePackCalculator) Algorithm() string {
+	return AlgorithmResidue
+}
+
+// Calculate determines the optimal pack distribution for the given quantity
+// It follows the same rules as DynamicPackCalculator:
+// 1. Only whole packs can be sent
+// 2. Send the least amount of items to fulfill the order
+// 3. Send as few packs as possible
+func (c *ResiduePackCalculator) Calculate(ctx context.Context, quantity int, packSizes []int) (map[int]int, error) {
+	if quantity <= 0 {
+		return map[int]int{}, nil
+	}
+
+	sizes := normalizePackSizes(packSizes)
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	// Amounts up to quantity + largest pack must not overflow
+	if quantity > math.MaxInt-sizes[len(sizes)-1] {
+		return nil, ErrQuantityTooLarge
+	}
+
+	ctx, cancel, err := c.limits.start(ctx, quantity)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	// The largest table has one cell per residue modulo the largest pack
+	if err := c.limits.checkTableSize(sizes[len(sizes)-1]); err != nil {
+		return nil, err
+	}
+
+	// Find the minimum amount that can fulfill the order
+	minAmount, err := c.findMinimumAmount(ctx, quantity, sizes)
+	if err != nil {
+		return nil, err
+	}
+	if minAmount == math.MaxInt {
+		return nil, ErrUnreachable
+	}
+
+	// Now find the minimum number of packs to achieve that amount
+	return c.findMinimumPacks(ctx, minAmount, sizes)
+}
+
+// findMinimumAmount finds the minimum number of items >= quantity that can be made
+// Every amount is written as base*k + rest, where base is the smallest pack.
+// dist[r] is the smallest reachable amount congruent to r modulo base, and every
+// amount dist[r] + base*k is reachable too, so one candidate per residue is enough
+func (c *ResiduePackCalculator) findMinimumAmount(ctx context.Context, quantity int, packSizes []int) (int, error) {
+	base := packSizes[0]
+	dist, err := shortestResiduePaths(ctx, base, packSizes[1:], func(pack int) int { return pack })
+	if err != nil {
+		return 0, err
+	}
+
+	best := math.MaxInt
+	for r, d := range dist {
+		if d.weight == math.MaxInt {
+			continue
+		}
+
+		// Smallest amount >= quantity in residue class r
+		candidate := quantity + ((r-quantity%base)%base+base)%base
+		if candidate < d.weight {
+			candidate = d.weight
+		}
+		if candidate < best {
+			best = candidate
+		}
+	}
+
+	return best, nil
+}
+
+// findMinimumPacks finds the minimum number of packs to achieve exact target amount
+// Every solution is written as largest*k plus a combination of the other packs.
+// Using a smaller pack instead of part of a largest pack costs (largest - pack),
+// so the cheapest combination per residue modulo largest minimizes the pack count
+func (c *ResiduePackCalculator) findMinimumPacks(ctx context.Context, target int, packSizes []int) (map[int]int, error) {
+	largest := packSizes[len(packSizes)-1]
+	others := packSizes[:len(packSizes)-1]
+	dist, err := shortestResiduePaths(ctx, largest, others, func(pack int) int { return largest - pack })
+	if err != nil {
+		return nil, err
+	}
+
+	residue := target % largest
+	if dist[residue].weight != math.MaxInt && dist[residue].sum <= target {
+		result := make(map[int]int)
+		for r := residue; r != 0; r = dist[r].prev {
+			result[dist[r].pack]++
+		}
+		if count := (target - dist[residue].sum) / largest; count > 0 {
+			result[largest] = count
+		}
+		return result, nil
+	}
+
+	// The cheapest combination needs more items than the target allows.
+	// This only happens for small targets, so a table bounded by target is fine here
+	if err := c.limits.checkTableSize(target + 1); err != nil {
+		return nil, err
+	}
+	return NewDynamicPackCalculator().findMinimumPacks(ctx, target, packSizes)
+}
+
+// residueNode holds the best known path to a residue class
+type residueNode struct {
+	weight int // accumulated edge weight
+	sum    int // items used by the path
+	pack   int // last pack added on the path
+	prev   int // residue before the last pack was added
+}
+
+// shortestResiduePaths runs Dijkstra over the residues modulo mod, where adding a
+// pack moves from residue r to (r+pack)%mod at the cost given by weight
+func shortestResiduePaths(ctx context.Context, mod int, packSizes []int, weight func(pack int) int) ([]residueNode, error) {
+	dist := make([]residueNode, mod)
+	for i := range dist {
+		dist[i].weight = math.MaxInt
+	}
+	dist[0].weight = 0
+
+	queue := &residueQueue{{residue: 0, weight: 0}}
+	for pops := 0; queue.Len() > 0; pops++ {
+		if err := checkContextEvery(ctx, pops); err != nil {
+			return nil, err
+		}
+		item := heap.Pop(queue).(residueItem)
+		if item.weight > dist[item.residue].weight {
+			continue // Stale queue entry
+		}
+
+		for _, pack := range packSizes {
+			next := (item.residue + pack) % mod
+			nextWeight := item.weight + weight(pack)
+			if nextWeight < dist[next].weight {
+				dist[next] = residueNode{
+					weight: nextWeight,
+					sum:    dist[item.residue].sum + pack,
+					pack:   pack,
+					prev:   item.residue,
+				}
+				heap.Push(queue, residueItem{residue: next, weight: nextWeight})
+			}
+		}
+	}
+
+	return dist, nil
+}
+
+// normalizePackSizes returns a sorted copy of the positive pack sizes without duplicates
+func normalizePackSizes(packSizes []int) []int {
+	sizes := make([]int, 0, len(packSizes))
+	for _, size := range packSizes {
+		if size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+
+	unique := sizes[:0]
+	for i, size := range sizes {
+		if i == 0 || size != sizes[i-1] {
+			unique = append(unique, size)
+		}
+	}
+	return unique
+}
+
+// residueItem is a priority queue entry for shortestResiduePaths
+type residueItem struct {
+	residue int
+	weight  int
+}
+
+// residueQueue is a min-heap of residueItem ordered by weight
+type residueQueue []residueItem
+
+func (q residueQueue) Len() int            { return len(q) }
+func (q residueQueue) Less(i, j int) bool  { return q[i].weight < q[j].weight }
+func (q residueQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *residueQueue) Push(x interface{}) { *q = append(*q, x.(residueItem)) }
+func (q *residueQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,165 @@
+package calculator
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestResiduePackCalculator_Calculate(t *testing.T) {
+	calc := NewResiduePackCalculator()
+	dynamic := NewDynamicPackCalculator()
+
+	for _, tt := range calculateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			totalItems, totalPacks := totals(result)
+			if totalItems != tt.wantTotal {
+				t.Errorf("Calculate() total items = %v, want %v. Breakdown: %v", totalItems, tt.wantTotal, result)
+			}
+			if totalPacks != tt.wantPacks {
+				t.Errorf("Calculate() total packs = %v, want %v. Breakdown: %v", totalPacks, tt.wantPacks, result)
+			}
+
+			expected, _ := dynamic.Calculate(context.Background(), tt.quantity, tt.packSizes)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Calculate() breakdown = %v, dynamic calculator gave %v", result, expected)
+			}
+		})
+	}
+}
+
+func TestResiduePackCalculator_MatchesDynamic(t *testing.T) {
+	calc := NewResiduePackCalculator()
+	dynamic := NewDynamicPackCalculator()
+
+	packSets := [][]int{
+		{23, 31, 53},
+		{3, 5},
+		{6, 9, 20},
+		{4, 10, 25},
+		{7},
+		{250, 500, 1000, 2000, 5000},
+	}
+
+	for _, packSizes := range packSets {
+		for quantity := 1; quantity <= 600; quantity++ {
+			result, err := calc.Calculate(context.Background(), quantity, append([]int(nil), packSizes...))
+			if err != nil {
+				t.Fatalf("Calculate(%d, %v) error = %v", quantity, packSizes, err)
+			}
+			expected, _ := dynamic.Calculate(context.Background(), quantity, append([]int(nil), packSizes...))
+
+			gotItems, gotPacks := totals(result)
+			wantItems, wantPacks := totals(expected)
+			if gotItems != wantItems || gotPacks != wantPacks {
+				t.Fatalf("Calculate(%d, %v) = %v (items:%d packs:%d), want items:%d packs:%d",
+					quantity, packSizes, result, gotItems, gotPacks, wantItems, wantPacks)
+			}
+		}
+	}
+}
+
+func TestResiduePackCalculator_EdgeCase(t *testing.T) {
+	calc := NewResiduePackCalculator()
+
+	result, err := calc.Calculate(context.Background(), 500000, []int{23, 31, 53})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	want := map[int]int{23: 2, 31: 7, 53: 9429}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Calculate() = %v, want %v", result, want)
+	}
+}
+
+func TestResiduePackCalculator_HugeQuantity(t *testing.T) {
+	calc := NewResiduePackCalculator()
+
+	result, err := calc.Calculate(context.Background(), math.MaxInt32, []int{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	totalItems, totalPacks := totals(result)
+	if totalItems != 2147483750 {
+		t.Errorf("Calculate() total items = %d, want 2147483750", totalItems)
+	}
+	if totalPacks != 429500 {
+		t.Errorf("Calculate() total packs = %d, want 429500. Breakdown: %v", totalPacks, result)
+	}
+}
+
+func TestResiduePackCalculator_DoesNotModifyInput(t *testing.T) {
+	calc := NewResiduePackCalculator()
+
+	packSizes := []int{1000, 250, 500}
+	if _, err := calc.Calculate(context.Background(), 501, packSizes); err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if !reflect.DeepEqual(packSizes, []int{1000, 250, 500}) {
+		t.Errorf("Calculate() modified pack sizes: %v", packSizes)
+	}
+}
+
+func TestNewPackCalculator(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		wantType  PackCalculator
+		wantErr   bool
+	}{
+		{"", &DynamicPackCalculator{}, false},
+		{AlgorithmDynamic, &DynamicPackCalculator{}, false},
+		{AlgorithmResidue, &ResiduePackCalculator{}, false},
+		{"greedy", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			calc, err := NewPackCalculator(tt.algorithm, DefaultLimits())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPackCalculator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if reflect.TypeOf(calc) != reflect.TypeOf(tt.wantType) {
+				t.Errorf("NewPackCalculator() = %T, want %T", calc, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestAlgorithm(t *testing.T) {
+	tests := []struct {
+		name string
+		calc PackCalculator
+		want string
+	}{
+		{"Dynamic", NewDynamicPackCalculator(), AlgorithmDynamic},
+		{"Residue", NewResiduePackCalculator(), AlgorithmResidue},
+		{"Bounded", NewBoundedPackCalculator(), AlgorithmBounded},
+		{"Cached", NewCachedPackCalculator(NewResiduePackCalculator(), 10, 0), AlgorithmResidue},
+		{"Unnamed", &countingCalculator{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Algorithm(tt.calc); got != tt.want {
+				t.Errorf("Algorithm() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// totals sums the items and packs of a breakdown
+func totals(breakdown map[int]int) (items, packs int) {
+	for packSize, count := range breakdown {
+		items += packSize * count
+		packs += count
+	}
+	return items, packs
+}
@@ -0,0 +1,259 @@
+package calculator
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+)
+
+// TableStoreOptions bounds the memory kept by a TableStore
+type TableStoreOptions struct {
+	// MaxCells is the number of amounts kept over all tables, each one taking 8 bytes
+	// A calculation whose table alone needs more builds its own table instead
+	MaxCells int
+	// MaxSets is the number of pack size sets kept, 0 for no limit
+	MaxSets int
+}
+
+// TableStats reports how a TableStore has been used
+type TableStats struct {
+	Sets     int `json:"sets"`
+	Cells    int `json:"cells"`
+	MaxCells int `json:"max_cells"`
+	MaxSets  int `json:"max_sets"`
+	// Hits are calculations answered by a table that already covered the quantity
+	Hits uint64 `json:"hits"`
+	// Extensions are calculations that created or grew a table
+	Extensions uint64 `json:"extensions"`
+	// Evictions are tables dropped to stay within the options
+	Evictions uint64 `json:"evictions"`
+	// Bypasses are calculations whose table would not fit in the store
+	Bypasses uint64 `json:"bypasses"`
+}
+
+// TableStore shares growable pack tables between calculations, one per pack size set
+// The fewest packs to reach an amount do not depend on the quantity ordered, so a table
+// built for one quantity answers every smaller one, and growing it keeps what was computed.
+// Tables double when they grow, so building them costs amortized O(1) per amount
+// and quantities already covered skip the table entirely
+// Least recently used tables are evicted first
+type TableStore struct {
+	opts TableStoreOptions
+
+	mu     sync.Mutex
+	tables map[string]*list.Element
+	order  *list.List // most recently used first
+	cells  int
+	stats  TableStats
+}
+
+// packTable holds the fewest packs per amount for one pack size set
+// Amounts already computed never change, the table only grows
+type packTable struct {
+	key   string
+	sizes []int
+	cells int // cells accounted for in the store, guarded by the store mutex
+
+	mu    sync.RWMutex
+	packs []int32 // fewest packs per amount, unreachablePacks when unreachable
+	last  []int32 // index in sizes of the last pack added, -1 when none
+}
+
+// NewTableStore creates an empty store bounded by opts
+// MaxCells is capped so pack counts fit in 32 bits
+func NewTableStore(opts TableStoreOptions) *TableStore {
+	opts.MaxCells = min(max(opts.MaxCells, 0), math.MaxInt32)
+	opts.MaxSets = max(opts.MaxSets, 0)
+	return &TableStore{
+		opts:   opts,
+		tables: make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// Stats returns the store statistics since it was created
+func (s *TableStore) Stats() TableStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Sets = s.order.Len()
+	stats.Cells = s.cells
+	stats.MaxCells = s.opts.MaxCells
+	stats.MaxSets = s.opts.MaxSets
+	return stats
+}
+
+// calculate returns the breakdown of the least amount >= quantity with the fewest packs,
+// using the shared table of sizes extended to span amounts
+// ok is false when the table does not fit in the store and the caller must build its own
+func (s *TableStore) calculate(ctx context.Context, quantity int, sizes []int, span int) (breakdown map[int]int, ok bool, err error) {
+	if span > s.opts.MaxCells {
+		s.count(&s.stats.Bypasses)
+		return nil, false, nil
+	}
+
+	table := s.table(sizes)
+
+	table.mu.RLock()
+	if len(table.packs) >= span {
+		breakdown, err = table.breakdown(quantity)
+		table.mu.RUnlock()
+		s.count(&s.stats.Hits)
+		return breakdown, true, err
+	}
+	table.mu.RUnlock()
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	// Another calculation may have grown the table meanwhile
+	if len(table.packs) < span {
+		length, reserved := s.reserve(table, span)
+		if !reserved {
+			s.count(&s.stats.Bypasses)
+			return nil, false, nil
+		}
+		if err := table.grow(ctx, length); err != nil {
+			s.release(table, len(table.packs))
+			return nil, true, err
+		}
+		s.count(&s.stats.Extensions)
+	} else {
+		s.count(&s.stats.Hits)
+	}
+
+	breakdown, err = table.breakdown(quantity)
+	return breakdown, true, err
+}
+
+// table returns the table of sizes, creating an empty one when needed
+func (s *TableStore) table(sizes []int) *packTable {
+	key := packSetKey(sizes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.tables[key]; ok {
+		s.order.MoveToFront(element)
+		return element.Value.(*packTable)
+	}
+
+	if s.opts.MaxSets > 0 && s.order.Len() >= s.opts.MaxSets {
+		s.evict(s.order.Back())
+	}
+	table := &packTable{key: key, sizes: sizes}
+	s.tables[key] = s.order.PushFront(table)
+	return table
+}
+
+// reserve makes room for table to grow to at least span amounts, evicting the least
+// recently used other tables, and returns the length to grow to
+// Tables double so that growing one amount at a time stays cheap
+// reserved is false when the table was evicted meanwhile
+func (s *TableStore) reserve(table *packTable, span int) (length int, reserved bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.holds(table) {
+		return 0, false
+	}
+
+	length = min(max(span, 2*table.cells), s.opts.MaxCells)
+	for s.cells-table.cells+length > s.opts.MaxCells {
+		oldest := s.order.Back()
+		if oldest.Value.(*packTable) == table {
+			oldest = oldest.Prev()
+		}
+		s.evict(oldest)
+	}
+
+	s.cells += length - table.cells
+	table.cells = length
+	return length, true
+}
+
+// release gives back the cells table reserved beyond length
+func (s *TableStore) release(table *packTable, length int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.holds(table) {
+		s.cells -= table.cells - length
+	}
+	table.cells = length
+}
+
+// holds reports whether table is still in the store; an evicted table may have been
+// replaced by a new one for the same pack sizes
+func (s *TableStore) holds(table *packTable) bool {
+	element, ok := s.tables[table.key]
+	return ok && element.Value.(*packTable) == table
+}
+
+// evict drops the table of element; calculations still using it finish normally
+func (s *TableStore) evict(element *list.Element) {
+	table := element.Value.(*packTable)
+	s.order.Remove(element)
+	delete(s.tables, table.key)
+	s.cells -= table.cells
+	s.stats.Evictions++
+}
+
+func (s *TableStore) count(counter *uint64) {
+	s.mu.Lock()
+	*counter++
+	s.mu.Unlock()
+}
+
+// grow extends the table to length amounts with the recurrence of buildPacksTable,
+// so the smallest pack size wins ties in the same way
+// The table is left unchanged when ctx is done before it is complete
+func (t *packTable) grow(ctx context.Context, length int) error {
+	start := len(t.packs)
+	packs := make([]int32, length)
+	last := make([]int32, length)
+	copy(packs, t.packs)
+	copy(last, t.last)
+
+	for i := start; i < length; i++ {
+		if err := checkContextEvery(ctx, i-start); err != nil {
+			return err
+		}
+
+		last[i] = -1
+		if i == 0 {
+			continue
+		}
+		packs[i] = unreachablePacks
+		for index, pack := range t.sizes {
+			if i >= pack && packs[i-pack] != unreachablePacks && packs[i-pack]+1 < packs[i] {
+				packs[i] = packs[i-pack] + 1
+				last[i] = int32(index)
+			}
+		}
+	}
+
+	t.packs, t.last = packs, last
+	return nil
+}
+
+// breakdown finds the least reachable amount >= quantity and backtracks its packs
+// The table must span quantity plus the largest pack
+func (t *packTable) breakdown(quantity int) (map[int]int, error) {
+	for amount := quantity; amount < len(t.packs); amount++ {
+		if t.packs[amount] == unreachablePacks {
+			continue
+		}
+
+		result := make(map[int]int)
+		for current := amount; current > 0; {
+			pack := t.sizes[t.last[current]]
+			result[pack]++
+			current -= pack
+		}
+		return result, nil
+	}
+	return nil, ErrUnreachable
+}
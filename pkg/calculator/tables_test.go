@@ -0,0 +1,183 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestDynamicPackCalculator_SharedTables(t *testing.T) {
+	calc := NewDynamicPackCalculator().WithTables(NewTableStore(TableStoreOptions{MaxCells: 1 << 20}))
+	private := NewDynamicPackCalculator()
+
+	for _, tt := range calculateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Calculate(context.Background(), tt.quantity, tt.packSizes)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			want, _ := private.Calculate(context.Background(), tt.quantity, tt.packSizes)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Calculate() = %v, private tables gave %v", got, want)
+			}
+		})
+	}
+
+	// Quantities in decreasing and increasing order hit and grow the same tables
+	for _, packSizes := range [][]int{{23, 31, 53}, {250, 500, 1000, 2000, 5000}, {6, 9, 20}} {
+		for _, quantities := range [][2]int{{3000, -1}, {1, 1}} {
+			for quantity := quantities[0]; quantity > 0 && quantity <= 3000; quantity += quantities[1] * 7 {
+				got, _ := calc.Calculate(context.Background(), quantity, packSizes)
+				want, _ := private.Calculate(context.Background(), quantity, packSizes)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("Calculate(%d, %v) = %v, private tables gave %v", quantity, packSizes, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTableStore_Growth(t *testing.T) {
+	store := NewTableStore(TableStoreOptions{MaxCells: 1 << 20})
+	calc := NewDynamicPackCalculator().WithTables(store)
+	packSizes := []int{250, 500, 1000}
+
+	steps := []struct {
+		quantity       int
+		wantHits       uint64
+		wantExtensions uint64
+		wantCells      int
+	}{
+		{1000, 0, 1, 2001},
+		{500, 1, 1, 2001},
+		{1000, 2, 1, 2001},
+		{1500, 2, 2, 4002}, // doubles instead of growing to 2501
+		{3000, 3, 2, 4002},
+		{3500, 3, 3, 8004},
+	}
+
+	for _, step := range steps {
+		if _, err := calc.Calculate(context.Background(), step.quantity, packSizes); err != nil {
+			t.Fatalf("Calculate(%d) error = %v", step.quantity, err)
+		}
+		stats := store.Stats()
+		if stats.Hits != step.wantHits || stats.Extensions != step.wantExtensions || stats.Cells != step.wantCells {
+			t.Errorf("After Calculate(%d) Stats() = %+v, want %d hits, %d extensions and %d cells",
+				step.quantity, stats, step.wantHits, step.wantExtensions, step.wantCells)
+		}
+	}
+}
+
+func TestTableStore_Eviction(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          TableStoreOptions
+		sets          [][]int
+		wantSets      int
+		wantEvictions uint64
+		wantBypasses  uint64
+	}{
+		{
+			name:          "Least recently used set beyond MaxSets",
+			opts:          TableStoreOptions{MaxCells: 1 << 20, MaxSets: 2},
+			sets:          [][]int{{250, 500}, {300, 600}, {250, 500}, {400, 800}},
+			wantSets:      2,
+			wantEvictions: 1,
+		},
+		{
+			name:          "Least recently used set beyond MaxCells",
+			opts:          TableStoreOptions{MaxCells: 3000},
+			sets:          [][]int{{250, 500}, {300, 600}, {400, 800}},
+			wantSets:      2,
+			wantEvictions: 1,
+		},
+		{
+			name:         "Table larger than MaxCells",
+			opts:         TableStoreOptions{MaxCells: 1000},
+			sets:         [][]int{{250, 500}, {300, 600}},
+			wantSets:     0,
+			wantBypasses: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewTableStore(tt.opts)
+			calc := NewDynamicPackCalculator().WithTables(store)
+			private := NewDynamicPackCalculator()
+
+			for _, packSizes := range tt.sets {
+				got, err := calc.Calculate(context.Background(), 501, packSizes)
+				if err != nil {
+					t.Fatalf("Calculate() error = %v", err)
+				}
+				want, _ := private.Calculate(context.Background(), 501, packSizes)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Calculate(%v) = %v, private tables gave %v", packSizes, got, want)
+				}
+			}
+
+			stats := store.Stats()
+			if stats.Sets != tt.wantSets || stats.Evictions != tt.wantEvictions || stats.Bypasses != tt.wantBypasses {
+				t.Errorf("Stats() = %+v, want %d sets, %d evictions and %d bypasses",
+					stats, tt.wantSets, tt.wantEvictions, tt.wantBypasses)
+			}
+			if stats.Cells > tt.opts.MaxCells {
+				t.Errorf("Stats() = %+v, more cells than the limit", stats)
+			}
+		})
+	}
+}
+
+func TestTableStore_CanceledGrowth(t *testing.T) {
+	store := NewTableStore(TableStoreOptions{MaxCells: 1 << 22})
+	calc := NewDynamicPackCalculator().WithTables(store)
+	packSizes := []int{23, 31, 53}
+
+	calc.Calculate(context.Background(), 1000, packSizes)
+	before := store.Stats()
+
+	_, err := calc.Calculate(&cancelAfter{Context: context.Background(), checks: 2}, 1_000_000, packSizes)
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Calculate() error = %v, want %v", err, ErrCanceled)
+	}
+	if after := store.Stats(); after.Cells != before.Cells || after.Extensions != before.Extensions {
+		t.Errorf("Stats() after a canceled growth = %+v, want the table unchanged from %+v", after, before)
+	}
+
+	if _, err := calc.Calculate(context.Background(), 1000, packSizes); err != nil {
+		t.Errorf("Calculate() after a canceled growth error = %v", err)
+	}
+}
+
+func TestTableStore_Concurrent(t *testing.T) {
+	store := NewTableStore(TableStoreOptions{MaxCells: 1 << 16, MaxSets: 2})
+	calc := NewDynamicPackCalculator().WithTables(store)
+	private := NewDynamicPackCalculator()
+	sets := [][]int{{23, 31, 53}, {250, 500, 1000}, {6, 9, 20}}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				quantity := 1 + (worker*7919+i*104729)%20_000
+				packSizes := sets[(worker+i)%len(sets)]
+				got, err := calc.Calculate(context.Background(), quantity, packSizes)
+				want, _ := private.Calculate(context.Background(), quantity, packSizes)
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("Calculate(%d, %v) = %v, %v, private tables gave %v", quantity, packSizes, got, err, want)
+					return
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	if stats := store.Stats(); stats.Cells > 1<<16 || stats.Sets > 2 {
+		t.Errorf("Stats() = %+v, beyond the limits", stats)
+	}
+}